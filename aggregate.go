@@ -0,0 +1,381 @@
+package updog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/RoaringBitmap/roaring"
+)
+
+// AggregateFunc is an aggregate function over the values of a numeric column.
+type AggregateFunc int
+
+const (
+	// AggregateSum computes the sum of the values, equivalent to `SUM(x)` in SQL.
+	AggregateSum AggregateFunc = iota + 1
+
+	// AggregateAvg computes the average of the values, equivalent to `AVG(x)` in SQL.
+	AggregateAvg
+
+	// AggregateMin determines the lowest value, equivalent to `MIN(x)` in SQL.
+	AggregateMin
+
+	// AggregateMax determines the highest value, equivalent to `MAX(x)` in SQL.
+	AggregateMax
+)
+
+func (f AggregateFunc) String() string {
+	switch f {
+	case AggregateSum:
+		return "SUM"
+	case AggregateAvg:
+		return "AVG"
+	case AggregateMin:
+		return "MIN"
+	case AggregateMax:
+		return "MAX"
+	default:
+		return fmt.Sprintf("AggregateFunc(%d)", int(f))
+	}
+}
+
+// Aggregate describes an aggregate function to compute over the values of a numeric column.
+// Rows that have no value in the column are ignored.
+type Aggregate struct {
+	Func   AggregateFunc
+	Column string
+}
+
+func (a Aggregate) String() string {
+	return fmt.Sprintf("%s(%s)", a.Func, a.Column)
+}
+
+// AggregateValue contains the result of an aggregate function among the rows of a result
+// or of a grouped result.
+type AggregateValue struct {
+	Func   AggregateFunc
+	Column string
+
+	// Valid is false if none of the rows has a value in the column. In that case, the
+	// result of the aggregate function is undefined, like NULL in SQL.
+	Valid bool
+
+	// Int contains the result of SUM, MIN and MAX.
+	Int int64
+
+	// Float contains the result of AVG.
+	Float float64
+}
+
+// aggregateState collects the values of a numeric column among a set of rows. States of
+// the same column can be merged, so that aggregates over multiple indexes can be computed.
+type aggregateState struct {
+	count    uint64
+	sum      *big.Int
+	min, max int64
+}
+
+func newAggregateState() *aggregateState {
+	return &aggregateState{sum: new(big.Int)}
+}
+
+func (s *aggregateState) clone() *aggregateState {
+	c := *s
+	c.sum = new(big.Int).Set(s.sum)
+	return &c
+}
+
+func (s *aggregateState) merge(o *aggregateState) {
+	if o.count == 0 {
+		return
+	}
+
+	if s.count == 0 || o.min < s.min {
+		s.min = o.min
+	}
+
+	if s.count == 0 || o.max > s.max {
+		s.max = o.max
+	}
+
+	s.count += o.count
+	s.sum.Add(s.sum, o.sum)
+}
+
+// mergeAggregateStates merges each of the states of src into the corresponding state of dst.
+func mergeAggregateStates(dst, src []*aggregateState) {
+	for i, state := range src {
+		dst[i].merge(state)
+	}
+}
+
+func (s *aggregateState) value(agg Aggregate) (AggregateValue, error) {
+	v := AggregateValue{Func: agg.Func, Column: agg.Column, Valid: s.count > 0}
+	if !v.Valid {
+		return v, nil
+	}
+
+	switch agg.Func {
+	case AggregateSum:
+		if !s.sum.IsInt64() {
+			return AggregateValue{}, fmt.Errorf("sum of column %q overflows a 64-bit integer", agg.Column)
+		}
+		v.Int = s.sum.Int64()
+	case AggregateAvg:
+		v.Float, _ = new(big.Float).Quo(new(big.Float).SetInt(s.sum), new(big.Float).SetUint64(s.count)).Float64()
+	case AggregateMin:
+		v.Int = s.min
+	case AggregateMax:
+		v.Int = s.max
+	}
+
+	return v, nil
+}
+
+func aggregateValues(states []*aggregateState, aggs []Aggregate) ([]AggregateValue, error) {
+	if len(states) == 0 {
+		return nil, nil
+	}
+
+	values := make([]AggregateValue, 0, len(states))
+
+	for i, state := range states {
+		v, err := state.value(aggs[i])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+// aggregateStates returns the states of the provided aggregates among the rows. The state of
+// a column is only computed once, even if multiple aggregates use the column.
+func (idx *Index) aggregateStates(ctx context.Context, rows *roaring.Bitmap, aggs []Aggregate) ([]*aggregateState, error) {
+	states := make([]*aggregateState, 0, len(aggs))
+	columns := map[string]*aggregateState{}
+
+	for _, agg := range aggs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if agg.Func < AggregateSum || agg.Func > AggregateMax {
+			return nil, fmt.Errorf("invalid aggregate function %d", int(agg.Func))
+		}
+
+		state, ok := columns[agg.Column]
+		if !ok {
+			var err error
+			if state, err = idx.aggregateState(agg.Column, rows); err != nil {
+				return nil, err
+			}
+			columns[agg.Column] = state
+		}
+
+		states = append(states, state.clone())
+	}
+
+	return states, nil
+}
+
+// aggregateState computes the state of a numeric column among the rows from the bit-sliced
+// index of the column.
+func (idx *Index) aggregateState(colName string, rows *roaring.Bitmap) (*aggregateState, error) {
+	col, err := idx.column(colName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !col.Numeric {
+		if _, ok := idx.schema.Columns[colName]; ok {
+			return nil, fmt.Errorf("column %q is not numeric", colName)
+		}
+
+		// the column is missing in this segment of a SegmentedIndex.
+		return newAggregateState(), nil
+	}
+
+	presence, err := idx.presence(colName, col)
+	if err != nil {
+		return nil, err
+	}
+
+	rows = roaring.And(rows, presence)
+
+	state := newAggregateState()
+
+	state.count = rows.GetCardinality()
+	if state.count == 0 {
+		return state, nil
+	}
+
+	bits, err := idx.numericBitmaps(colName, col)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sum of the encoded values is the sum of the value of each bit times the number
+	// of rows that have the bit set. As the encoding adds 2^63 to every value, that is
+	// subtracted again for every row.
+	var term big.Int
+
+	for i, bm := range bits {
+		if bm != nil {
+			term.SetUint64(rows.AndCardinality(bm))
+			state.sum.Add(state.sum, term.Lsh(&term, uint(i)))
+		}
+	}
+
+	term.SetUint64(state.count)
+	state.sum.Sub(state.sum, term.Lsh(&term, 63))
+
+	state.min = decodeNumeric(extremum(rows, bits, false))
+	state.max = decodeNumeric(extremum(rows, bits, true))
+
+	return state, nil
+}
+
+// numericBitmaps returns the bitmaps of all bits of the bit-sliced index of a numeric column.
+// The bitmaps of bits that no row has set are nil.
+func (idx *Index) numericBitmaps(colName string, col *column) ([]*roaring.Bitmap, error) {
+	bits := make([]*roaring.Bitmap, numericBits)
+
+	for i, bitIdx := range col.Bits {
+		if bitIdx == 0 {
+			continue
+		}
+
+		bm, err := idx.values.GetCol(bitIdx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bitmap of bit %d of column %q: %w", i, colName, err)
+		}
+
+		if bm == nil {
+			return nil, fmt.Errorf("bitmap of bit %d of column %q not found", i, colName)
+		}
+
+		bits[i] = bm
+	}
+
+	return bits, nil
+}
+
+// numericRange returns all rows whose value of a numeric column is within the range, by
+// comparing the encoded values of the rows to the encoded bounds bit by bit.
+func (idx *Index) numericRange(colName string, col *column, lower, upper *RangeBound) (*roaring.Bitmap, error) {
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+
+	if lower != nil {
+		n, ok, err := lower.intBound(colName, false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return roaring.New(), nil
+		}
+		from = n
+	}
+
+	if upper != nil {
+		n, ok, err := upper.intBound(colName, true)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return roaring.New(), nil
+		}
+		to = n
+	}
+
+	if from > to {
+		return roaring.New(), nil
+	}
+
+	presence, err := idx.presence(colName, col)
+	if err != nil {
+		return nil, err
+	}
+
+	bits, err := idx.numericBitmaps(colName, col)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := presence.Clone()
+
+	if from > math.MinInt64 {
+		greater, equal := compareBits(presence, bits, encodeNumeric(from))
+		rows = roaring.Or(greater, equal)
+	}
+
+	if to < math.MaxInt64 {
+		greater, _ := compareBits(presence, bits, encodeNumeric(to))
+		rows.AndNot(greater)
+	}
+
+	return rows, nil
+}
+
+// compareBits returns the rows whose encoded value is greater than, and the rows whose encoded
+// value is equal to the provided encoded value. Starting with the most significant bit, the rows
+// that are still equal are narrowed down to the rows that have the same bit, and rows that have
+// the bit set where the value doesn't are greater.
+func compareBits(rows *roaring.Bitmap, bits []*roaring.Bitmap, encoded uint64) (greater, equal *roaring.Bitmap) {
+	greater, equal = roaring.New(), rows.Clone()
+
+	for i := numericBits - 1; i >= 0; i-- {
+		bm := bits[i]
+
+		if encoded&(1<<i) != 0 {
+			if bm == nil {
+				equal.Clear()
+			} else {
+				equal.And(bm)
+			}
+			continue
+		}
+
+		if bm != nil {
+			greater.Or(roaring.And(equal, bm))
+			equal.AndNot(bm)
+		}
+	}
+
+	return greater, equal
+}
+
+// extremum returns the lowest or the highest encoded value among the rows. Starting with
+// the most significant bit, the candidate rows are narrowed down to the rows that have the
+// bit unset for the lowest value, or set for the highest value, unless none of them has.
+func extremum(rows *roaring.Bitmap, bits []*roaring.Bitmap, highest bool) uint64 {
+	var encoded uint64
+
+	candidates := rows
+
+	for i := numericBits - 1; i >= 0; i-- {
+		if bits[i] == nil {
+			continue
+		}
+
+		set := roaring.And(candidates, bits[i])
+
+		switch {
+		case set.IsEmpty():
+			// none of the candidates has the bit set.
+		case highest:
+			candidates = set
+			encoded |= 1 << i
+		case set.GetCardinality() == candidates.GetCardinality():
+			// all of the candidates have the bit set.
+			encoded |= 1 << i
+		default:
+			candidates = roaring.AndNot(candidates, set)
+		}
+	}
+
+	return encoded
+}
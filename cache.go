@@ -2,17 +2,32 @@ package updog
 
 import (
 	"container/list"
+	"sync"
 	"unsafe"
 
 	"github.com/RoaringBitmap/roaring"
+	"github.com/cespare/xxhash/v2"
 )
 
 // Cache is the interface that needs to be fulfilled for a cache implementation.
+// The key is a hash of the index ID and the canonical encoding of an expression,
+// so a single cache can be shared between multiple indexes.
 type Cache interface {
 	Get(key uint64) (bm *roaring.Bitmap, found bool)
 	Put(key uint64, bm *roaring.Bitmap)
 }
 
+// VerifyingCache is an optional interface for cache implementations that additionally
+// receive the canonical encoding of the expression that a key was derived from, so that
+// they can verify that a cached bitmap really belongs to the requested expression. If
+// the cache of an index implements it, GetVerified and PutVerified are used instead of
+// Get and Put.
+type VerifyingCache interface {
+	Cache
+	GetVerified(key uint64, expr string) (bm *roaring.Bitmap, found bool)
+	PutVerified(key uint64, expr string, bm *roaring.Bitmap)
+}
+
 type nullCache struct{}
 
 func (c *nullCache) Get(key uint64) (*roaring.Bitmap, bool) {
@@ -22,6 +37,95 @@ func (c *nullCache) Get(key uint64) (*roaring.Bitmap, bool) {
 func (c *nullCache) Put(key uint64, bm *roaring.Bitmap) {
 }
 
+// exprKeys computes the cache keys of the expressions of a query on an index. The
+// canonical encoding of each expression is only computed once and reused for the
+// encodings of the expressions that contain it. A nil *exprKeys is used for indexes
+// without a cache, so that no canonical encodings are computed at all.
+type exprKeys struct {
+	indexID string
+	entries map[Expression]exprKey
+}
+
+type exprKey struct {
+	key       uint64
+	canonical string
+}
+
+func newExprKeys(indexID string) *exprKeys {
+	return &exprKeys{indexID: indexID, entries: make(map[Expression]exprKey)}
+}
+
+// key returns the cache key and the canonical encoding, prefixed with the index ID,
+// of the expression.
+func (k *exprKeys) key(e Expression) (uint64, string) {
+	entry := k.entry(e)
+	return entry.key, k.indexID + ":" + entry.canonical
+}
+
+// canonical returns the canonical encoding of the expression.
+func (k *exprKeys) canonical(e Expression) string {
+	return k.entry(e).canonical
+}
+
+func (k *exprKeys) entry(e Expression) exprKey {
+	if entry, ok := k.entries[e]; ok {
+		return entry
+	}
+
+	canonical := e.canonical(k)
+
+	h := xxhash.New()
+	_, _ = h.WriteString(k.indexID)
+	_, _ = h.WriteString(":")
+	_, _ = h.WriteString(canonical)
+
+	entry := exprKey{key: h.Sum64(), canonical: canonical}
+	k.entries[e] = entry
+
+	return entry
+}
+
+// exprKeys returns the exprKeys to evaluate a query with, or nil if the index has no cache.
+func (idx *Index) exprKeys() *exprKeys {
+	if _, ok := idx.cache.(*nullCache); ok {
+		return nil
+	}
+
+	return newExprKeys(idx.id)
+}
+
+// getCached returns the cached bitmap of the expression, if there is one.
+func (idx *Index) getCached(keys *exprKeys, e Expression) (*roaring.Bitmap, bool) {
+	if keys == nil {
+		return nil, false
+	}
+
+	if vc, ok := idx.cache.(VerifyingCache); ok {
+		return vc.GetVerified(keys.key(e))
+	}
+
+	key, _ := keys.key(e)
+
+	return idx.cache.Get(key)
+}
+
+// putCached stores the bitmap of the expression in the cache.
+func (idx *Index) putCached(keys *exprKeys, e Expression, bm *roaring.Bitmap) {
+	if keys == nil {
+		return
+	}
+
+	if vc, ok := idx.cache.(VerifyingCache); ok {
+		key, canonical := keys.key(e)
+		vc.PutVerified(key, canonical, bm)
+		return
+	}
+
+	key, _ := keys.key(e)
+
+	idx.cache.Put(key, bm)
+}
+
 type CounterMetric interface {
 	Inc()
 }
@@ -33,36 +137,71 @@ type CacheMetrics struct {
 	CacheMiss CounterMetric
 	GetCall   CounterMetric
 	PutCall   CounterMetric
+
+	// KeyCollision is only incremented if verification is enabled for the cache.
+	KeyCollision CounterMetric
 }
 
-// NewLRUCache returns a new LRUCache object.
+// NewLRUCache returns a new LRUCache object. The maximum size is split evenly
+// across all shards of the cache. A bitmap that is larger than the share of its
+// shard but not larger than the maximum size is still cached, by evicting all
+// other entries of its shard, so the cache can temporarily exceed its maximum
+// size by up to one such entry per shard. Bitmaps larger than the maximum size
+// are never cached.
 func NewLRUCache(maxSizeBytes uint64, opts ...LRUCacheOption) *LRUCache {
 	cache := &LRUCache{
-		entries: make(map[uint64]*list.Element),
-		lruList: list.New(),
-		maxSize: maxSizeBytes,
-		metrics: &CacheMetrics{},
+		numShards: defaultLRUCacheShards,
+		metrics:   &CacheMetrics{},
 	}
 
 	for _, o := range opts {
 		o(cache)
 	}
 
+	if cache.numShards < 1 {
+		cache.numShards = 1
+	}
+
+	cache.shards = make([]*lruCacheShard, cache.numShards)
+
+	for i := range cache.shards {
+		cache.shards[i] = &lruCacheShard{
+			entries: make(map[uint64]*list.Element),
+			lruList: list.New(),
+			maxSize: maxSizeBytes / uint64(cache.numShards),
+		}
+	}
+
+	cache.maxSize = maxSizeBytes
+
 	return cache
 }
 
 type LRUCacheOption func(c *LRUCache)
 
+const defaultLRUCacheShards = 16
+
 // LRUCache is a size-bounded cache with a LRU cache replacement policy. You
 // have to use the NewLRUCache constructor function to create an instance of it.
+// It is safe for concurrent use. To reduce lock contention, the cache is split
+// into several shards, each with its own lock and its own LRU list.
 type LRUCache struct {
+	shards    []*lruCacheShard
+	numShards int
+	maxSize   uint64
+	verify    bool
+
+	metrics *CacheMetrics
+}
+
+type lruCacheShard struct {
+	mtx sync.Mutex
+
 	entries map[uint64]*list.Element
 	lruList *list.List
 
 	curSize uint64
 	maxSize uint64
-
-	metrics *CacheMetrics
 }
 
 // WithCacheMetrics is an option for LRUCache to set a CacheMetrics object.
@@ -72,13 +211,56 @@ func WithCacheMetrics(metrics *CacheMetrics) LRUCacheOption {
 	}
 }
 
+// WithCacheShards is an option for LRUCache to set the number of shards the
+// cache is split into. The default is 16 shards. As the maximum size of the cache
+// is split across the shards, more shards mean that more bitmaps exceed the share
+// of their shard, see NewLRUCache.
+func WithCacheShards(numShards int) LRUCacheOption {
+	return func(c *LRUCache) {
+		c.numShards = numShards
+	}
+}
+
+// WithCacheVerification is an option for LRUCache to enable verification of cache hits
+// by GetVerified. If enabled, the cache additionally stores the canonical encoding of
+// the expression for each bitmap cached by PutVerified, and on a cache hit compares it
+// to the requested one. A mismatch, i.e. a key collision, is treated like a cache miss.
+// This costs some additional memory.
+func WithCacheVerification() LRUCacheOption {
+	return func(c *LRUCache) {
+		c.verify = true
+	}
+}
+
+func (c *LRUCache) shard(key uint64) *lruCacheShard {
+	return c.shards[key%uint64(len(c.shards))]
+}
+
 // Get returns the bitmap associated with the provided key, if available.
 func (c *LRUCache) Get(key uint64) (*roaring.Bitmap, bool) {
+	return c.get(key, "", false)
+}
+
+// GetVerified returns the bitmap associated with the provided key, if available. If
+// verification is enabled, the bitmap is only returned if it was cached for the same
+// expression.
+func (c *LRUCache) GetVerified(key uint64, expr string) (*roaring.Bitmap, bool) {
+	return c.get(key, expr, c.verify)
+}
+
+func (c *LRUCache) get(key uint64, expr string, verify bool) (*roaring.Bitmap, bool) {
 	if c.metrics.GetCall != nil {
 		c.metrics.GetCall.Inc()
 	}
 
-	elem, ok := c.entries[key]
+	bm, cachedExpr, ok := c.shard(key).get(key)
+	if ok && verify && cachedExpr != expr {
+		if c.metrics.KeyCollision != nil {
+			c.metrics.KeyCollision.Inc()
+		}
+		ok = false
+	}
+
 	if !ok {
 		if c.metrics.CacheMiss != nil {
 			c.metrics.CacheMiss.Inc()
@@ -90,45 +272,98 @@ func (c *LRUCache) Get(key uint64) (*roaring.Bitmap, bool) {
 		c.metrics.CacheHit.Inc()
 	}
 
-	c.lruList.MoveToFront(elem)
-
-	item := elem.Value.(*lruCacheItem)
-
-	return item.bm, true
+	return bm, true
 }
 
 // Put stores the provided bitmap under the provided key in the cache. It ensures
 // that the maximum size of the LRU cache is kept, by evicting other cached elements
 // if necessary.
 func (c *LRUCache) Put(key uint64, bm *roaring.Bitmap) {
+	c.PutVerified(key, "", bm)
+}
+
+// PutVerified stores the provided bitmap under the provided key in the cache like Put.
+// If verification is enabled, the expression is stored as well.
+func (c *LRUCache) PutVerified(key uint64, expr string, bm *roaring.Bitmap) {
 	if c.metrics.PutCall != nil {
 		c.metrics.PutCall.Inc()
 	}
 
-	if elem, ok := c.entries[key]; ok {
-		c.lruList.MoveToFront(elem)
-		item := elem.Value.(*lruCacheItem)
-		item.bm = bm
+	if !c.verify {
+		expr = ""
+	}
+
+	shard := c.shard(key)
+
+	if entrySize(expr, bm) > c.maxSize {
+		shard.remove(key)
 		return
 	}
 
+	shard.put(key, expr, bm)
+}
+
+func (s *lruCacheShard) get(key uint64) (*roaring.Bitmap, string, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, "", false
+	}
+
+	s.lruList.MoveToFront(elem)
+
+	item := elem.Value.(*lruCacheItem)
+
+	return item.bm, item.expr, true
+}
+
+func (s *lruCacheShard) put(key uint64, expr string, bm *roaring.Bitmap) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		item := s.lruList.Remove(elem).(*lruCacheItem)
+		s.curSize -= item.size
+	}
+
 	item := &lruCacheItem{
 		key:  key,
-		size: bm.GetSizeInBytes(),
+		expr: expr,
+		size: entrySize(expr, bm),
 		bm:   bm,
 	}
 
-	c.entries[key] = c.lruList.PushFront(item)
+	s.entries[key] = s.lruList.PushFront(item)
 
-	c.curSize += item.size + uint64(lruCacheItemSize) + uint64(listElementSize)
+	s.curSize += item.size
 
-	for c.curSize > c.maxSize && c.lruList.Len() > 0 {
-		item := c.lruList.Remove(c.lruList.Back()).(*lruCacheItem)
-		c.curSize -= item.size + uint64(lruCacheItemSize) + uint64(listElementSize)
-		delete(c.entries, item.key)
+	// the new item itself is never evicted, even if it exceeds the maximum size of the
+	// shard on its own.
+	for s.curSize > s.maxSize && s.lruList.Len() > 1 {
+		item := s.lruList.Remove(s.lruList.Back()).(*lruCacheItem)
+		s.curSize -= item.size
+		delete(s.entries, item.key)
 	}
 }
 
+func (s *lruCacheShard) remove(key uint64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		item := s.lruList.Remove(elem).(*lruCacheItem)
+		s.curSize -= item.size
+		delete(s.entries, key)
+	}
+}
+
+// entrySize returns the memory used by a cache entry, including the overhead of the cache.
+func entrySize(expr string, bm *roaring.Bitmap) uint64 {
+	return bm.GetSizeInBytes() + uint64(len(expr)) + uint64(lruCacheItemSize) + uint64(listElementSize)
+}
+
 var (
 	lruCacheItemSize = unsafe.Sizeof(lruCacheItem{})
 	listElementSize  = unsafe.Sizeof(list.Element{})
@@ -136,6 +371,7 @@ var (
 
 type lruCacheItem struct {
 	key  uint64
+	expr string
 	size uint64
 	bm   *roaring.Bitmap
 }
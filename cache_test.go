@@ -0,0 +1,123 @@
+package updog
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestLRUCacheVerification(t *testing.T) {
+	bm := roaring.BitmapOf(1, 2, 3)
+
+	cache := NewLRUCache(1024 * 1024)
+	cache.PutVerified(42, "(EQUAL \"a\" \"1\")", bm)
+
+	cachedBM, ok := cache.GetVerified(42, "(EQUAL \"b\" \"2\")")
+	require.True(t, ok, "without verification, a colliding key should return the cached bitmap")
+	require.Equal(t, bm, cachedBM)
+
+	verifyingCache := NewLRUCache(1024*1024, WithCacheVerification())
+	verifyingCache.PutVerified(42, "(EQUAL \"a\" \"1\")", bm)
+
+	_, ok = verifyingCache.GetVerified(42, "(EQUAL \"b\" \"2\")")
+	require.False(t, ok, "with verification, a colliding key should be a cache miss")
+
+	cachedBM, ok = verifyingCache.GetVerified(42, "(EQUAL \"a\" \"1\")")
+	require.True(t, ok)
+	require.Equal(t, bm, cachedBM)
+
+	cachedBM, ok = verifyingCache.Get(42)
+	require.True(t, ok, "Get should not verify the expression")
+	require.Equal(t, bm, cachedBM)
+}
+
+// plainCache is a Cache that doesn't implement VerifyingCache.
+type plainCache struct {
+	bitmaps map[uint64]*roaring.Bitmap
+}
+
+func (c *plainCache) Get(key uint64) (*roaring.Bitmap, bool) {
+	bm, ok := c.bitmaps[key]
+	return bm, ok
+}
+
+func (c *plainCache) Put(key uint64, bm *roaring.Bitmap) {
+	c.bitmaps[key] = bm
+}
+
+func TestPlainCache(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+	for _, v := range []string{"a", "b", "a"} {
+		idxWriter.AddRow(map[string]string{"col": v})
+	}
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	cache := &plainCache{bitmaps: map[uint64]*roaring.Bitmap{}}
+
+	idx, err := OpenIndexFromBoltDatabase(db, WithCache(cache))
+	require.NoError(t, err)
+
+	q := &Query{Expr: &ExprOr{Exprs: []Expression{&ExprEqual{Column: "col", Value: "a"}, &ExprNot{Expr: &ExprEqual{Column: "col", Value: "a"}}}}}
+
+	result, err := idx.Execute(q)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), result.Count)
+	require.Len(t, cache.bitmaps, 3)
+
+	result, err = idx.Execute(q)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), result.Count)
+}
+
+func TestLRUCacheLargeEntries(t *testing.T) {
+	bm := roaring.New()
+	bm.AddRange(0, 100000)
+	for i := uint32(0); i < 100000; i += 2 {
+		bm.Remove(i)
+	}
+
+	size := entrySize("", bm)
+
+	// the bitmap exceeds the share of its shard, but fits into the cache.
+	cache := NewLRUCache(2 * size)
+	require.Greater(t, size, cache.shards[0].maxSize)
+
+	cache.Put(1, roaring.BitmapOf(1))
+	cache.Put(uint64(len(cache.shards))+1, bm)
+
+	cachedBM, ok := cache.Get(uint64(len(cache.shards)) + 1)
+	require.True(t, ok, "a bitmap that fits into the cache should be cached even if it exceeds the share of its shard")
+	require.Equal(t, bm, cachedBM)
+
+	_, ok = cache.Get(1)
+	require.False(t, ok, "other entries of the shard should have been evicted")
+
+	// replacing the entry with a bitmap that doesn't fit into the cache removes it.
+	tooLarge := roaring.New()
+	tooLarge.Or(bm)
+	tooLarge.AddMany([]uint32{200000, 400000, 600000, 800000, 1000000, 1200000, 1400000})
+	cache = NewLRUCache(size)
+	cache.Put(7, bm)
+	_, ok = cache.Get(7)
+	require.True(t, ok)
+
+	cache.Put(7, tooLarge)
+	_, ok = cache.Get(7)
+	require.False(t, ok, "a bitmap larger than the cache should never be cached")
+	require.Zero(t, cache.shards[7%len(cache.shards)].curSize)
+}
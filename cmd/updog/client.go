@@ -53,9 +53,16 @@ func clientCmd(cfg *clientConfig, queries []string) error {
 		}
 
 		fmt.Printf("Query %d:\n", result.QueryId)
-		fmt.Printf("\tTotal count: %d\n", result.TotalCount)
+		if result.Error != "" {
+			fmt.Printf("\tError: %s\n", result.Error)
+			continue
+		}
+		for _, warning := range result.Warnings {
+			fmt.Printf("\tWarning: %s\n", warning)
+		}
+		fmt.Printf("\tTotal count: %d%s%s\n", result.TotalCount, formatDistinctCounts(result.Distinct), formatAggregates(result.Aggregates))
 		for _, group := range result.Groups {
-			fmt.Printf("\tGroup %s: %d\n", formatGroupFields(group.Fields), group.Count)
+			fmt.Printf("\tGroup %s: %d%s%s\n", formatGroupFields(group.Fields), group.Count, formatDistinctCounts(group.Distinct), formatAggregates(group.Aggregates))
 		}
 	}
 
@@ -91,3 +98,36 @@ func parseQueries(queries []string) (parsedQueries []*proto.Query, err error) {
 
 	return parsedQueries, nil
 }
+
+func formatDistinctCounts(counts []*proto.Result_DistinctCount) string {
+	var buf strings.Builder
+
+	for _, c := range counts {
+		approx := ""
+		if c.Approximate {
+			approx = "~"
+		}
+		fmt.Fprintf(&buf, ", distinct %s: %s%d", c.Column, approx, c.Count)
+	}
+
+	return buf.String()
+}
+
+func formatAggregates(values []*proto.Result_AggregateValue) string {
+	var buf strings.Builder
+
+	for _, v := range values {
+		fmt.Fprintf(&buf, ", %s(%s): ", strings.TrimPrefix(v.Function.String(), "FUNCTION_"), v.Column)
+
+		switch {
+		case !v.Valid:
+			buf.WriteString("NULL")
+		case v.Function == proto.Query_Aggregate_FUNCTION_AVG:
+			buf.WriteString(strconv.FormatFloat(v.FloatValue, 'g', -1, 64))
+		default:
+			buf.WriteString(strconv.FormatInt(v.IntValue, 10))
+		}
+	}
+
+	return buf.String()
+}
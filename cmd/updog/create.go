@@ -17,10 +17,21 @@ type createConfig struct {
 	outputFile string
 	inputFile  string
 	big        bool
+	append     bool
+
+	multiValueSeparator string
+	multiValueColumns   []string
+	emptyAsMissing      bool
+
+	numericColumns []string
+	columnTypes    []string
+	inferTypes     bool
 }
 
 type indexWriter interface {
-	AddRow(values map[string]string) (uint32, error)
+	SetNumericColumn(name string) error
+	SetColumnType(name string, typ updog.ColumnType) error
+	AddRowMulti(values map[string][]string) (uint32, error)
 	Flush() error
 }
 
@@ -40,6 +51,11 @@ func createCmd(globalCfg *globalConfig, cfg *createConfig) error {
 
 	header = normalizeHeader(header)
 
+	multiValueColumns := map[string]bool{}
+	for _, col := range normalizeHeader(cfg.multiValueColumns) {
+		multiValueColumns[col] = true
+	}
+
 	var iw indexWriter
 
 	if cfg.big {
@@ -56,23 +72,96 @@ func createCmd(globalCfg *globalConfig, cfg *createConfig) error {
 		}
 		defer tempDB.Close()
 
-		db, err := bbolt.Open(cfg.outputFile, 0644, &bbolt.Options{OpenFile: openfile.OpenFile(openfile.Options{FailIfFileExists: true})})
+		db, err := bbolt.Open(cfg.outputFile, 0644, &bbolt.Options{OpenFile: openfile.OpenFile(openfile.Options{
+			FailIfFileExists:      !cfg.append,
+			FailIfFileDoesntExist: cfg.append,
+		})})
 		if err != nil {
 			return fmt.Errorf("failed to open output file: %w", err)
 		}
 		defer db.Close()
 
-		idx, err := updog.NewBigIndexWriter(db, tempDB)
+		var idx *updog.BigIndexWriter
+
+		if cfg.append {
+			idx, err = updog.OpenBigIndexWriterForAppend(db, tempDB)
+		} else {
+			idx, err = updog.NewBigIndexWriter(db, tempDB)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to create big index writer: %w", err)
 		}
 
+		iw = idx
+	} else if cfg.append {
+		idx, err := updog.OpenIndexWriterForAppend(cfg.outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to open index for appending: %w", err)
+		}
 		iw = idx
 	} else {
 		idx := updog.NewIndexWriter(cfg.outputFile)
 		iw = idx
 	}
 
+	numericColumns := map[string]bool{}
+	for _, col := range normalizeHeader(cfg.numericColumns) {
+		if err := iw.SetNumericColumn(col); err != nil {
+			return fmt.Errorf("failed to declare numeric column: %w", err)
+		}
+		numericColumns[col] = true
+	}
+
+	columnTypes := map[string]updog.ColumnType{}
+	for _, decl := range cfg.columnTypes {
+		name, typeName, ok := strings.Cut(decl, ":")
+		if !ok {
+			return fmt.Errorf("invalid column type declaration %q, expected name:type", decl)
+		}
+
+		typ, err := updog.ParseColumnType(typeName)
+		if err != nil {
+			return fmt.Errorf("invalid column type declaration %q: %w", decl, err)
+		}
+
+		columnTypes[normalizeHeader([]string{name})[0]] = typ
+	}
+
+	splitCell := func(k, v string) []string {
+		if cfg.multiValueSeparator != "" && !numericColumns[k] && (len(multiValueColumns) == 0 || multiValueColumns[k]) {
+			return strings.Split(v, cfg.multiValueSeparator)
+		}
+		return []string{v}
+	}
+
+	if cfg.inferTypes {
+		inferred, err := inferColumnTypes(r, header, splitCell)
+		if err != nil {
+			return err
+		}
+
+		for k, typ := range inferred {
+			if _, ok := columnTypes[k]; !ok && !numericColumns[k] && typ != updog.ColumnTypeString {
+				columnTypes[k] = typ
+			}
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind input file: %w", err)
+		}
+
+		r = csv.NewReader(f)
+		if _, err := r.Read(); err != nil {
+			return fmt.Errorf("failed to read input file header: %w", err)
+		}
+	}
+
+	for col, typ := range columnTypes {
+		if err := iw.SetColumnType(col, typ); err != nil {
+			return fmt.Errorf("failed to declare column type: %w", err)
+		}
+	}
+
 	idx := 0
 
 	for {
@@ -84,14 +173,17 @@ func createCmd(globalCfg *globalConfig, cfg *createConfig) error {
 			return fmt.Errorf("failed to read record: %w", err)
 		}
 
-		values := map[string]string{}
+		values := map[string][]string{}
 
 		for idx, v := range record {
 			k := header[idx]
-			values[k] = v
+			if v == "" && (cfg.emptyAsMissing || numericColumns[k] || columnTypes[k] != updog.ColumnTypeString) {
+				continue
+			}
+			values[k] = splitCell(k, v)
 		}
 
-		if _, err := iw.AddRow(values); err != nil {
+		if _, err := iw.AddRowMulti(values); err != nil {
 			return fmt.Errorf("failed to add row: %w", err)
 		}
 
@@ -117,6 +209,68 @@ func createCmd(globalCfg *globalConfig, cfg *createConfig) error {
 	return nil
 }
 
+// inferColumnTypes reads all records and determines the most specific type that all non-empty
+// values of each column are valid for. Integer is preferred over float, and columns without
+// any values are string columns.
+func inferColumnTypes(r *csv.Reader, header []string, splitCell func(k, v string) []string) (map[string]updog.ColumnType, error) {
+	type candidates struct {
+		seen                               bool
+		integer, float, boolean, timestamp bool
+	}
+
+	columns := make([]candidates, len(header))
+	for i := range columns {
+		columns[i] = candidates{integer: true, float: true, boolean: true, timestamp: true}
+	}
+
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+
+		for i, cell := range record {
+			if cell == "" {
+				continue
+			}
+
+			c := &columns[i]
+
+			for _, v := range splitCell(header[i], cell) {
+				c.seen = true
+				c.integer = c.integer && updog.ColumnTypeInteger.ValidValue(v)
+				c.float = c.float && updog.ColumnTypeFloat.ValidValue(v)
+				c.boolean = c.boolean && updog.ColumnTypeBoolean.ValidValue(v)
+				c.timestamp = c.timestamp && updog.ColumnTypeTimestamp.ValidValue(v)
+			}
+		}
+	}
+
+	types := map[string]updog.ColumnType{}
+
+	for i, c := range columns {
+		switch {
+		case !c.seen:
+			types[header[i]] = updog.ColumnTypeString
+		case c.integer:
+			types[header[i]] = updog.ColumnTypeInteger
+		case c.float:
+			types[header[i]] = updog.ColumnTypeFloat
+		case c.boolean:
+			types[header[i]] = updog.ColumnTypeBoolean
+		case c.timestamp:
+			types[header[i]] = updog.ColumnTypeTimestamp
+		default:
+			types[header[i]] = updog.ColumnTypeString
+		}
+	}
+
+	return types, nil
+}
+
 func normalizeHeader(header []string) []string {
 	newHeader := make([]string, 0, len(header))
 
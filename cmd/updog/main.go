@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"runtime/pprof"
 
+	"github.com/akrennmair/updog"
 	"github.com/spf13/cobra"
 )
 
@@ -92,7 +93,13 @@ func main() {
 	serverCmd.PersistentFlags().StringVarP(&serverCfg.indexFile, "index-file", "f", "out.updog", "index file to load")
 	serverCmd.PersistentFlags().BoolVarP(&serverCfg.enableCache, "enable-cache", "c", true, "enable query cache")
 	serverCmd.PersistentFlags().Uint64VarP(&serverCfg.maxCacheSize, "max-cache-size", "s", 50*1024*1024, "maximum query cache size")
+	serverCmd.PersistentFlags().BoolVar(&serverCfg.verifyCache, "verify-cache", false, "verify cache hits to detect cache key collisions")
 	serverCmd.PersistentFlags().BoolVarP(&serverCfg.enablePreloadedData, "enable-preloaded-data", "p", false, "enable preloaded data")
+	serverCmd.PersistentFlags().IntVar(&serverCfg.maxMatchValues, "max-match-values", updog.DefaultMaxMatchValues, "maximum number of values a single pattern match may expand to")
+	serverCmd.PersistentFlags().IntVar(&serverCfg.maxExactDistinctValues, "max-exact-distinct-values", updog.DefaultMaxExactDistinctValues, "maximum number of values of a column whose distinct values are counted exactly instead of estimated")
+	serverCmd.PersistentFlags().Uint64Var(&serverCfg.maxRowValuesSize, "max-row-values-size", updog.DefaultMaxRowValuesSize, "maximum memory used to keep the row values that distinct counts are estimated from")
+	serverCmd.PersistentFlags().BoolVar(&serverCfg.partialResults, "partial-results", false, "return partial results with warnings instead of failing queries when bitmaps can't be read")
+	serverCmd.PersistentFlags().IntVarP(&serverCfg.maxConcurrency, "max-concurrency", "m", runtime.NumCPU(), "maximum number of queries per request that are executed concurrently")
 
 	var clientCfg clientConfig
 
@@ -127,6 +134,13 @@ func main() {
 
 	createCmd.PersistentFlags().StringVarP(&createCfg.outputFile, "output", "o", "out.updog", "output index file")
 	createCmd.PersistentFlags().BoolVarP(&createCfg.big, "big", "b", false, "enable big mode that allows you to create files larger than the available memory, but creation will be slower")
+	createCmd.PersistentFlags().BoolVarP(&createCfg.append, "append", "a", false, "append rows to an existing output index file instead of creating a new one")
+	createCmd.PersistentFlags().StringVarP(&createCfg.multiValueSeparator, "multi-value-separator", "s", "", "separator to split cells into multiple values, e.g. for tag lists; disabled if empty")
+	createCmd.PersistentFlags().StringSliceVar(&createCfg.multiValueColumns, "multi-value-columns", nil, "columns whose cells are split into multiple values; all columns if empty")
+	createCmd.PersistentFlags().BoolVarP(&createCfg.emptyAsMissing, "empty-as-missing", "e", false, "treat empty cells as missing values instead of empty strings")
+	createCmd.PersistentFlags().StringSliceVar(&createCfg.numericColumns, "numeric-columns", nil, "columns with integer values that are stored as numeric columns to compute SUM, AVG, MIN and MAX; empty cells are missing values")
+	createCmd.PersistentFlags().StringSliceVar(&createCfg.columnTypes, "column-types", nil, "types of columns as name:type, with the types string, integer, float, boolean and timestamp; empty cells of typed columns are missing values")
+	createCmd.PersistentFlags().BoolVar(&createCfg.inferTypes, "infer-types", false, "infer the types of columns from their values, which reads the input file twice; declared types take precedence")
 
 	var schemaCfg schemaConfig
 
@@ -141,6 +155,18 @@ func main() {
 	schemaCmd.PersistentFlags().StringVarP(&schemaCfg.indexFile, "index-file", "f", "out.updog", "index file to introspect")
 	schemaCmd.PersistentFlags().BoolVar(&schemaCfg.full, "full", false, "show all available values")
 
+	var verifyCfg verifyConfig
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: `Verify the integrity of an updog index file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return verifyCmd(&verifyCfg)
+		},
+	}
+
+	verifyCmd.PersistentFlags().StringVarP(&verifyCfg.indexFile, "index-file", "f", "out.updog", "index file to verify")
+
 	var driverCfg driverConfig
 
 	driverCmd := &cobra.Command{
@@ -153,7 +179,7 @@ func main() {
 
 	driverCmd.PersistentFlags().StringVarP(&driverCfg.dsn, "dsn", "d", "", "data source name")
 
-	rootCmd.AddCommand(serverCmd, clientCmd, createCmd, schemaCmd, driverCmd)
+	rootCmd.AddCommand(serverCmd, clientCmd, createCmd, schemaCmd, verifyCmd, driverCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/akrennmair/updog"
 	"github.com/fraugster/cli"
@@ -12,8 +14,14 @@ type schemaConfig struct {
 	full      bool
 }
 
+type metadataRecord struct {
+	Property string `table:"PROPERTY"`
+	Value    string `table:"VALUE"`
+}
+
 type schemaRecord struct {
 	Column string `table:"COLUMN"`
+	Type   string `table:"TYPE"`
 	Values int    `table:"UNIQUE VALUES"`
 }
 
@@ -28,6 +36,30 @@ func schemaCmd(schemaCfg *schemaConfig) error {
 		return fmt.Errorf("failed to open index file: %w", err)
 	}
 
+	md := idx.GetMetadata()
+
+	createdAt := "unknown"
+	if !md.CreatedAt.IsZero() {
+		createdAt = md.CreatedAt.Format(time.RFC3339)
+	}
+
+	writerVersion := "unknown"
+	if md.WriterVersion != "" {
+		writerVersion = md.WriterVersion
+	}
+
+	if err := cli.Print("table", []metadataRecord{
+		{Property: "Format version", Value: strconv.Itoa(md.FormatVersion)},
+		{Property: "Writer version", Value: writerVersion},
+		{Property: "Created at", Value: createdAt},
+		{Property: "Rows", Value: strconv.FormatUint(uint64(md.Rows), 10)},
+		{Property: "Columns", Value: strconv.Itoa(len(md.Columns))},
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println()
+
 	schema := idx.GetSchema()
 
 	if schemaCfg.full {
@@ -45,7 +77,7 @@ func schemaCmd(schemaCfg *schemaConfig) error {
 	var table []schemaRecord
 
 	for _, col := range schema.Columns {
-		table = append(table, schemaRecord{Column: col.Name, Values: len(col.Values)})
+		table = append(table, schemaRecord{Column: col.Name, Type: col.Type.String(), Values: len(col.Values)})
 	}
 
 	return cli.Print("table", table)
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/pprof"
+	"sync"
 
 	"github.com/akrennmair/updog"
 	"github.com/akrennmair/updog/internal/convert"
@@ -15,6 +16,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 type serverConfig struct {
@@ -23,7 +25,14 @@ type serverConfig struct {
 	indexFile           string
 	enableCache         bool
 	maxCacheSize        uint64
+	verifyCache         bool
 	enablePreloadedData bool
+	maxConcurrency      int
+	maxMatchValues      int
+	partialResults      bool
+
+	maxExactDistinctValues int
+	maxRowValuesSize       uint64
 }
 
 func serverCmd(cfg *serverConfig) error {
@@ -62,6 +71,13 @@ func serverCmd(cfg *serverConfig) error {
 			},
 		)
 
+		keyCollisionCounter := prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "updog_server_cache_key_collisions_total",
+				Help: "Number of detected cache key collisions.",
+			},
+		)
+
 		if err := reg.Register(cacheHitCounter); err != nil {
 			return err
 		}
@@ -74,19 +90,39 @@ func serverCmd(cfg *serverConfig) error {
 		if err := reg.Register(putCallCounter); err != nil {
 			return err
 		}
+		if err := reg.Register(keyCollisionCounter); err != nil {
+			return err
+		}
+
+		cacheOpts := []updog.LRUCacheOption{
+			updog.WithCacheMetrics(&updog.CacheMetrics{
+				CacheHit:     cacheHitCounter,
+				CacheMiss:    cacheMissCounter,
+				GetCall:      getCallCounter,
+				PutCall:      putCallCounter,
+				KeyCollision: keyCollisionCounter,
+			}),
+		}
 
-		opts = append(opts, updog.WithCache(updog.NewLRUCache(cfg.maxCacheSize, updog.WithCacheMetrics(&updog.CacheMetrics{
-			CacheHit:  cacheHitCounter,
-			CacheMiss: cacheMissCounter,
-			GetCall:   getCallCounter,
-			PutCall:   putCallCounter,
-		}))))
+		if cfg.verifyCache {
+			cacheOpts = append(cacheOpts, updog.WithCacheVerification())
+		}
+
+		opts = append(opts, updog.WithCache(updog.NewLRUCache(cfg.maxCacheSize, cacheOpts...)))
 	}
 
 	if cfg.enablePreloadedData {
 		opts = append(opts, updog.WithPreloadedData())
 	}
 
+	opts = append(opts, updog.WithMaxMatchValues(cfg.maxMatchValues))
+	opts = append(opts, updog.WithMaxExactDistinctValues(cfg.maxExactDistinctValues))
+	opts = append(opts, updog.WithMaxRowValuesSize(cfg.maxRowValuesSize))
+
+	if cfg.partialResults {
+		opts = append(opts, updog.WithReadErrorPolicy(updog.PartialResultOnReadError))
+	}
+
 	executeDurationHistogram := prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:    "updog_server_query_exec_duration_seconds",
@@ -143,7 +179,7 @@ func serverCmd(cfg *serverConfig) error {
 
 	s := grpc.NewServer()
 
-	proto.RegisterQueryServiceServer(s, &server{idx: idx})
+	proto.RegisterQueryServiceServer(s, &server{idx: idx, maxConcurrency: cfg.maxConcurrency})
 
 	if err := s.Serve(l); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
@@ -154,31 +190,73 @@ func serverCmd(cfg *serverConfig) error {
 
 type server struct {
 	proto.UnimplementedQueryServiceServer
-	idx *updog.Index
+	idx            *updog.Index
+	maxConcurrency int
 }
 
 func (s *server) Query(ctx context.Context, req *proto.QueryRequest) (*proto.QueryResponse, error) {
-	var resp proto.QueryResponse
+	resp := proto.QueryResponse{
+		Results: make([]*proto.Result, len(req.Queries)),
+	}
 
-	// TODO: execute queries concurrently.
+	maxConcurrency := s.maxConcurrency
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
 
-	for idx, pbq := range req.Queries {
-		q := convert.ToQuery(pbq)
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrency)
+	)
 
+	for idx, pbq := range req.Queries {
 		qid := pbq.Id
 		if qid == 0 {
 			qid = int32(idx + 1)
 		}
 
-		result, err := s.idx.Execute(q)
-		if err != nil {
-			return nil, err
-		}
+		sem <- struct{}{}
+		wg.Add(1)
 
-		pbr := convert.ToProtobufResult(result, qid)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
 
-		resp.Results = append(resp.Results, pbr)
+			resp.Results[idx] = s.executeQuery(ctx, pbq, qid)
+		}()
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
 	}
 
 	return &resp, nil
 }
+
+// executeQuery runs a single query on the index. If the query fails, the returned
+// result only contains the query ID and the error message, so that a single failing
+// query doesn't fail all other queries of the same request.
+func (s *server) executeQuery(ctx context.Context, pbq *proto.Query, qid int32) *proto.Result {
+	q := convert.ToQuery(pbq)
+
+	result, err := s.idx.ExecuteContext(ctx, q)
+	if err != nil {
+		return &proto.Result{QueryId: qid, Error: err.Error()}
+	}
+
+	pbr := convert.ToProtobufResult(result, qid)
+
+	for _, colName := range q.GroupBy {
+		colType, err := s.idx.ColumnType(colName)
+		if err != nil {
+			return &proto.Result{QueryId: qid, Error: err.Error()}
+		}
+		pbr.GroupByTypes = append(pbr.GroupByTypes, proto.ColumnType(colType))
+	}
+
+	return pbr
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/akrennmair/updog"
+)
+
+type verifyConfig struct {
+	indexFile string
+}
+
+func verifyCmd(cfg *verifyConfig) error {
+	idx, err := updog.OpenIndex(cfg.indexFile)
+	if err != nil {
+		return fmt.Errorf("failed to open index file: %w", err)
+	}
+	defer idx.Close()
+
+	if err := idx.Verify(); err != nil {
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+
+		return fmt.Errorf("found %d problems in index file %s", len(problems), cfg.indexFile)
+	}
+
+	fmt.Printf("No problems found in index file %s\n", cfg.indexFile)
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package updog
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the type of the values of a column. Values of typed columns are validated
+// when rows are added, and stored in a canonical form, e.g. integers without leading zeros.
+// Grouped results are ordered according to the type of the column, so that "9" comes before
+// "10" in integer columns.
+type ColumnType int
+
+const (
+	// ColumnTypeString is the type of columns with arbitrary string values. It is the type of
+	// all columns whose type wasn't declared.
+	ColumnTypeString ColumnType = iota
+
+	// ColumnTypeInteger is the type of columns with 64-bit signed integer values.
+	ColumnTypeInteger
+
+	// ColumnTypeFloat is the type of columns with 64-bit floating-point values.
+	ColumnTypeFloat
+
+	// ColumnTypeBoolean is the type of columns with the values "true" and "false". All values
+	// accepted by strconv.ParseBool are valid.
+	ColumnTypeBoolean
+
+	// ColumnTypeTimestamp is the type of columns with timestamps in RFC 3339 format, or in one
+	// of the formats "2006-01-02 15:04:05", "2006-01-02T15:04:05" and "2006-01-02", which are
+	// interpreted as UTC. Timestamps are stored in UTC.
+	ColumnTypeTimestamp
+)
+
+var columnTypeNames = []string{
+	ColumnTypeString:    "string",
+	ColumnTypeInteger:   "integer",
+	ColumnTypeFloat:     "float",
+	ColumnTypeBoolean:   "boolean",
+	ColumnTypeTimestamp: "timestamp",
+}
+
+func (t ColumnType) String() string {
+	if t < 0 || int(t) >= len(columnTypeNames) {
+		return fmt.Sprintf("ColumnType(%d)", int(t))
+	}
+
+	return columnTypeNames[t]
+}
+
+// ParseColumnType returns the column type with the provided name, as returned by ColumnType.String.
+func ParseColumnType(name string) (ColumnType, error) {
+	for t, n := range columnTypeNames {
+		if strings.EqualFold(name, n) {
+			return ColumnType(t), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown column type %q", name)
+}
+
+// timestampLayouts are the layouts that values of timestamp columns are parsed with, in order.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.DateOnly,
+}
+
+func parseTimestamp(v string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+}
+
+// canonical validates a value of the type and returns its canonical form.
+func (t ColumnType) canonical(v string) (string, error) {
+	switch t {
+	case ColumnTypeInteger:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n, 10), nil
+	case ColumnTypeFloat:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case ColumnTypeBoolean:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case ColumnTypeTimestamp:
+		ts, err := parseTimestamp(v)
+		if err != nil {
+			return "", err
+		}
+		return ts.Format(time.RFC3339Nano), nil
+	default:
+		return v, nil
+	}
+}
+
+// ValidValue returns true if the value is valid for the type.
+func (t ColumnType) ValidValue(v string) bool {
+	_, err := t.canonical(v)
+	return err == nil
+}
+
+// parse returns a value of the type as int64, float64, bool, time.Time or string. Values that
+// are invalid for the type, which can only occur in columns whose type was declared after the
+// values were added, are returned as string.
+func (t ColumnType) parse(v string) any {
+	var (
+		typed any
+		err   error
+	)
+
+	switch t {
+	case ColumnTypeInteger:
+		typed, err = strconv.ParseInt(v, 10, 64)
+	case ColumnTypeFloat:
+		typed, err = strconv.ParseFloat(v, 64)
+	case ColumnTypeBoolean:
+		typed, err = strconv.ParseBool(v)
+	case ColumnTypeTimestamp:
+		typed, err = parseTimestamp(v)
+	default:
+		return v
+	}
+
+	if err != nil {
+		return v
+	}
+
+	return typed
+}
+
+// sortKey is a value of a column type that was parsed once, so that it can be compared to
+// other values of the same type repeatedly without parsing it again.
+type sortKey struct {
+	value string
+
+	// typed is set if the value is valid for a type other than ColumnTypeString, in which
+	// case the typed value is contained in n for integers and booleans, f for floats and
+	// t for timestamps.
+	typed bool
+	n     int64
+	f     float64
+	t     time.Time
+}
+
+// sortKey parses a value of the type into a sortKey.
+func (t ColumnType) sortKey(v string) sortKey {
+	key := sortKey{value: v}
+
+	switch x := t.parse(v).(type) {
+	case int64:
+		key.typed, key.n = true, x
+	case float64:
+		key.typed, key.f = true, x
+	case bool:
+		key.typed = true
+		if x {
+			key.n = 1
+		}
+	case time.Time:
+		key.typed, key.t = true, x
+	}
+
+	return key
+}
+
+// compare compares two sort keys of the type according to their typed values, e.g. numerically
+// for integers. Values that are invalid for the type are ordered before all valid values.
+func (t ColumnType) compare(a, b sortKey) int {
+	switch {
+	case !a.typed && !b.typed:
+		return strings.Compare(a.value, b.value)
+	case !a.typed:
+		return -1
+	case !b.typed:
+		return 1
+	}
+
+	switch t {
+	case ColumnTypeInteger, ColumnTypeBoolean:
+		return cmp.Compare(a.n, b.n)
+	case ColumnTypeFloat:
+		return cmp.Compare(a.f, b.f)
+	case ColumnTypeTimestamp:
+		return a.t.Compare(b.t)
+	default:
+		return strings.Compare(a.value, b.value)
+	}
+}
+
+// sortByType sorts elements by their values of the type, parsing each value only once.
+func sortByType[E any](t ColumnType, elems []E, value func(E) string) {
+	keyed := make([]struct {
+		key  sortKey
+		elem E
+	}, len(elems))
+
+	for i, e := range elems {
+		keyed[i].key, keyed[i].elem = t.sortKey(value(e)), e
+	}
+
+	slices.SortFunc(keyed, func(a, b struct {
+		key  sortKey
+		elem E
+	}) int {
+		return t.compare(a.key, b.key)
+	})
+
+	for i := range keyed {
+		elems[i] = keyed[i].elem
+	}
+}
@@ -0,0 +1,274 @@
+package updog
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/RoaringBitmap/roaring"
+	"go.etcd.io/bbolt"
+)
+
+// DeleteRows marks the rows with the provided row IDs as deleted. Deleted rows are
+// excluded from all query results. The index data of deleted rows is only removed
+// when Compact is called.
+func (idx *Index) DeleteRows(rowIDs []uint32) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	for _, rowID := range rowIDs {
+		if rowID >= idx.nextRowID {
+			return fmt.Errorf("row ID %d doesn't exist", rowID)
+		}
+	}
+
+	deleted := idx.deleted.Clone()
+	deleted.AddMany(rowIDs)
+
+	return idx.writeDeletedRows(deleted)
+}
+
+// DeleteWhere marks all rows matching the provided expression as deleted and returns
+// the number of rows that were deleted. Deleted rows are excluded from all query
+// results. The index data of deleted rows is only removed when Compact is called.
+func (idx *Index) DeleteWhere(expr Expression) (uint64, error) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	result, err := expr.eval(context.Background(), idx, idx.exprKeys())
+	if err != nil {
+		return 0, err
+	}
+
+	result = roaring.AndNot(result, idx.deleted)
+	if result.IsEmpty() {
+		return 0, nil
+	}
+
+	deleted := roaring.Or(idx.deleted, result)
+
+	if err := idx.writeDeletedRows(deleted); err != nil {
+		return 0, err
+	}
+
+	return result.GetCardinality(), nil
+}
+
+// writeDeletedRows persists the deleted rows. As this changes the results of queries,
+// the index also gets a new index ID, so that no previously cached results are used anymore.
+func (idx *Index) writeDeletedRows(deleted *roaring.Bitmap) error {
+	deleted.RunOptimize()
+
+	indexID, err := newIndexID()
+	if err != nil {
+		return err
+	}
+
+	if err := idx.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("data"))
+
+		buf, err := deleted.ToBytes()
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put(keyDeletedRows, buf); err != nil {
+			return err
+		}
+
+		return bucket.Put(keyIndexID, indexID)
+	}); err != nil {
+		return fmt.Errorf("failed to write deleted rows: %w", err)
+	}
+
+	idx.deleted = deleted
+	idx.id = hex.EncodeToString(indexID)
+
+	return nil
+}
+
+// Compact removes the deleted rows from the index data. Values that only occurred in
+// deleted rows are removed from the schema. The IDs of the remaining rows don't change,
+// and the IDs of the deleted rows are kept so that they can't reappear in query results.
+func (idx *Index) Compact() error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	if idx.deleted.IsEmpty() {
+		return nil
+	}
+
+	indexID, err := newIndexID()
+	if err != nil {
+		return err
+	}
+
+	newSchema := &schema{
+		Columns: make(map[string]*column),
+	}
+
+	metadata := *idx.metadata
+
+	if err := idx.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("data"))
+
+		presences := map[string]*roaring.Bitmap{}
+
+		for colName, col := range idx.schema.Columns {
+			newCol := &column{
+				Values:   make(map[string]uint64),
+				Presence: col.Presence,
+				Type:     col.Type,
+			}
+
+			presence := roaring.New()
+
+			if col.Numeric {
+				var err error
+				if presence, err = compactNumeric(bucket, colName, col, newCol, idx.deleted); err != nil {
+					return err
+				}
+			}
+
+			for v, valueIdx := range col.Values {
+				bm := roaring.New()
+				if err := bm.UnmarshalBinary(bucket.Get(valueKey(keyPrefixValue, valueIdx))); err != nil {
+					return fmt.Errorf("failed to decode bitmap for value %q of column %q: %w", v, colName, err)
+				}
+
+				bm.AndNot(idx.deleted)
+
+				if bm.IsEmpty() {
+					if err := deleteBitmap(bucket, valueIdx); err != nil {
+						return err
+					}
+					continue
+				}
+
+				bm.RunOptimize()
+
+				if err := putBitmap(bucket, valueIdx, bm); err != nil {
+					return err
+				}
+
+				newCol.Values[v] = valueIdx
+
+				presence.Or(bm)
+			}
+
+			// numeric and typed columns are kept even without any values, as they are
+			// declared by the writer rather than added by the rows.
+			if !presence.IsEmpty() || col.Numeric || col.Type != ColumnTypeString {
+				newSchema.Columns[colName] = newCol
+				presences[colName] = presence
+			} else if col.Presence != 0 {
+				if err := deleteBitmap(bucket, col.Presence); err != nil {
+					return err
+				}
+			}
+		}
+
+		// presence bitmaps are rebuilt from the remaining values, which also adds them
+		// to index files that were written before presence bitmaps were introduced.
+		newSchema.addMissingPresence()
+
+		for colName, presence := range presences {
+			presence.RunOptimize()
+
+			if err := putBitmap(bucket, newSchema.Columns[colName].Presence, presence); err != nil {
+				return err
+			}
+		}
+
+		metadata.FormatVersion = formatVersionCurrent
+		metadata.Columns = newSchema.columnNames()
+
+		schemaBuf, err := newSchema.marshal()
+		if err != nil {
+			return err
+		}
+
+		if err := bucket.Put(keySchema, schemaBuf); err != nil {
+			return err
+		}
+
+		if err := writeMetadata(bucket, &metadata); err != nil {
+			return err
+		}
+
+		return bucket.Put(keyIndexID, indexID)
+	}); err != nil {
+		return fmt.Errorf("failed to compact index: %w", err)
+	}
+
+	idx.schema = newSchema
+	idx.metadata = &metadata
+	idx.id = hex.EncodeToString(indexID)
+
+	idx.numericMtx.Lock()
+	idx.numericCols = nil
+	idx.timeCols = nil
+	idx.numericMtx.Unlock()
+
+	idx.rowValuesMtx.Lock()
+	idx.rowValuesCols = nil
+	idx.rowValuesOrder = nil
+	idx.rowValuesSize = 0
+	idx.rowValuesMtx.Unlock()
+
+	if _, ok := idx.values.(*preloadedColGetter); ok {
+		cg, err := newPreloadedColGetter(idx.db)
+		if err != nil {
+			return err
+		}
+
+		idx.values = cg
+	}
+
+	return nil
+}
+
+// compactNumeric removes the deleted rows from the bitmaps of the bits of a numeric column
+// and adds the remaining bits to newCol. Bitmaps of bits that are only set in deleted rows
+// are removed. It returns the presence bitmap of the column without the deleted rows.
+func compactNumeric(bucket *bbolt.Bucket, colName string, col, newCol *column, deleted *roaring.Bitmap) (*roaring.Bitmap, error) {
+	newCol.Numeric = true
+	newCol.Bits = make([]uint64, numericBits)
+
+	presence := roaring.New()
+	if err := presence.UnmarshalBinary(bucket.Get(valueKey(keyPrefixValue, col.Presence))); err != nil {
+		return nil, fmt.Errorf("failed to decode presence bitmap of column %q: %w", colName, err)
+	}
+
+	presence.AndNot(deleted)
+
+	for i, bitIdx := range col.Bits {
+		if bitIdx == 0 {
+			continue
+		}
+
+		bm := roaring.New()
+		if err := bm.UnmarshalBinary(bucket.Get(valueKey(keyPrefixValue, bitIdx))); err != nil {
+			return nil, fmt.Errorf("failed to decode bitmap for bit %d of column %q: %w", i, colName, err)
+		}
+
+		bm.AndNot(deleted)
+
+		if bm.IsEmpty() {
+			if err := deleteBitmap(bucket, bitIdx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		bm.RunOptimize()
+
+		if err := putBitmap(bucket, bitIdx, bm); err != nil {
+			return nil, err
+		}
+
+		newCol.Bits[i] = bitIdx
+	}
+
+	return presence, nil
+}
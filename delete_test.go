@@ -0,0 +1,154 @@
+package updog_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/akrennmair/updog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteRows(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := updog.NewIndexWriter(filename)
+
+	require.NoError(t, w.SetNumericColumn("n"))
+
+	for _, row := range []map[string]string{
+		{"a": "1", "b": "x", "n": "1"},
+		{"a": "2", "b": "x", "n": "2"},
+		{"a": "1", "b": "y", "n": "4"},
+		{"a": "3", "b": "z", "n": "8"},
+	} {
+		_, err := w.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Flush())
+
+	idx, err := updog.OpenIndex(filename, updog.WithCache(updog.NewLRUCache(1<<20)))
+	require.NoError(t, err)
+
+	count := func(expr updog.Expression) uint64 {
+		result, err := idx.Execute(&updog.Query{Expr: expr})
+		require.NoError(t, err)
+		return result.Count
+	}
+
+	notA1 := &updog.ExprNot{Expr: &updog.ExprEqual{Column: "a", Value: "1"}}
+
+	require.Equal(t, uint64(2), count(notA1))
+
+	require.NoError(t, idx.DeleteRows([]uint32{1}))
+	require.Error(t, idx.DeleteRows([]uint32{4}))
+
+	require.Equal(t, uint64(1), count(notA1))
+	require.Equal(t, uint64(1), count(&updog.ExprEqual{Column: "b", Value: "x"}))
+
+	deleted, err := idx.DeleteWhere(&updog.ExprEqual{Column: "b", Value: "z"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), deleted)
+
+	deleted, err = idx.DeleteWhere(&updog.ExprEqual{Column: "b", Value: "z"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), deleted)
+
+	require.Equal(t, uint64(0), count(notA1))
+
+	result, err := idx.Execute(&updog.Query{Expr: &updog.ExprEqual{Column: "a", Value: "1"}, GroupBy: []string{"b"}})
+	require.NoError(t, err)
+	require.Equal(t, &updog.Result{
+		Count: 2,
+		Groups: []updog.ResultGroup{
+			{Fields: []updog.ResultField{{Column: "b", Value: "x"}}, Count: 1},
+			{Fields: []updog.ResultField{{Column: "b", Value: "y"}}, Count: 1},
+		},
+	}, result)
+
+	sum := func() int64 {
+		result, err := idx.Execute(&updog.Query{Expr: &updog.ExprPresent{Column: "n"}, Aggregates: []updog.Aggregate{{Func: updog.AggregateSum, Column: "n"}}})
+		require.NoError(t, err)
+		return result.Aggregates[0].Int
+	}
+
+	require.Equal(t, int64(5), sum())
+
+	require.NoError(t, idx.Compact())
+
+	require.Equal(t, uint64(0), count(notA1))
+	require.Equal(t, uint64(2), count(&updog.ExprEqual{Column: "a", Value: "1"}))
+	require.Equal(t, int64(5), sum())
+	require.NoError(t, idx.Verify())
+
+	require.Equal(t, &updog.Schema{
+		Columns: []updog.SchemaColumn{
+			{Name: "a", Values: []updog.SchemaColumnValue{{Value: "1"}}},
+			{Name: "b", Values: []updog.SchemaColumnValue{{Value: "x"}, {Value: "y"}}},
+			{Name: "n", Type: updog.ColumnTypeInteger, Numeric: true},
+		},
+	}, idx.GetSchema())
+
+	require.NoError(t, idx.Close())
+
+	idx, err = updog.OpenIndex(filename, updog.WithPreloadedData())
+	require.NoError(t, err)
+	defer idx.Close()
+
+	require.Equal(t, uint64(0), count(notA1))
+	require.Equal(t, uint64(2), count(&updog.ExprEqual{Column: "a", Value: "1"}))
+	require.Equal(t, uint64(0), count(&updog.ExprEqual{Column: "a", Value: "2"}))
+	require.Equal(t, int64(5), sum())
+}
+
+func TestCompactKeepsColumnTypes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := updog.NewIndexWriter(filename)
+
+	require.NoError(t, w.SetColumnType("n", updog.ColumnTypeInteger))
+
+	for _, row := range []map[string]string{
+		{"a": "1", "n": "10"},
+		{"a": "2", "s": "x"},
+	} {
+		_, err := w.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Flush())
+
+	idx, err := updog.OpenIndex(filename)
+	require.NoError(t, err)
+
+	require.NoError(t, idx.DeleteRows([]uint32{0, 1}))
+	require.NoError(t, idx.Compact())
+
+	colType, err := idx.ColumnType("n")
+	require.NoError(t, err)
+	require.Equal(t, updog.ColumnTypeInteger, colType)
+
+	_, err = idx.ColumnType("s")
+	require.Error(t, err, "untyped columns without values should be dropped")
+
+	require.NoError(t, idx.Close())
+
+	w, err = updog.OpenIndexWriterForAppend(filename)
+	require.NoError(t, err)
+
+	_, err = w.AddRow(map[string]string{"n": "ten"})
+	require.Error(t, err, "the type of the column should be kept for appends")
+
+	_, err = w.AddRow(map[string]string{"n": "07"})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Flush())
+
+	idx, err = updog.OpenIndex(filename)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	result, err := idx.Execute(&updog.Query{Expr: &updog.ExprEqual{Column: "n", Value: "7"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+}
@@ -0,0 +1,302 @@
+package updog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/cespare/xxhash/v2"
+)
+
+// DistinctCount contains the number of distinct values of a column among the rows of a result
+// or of a grouped result.
+type DistinctCount struct {
+	Column string
+	Count  uint64
+
+	// Approximate is set if the column has more values than the index counts exactly, and
+	// the count was estimated using HyperLogLog instead.
+	Approximate bool
+}
+
+// distinctSet collects the distinct values of a column among a set of rows. It either contains
+// the values themselves, or a HyperLogLog sketch of them for columns with too many values.
+type distinctSet struct {
+	values map[string]struct{}
+	sketch *hyperLogLog
+}
+
+// merge adds all values of another set to the set. If either of the sets is a sketch, the
+// set is turned into a sketch.
+func (s *distinctSet) merge(o *distinctSet) {
+	if s.sketch == nil && o.sketch == nil {
+		for v := range o.values {
+			s.values[v] = struct{}{}
+		}
+		return
+	}
+
+	if s.sketch == nil {
+		s.sketch = newHyperLogLog()
+		for v := range s.values {
+			s.sketch.add(xxhash.Sum64String(v))
+		}
+		s.values = nil
+	}
+
+	if o.sketch != nil {
+		s.sketch.merge(o.sketch)
+		return
+	}
+
+	for v := range o.values {
+		s.sketch.add(xxhash.Sum64String(v))
+	}
+}
+
+// mergeDistinctSets merges each of the sets of src into the corresponding set of dst.
+func mergeDistinctSets(dst, src []*distinctSet) {
+	for i, set := range src {
+		dst[i].merge(set)
+	}
+}
+
+func (s *distinctSet) count(column string) DistinctCount {
+	if s.sketch != nil {
+		return DistinctCount{Column: column, Count: s.sketch.estimate(), Approximate: true}
+	}
+
+	return DistinctCount{Column: column, Count: uint64(len(s.values))}
+}
+
+// distinctSets returns the sets of distinct values of the provided columns among the rows.
+func (idx *Index) distinctSets(ctx context.Context, rows *roaring.Bitmap, columns []string) ([]*distinctSet, error) {
+	sets := make([]*distinctSet, 0, len(columns))
+
+	for _, colName := range columns {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		col, err := idx.valueColumn(colName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(col.Values) > idx.maxExactDistinctValues {
+			rv, err := idx.rowValues(colName, col)
+			if err != nil {
+				return nil, err
+			}
+
+			set, err := rv.sketch(colName, rows)
+			if err != nil {
+				return nil, err
+			}
+
+			sets = append(sets, set)
+			continue
+		}
+
+		set := &distinctSet{values: map[string]struct{}{}}
+
+		for v, valueIdx := range col.Values {
+			vbm, err := idx.values.GetCol(valueIdx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read bitmap of value %q of column %q: %w", v, colName, err)
+			}
+
+			if vbm != nil && rows.Intersects(vbm) {
+				set.values[v] = struct{}{}
+			}
+		}
+
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
+
+// rowValues maps the row IDs of an index to the values of a column. It is used to estimate
+// the distinct counts of columns with too many values to count them exactly by intersecting
+// each value's bitmap.
+type rowValues struct {
+	// hashes contains the hashes of all values of the column.
+	hashes []uint64
+
+	// rows contains the position of each row's value in hashes plus 1, or 0 if the row has
+	// no value in the column.
+	rows []uint32
+
+	// multi contains the positions of further values of rows with multiple values.
+	multi map[uint32][]uint32
+}
+
+// size returns the approximate number of bytes of memory that the mapping uses.
+func (rv *rowValues) size() uint64 {
+	size := uint64(len(rv.hashes))*8 + uint64(len(rv.rows))*4
+
+	for _, positions := range rv.multi {
+		size += 4 + uint64(len(positions))*4
+	}
+
+	return size
+}
+
+func (rv *rowValues) sketch(colName string, rows *roaring.Bitmap) (*distinctSet, error) {
+	sketch := newHyperLogLog()
+
+	it := rows.Iterator()
+	for it.HasNext() {
+		rowID := it.Next()
+
+		if rowID >= uint32(len(rv.rows)) {
+			return nil, fmt.Errorf("row ID %d is out of range for the values of column %q, as the index only has %d rows", rowID, colName, len(rv.rows))
+		}
+
+		if pos := rv.rows[rowID]; pos > 0 {
+			sketch.add(rv.hashes[pos-1])
+		}
+
+		for _, pos := range rv.multi[rowID] {
+			sketch.add(rv.hashes[pos])
+		}
+	}
+
+	return &distinctSet{sketch: sketch}, nil
+}
+
+// rowValues returns the mapping of row IDs to the values of a column. The mapping is built on
+// first use and then kept in memory, as long as the mappings of all columns fit into the limit
+// set by WithMaxRowValuesSize. If they don't, the least recently used mappings are dropped.
+func (idx *Index) rowValues(colName string, col *column) (*rowValues, error) {
+	idx.rowValuesMtx.Lock()
+	defer idx.rowValuesMtx.Unlock()
+
+	if rv, ok := idx.rowValuesCols[colName]; ok {
+		idx.touchRowValues(colName)
+		return rv, nil
+	}
+
+	rv := &rowValues{
+		hashes: make([]uint64, 0, len(col.Values)),
+		rows:   make([]uint32, idx.nextRowID),
+		multi:  map[uint32][]uint32{},
+	}
+
+	for v, valueIdx := range col.Values {
+		vbm, err := idx.values.GetCol(valueIdx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bitmap of value %q of column %q: %w", v, colName, err)
+		}
+
+		if vbm == nil {
+			continue
+		}
+
+		pos := uint32(len(rv.hashes))
+		rv.hashes = append(rv.hashes, xxhash.Sum64String(v))
+
+		it := vbm.Iterator()
+		for it.HasNext() {
+			rowID := it.Next()
+			if rowID >= idx.nextRowID {
+				return nil, fmt.Errorf("bitmap of value %q of column %q contains row ID %d, but the index only has %d rows", v, colName, rowID, idx.nextRowID)
+			}
+
+			if rv.rows[rowID] == 0 {
+				rv.rows[rowID] = pos + 1
+			} else {
+				rv.multi[rowID] = append(rv.multi[rowID], pos)
+			}
+		}
+	}
+
+	size := rv.size()
+	if size > idx.maxRowValuesSize {
+		return rv, nil
+	}
+
+	for idx.rowValuesSize+size > idx.maxRowValuesSize {
+		oldest := idx.rowValuesOrder[0]
+		idx.rowValuesOrder = idx.rowValuesOrder[1:]
+		idx.rowValuesSize -= idx.rowValuesCols[oldest].size()
+		delete(idx.rowValuesCols, oldest)
+	}
+
+	if idx.rowValuesCols == nil {
+		idx.rowValuesCols = make(map[string]*rowValues)
+	}
+
+	idx.rowValuesCols[colName] = rv
+	idx.rowValuesOrder = append(idx.rowValuesOrder, colName)
+	idx.rowValuesSize += size
+
+	return rv, nil
+}
+
+// touchRowValues marks the mapping of a column as most recently used.
+func (idx *Index) touchRowValues(colName string) {
+	i := slices.Index(idx.rowValuesOrder, colName)
+	idx.rowValuesOrder = append(slices.Delete(idx.rowValuesOrder, i, i+1), colName)
+}
+
+// hllPrecision is the number of bits of a hash that select the register of a HyperLogLog
+// sketch. With 2^12 registers, the standard error of estimates is about 1.6%.
+const hllPrecision = 12
+
+// hyperLogLog is a HyperLogLog sketch to estimate the number of distinct values.
+type hyperLogLog struct {
+	registers []uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+func (h *hyperLogLog) add(hash uint64) {
+	register := hash >> (64 - hllPrecision)
+
+	// the remaining bits are followed by a 1 bit so that the number of leading zeros is bounded.
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1))) + 1
+
+	if rank > h.registers[register] {
+		h.registers[register] = rank
+	}
+}
+
+func (h *hyperLogLog) merge(o *hyperLogLog) {
+	for i, rank := range o.registers {
+		if rank > h.registers[i] {
+			h.registers[i] = rank
+		}
+	}
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+
+	var (
+		sum   float64
+		zeros int
+	)
+
+	for _, rank := range h.registers {
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+
+	// small cardinalities are estimated more accurately by linear counting.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,47 @@
+package updog
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			h := newHyperLogLog()
+			for i := 0; i < n; i++ {
+				h.add(xxhash.Sum64String(strconv.Itoa(i)))
+			}
+
+			require.InDelta(t, float64(n), float64(h.estimate()), 0.05*float64(n)+1)
+		})
+	}
+}
+
+func TestRowValuesOutOfRange(t *testing.T) {
+	rv := &rowValues{hashes: []uint64{42}, rows: []uint32{1, 0}}
+
+	set, err := rv.sketch("x", roaring.BitmapOf(0, 1))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), set.sketch.estimate())
+
+	_, err = rv.sketch("x", roaring.BitmapOf(0, 2))
+	require.Error(t, err)
+}
+
+func TestDistinctSetMerge(t *testing.T) {
+	exact := &distinctSet{values: map[string]struct{}{"a": {}, "b": {}}}
+	exact.merge(&distinctSet{values: map[string]struct{}{"b": {}, "c": {}}})
+	require.Equal(t, DistinctCount{Column: "x", Count: 3}, exact.count("x"))
+
+	sketch := newHyperLogLog()
+	sketch.add(xxhash.Sum64String("c"))
+	sketch.add(xxhash.Sum64String("d"))
+
+	exact.merge(&distinctSet{sketch: sketch})
+	require.Equal(t, DistinctCount{Column: "x", Count: 4, Approximate: true}, exact.count("x"))
+}
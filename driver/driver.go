@@ -10,9 +10,12 @@ import (
 	"math"
 	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/akrennmair/updog"
 	"github.com/akrennmair/updog/internal/convert"
@@ -89,6 +92,16 @@ func (d *updogDriver) openFile(file string, optValues url.Values) (driver.Conn,
 		opts = append(opts, updog.WithCache(lruCache))
 	}
 
+	if maxValuesStr := optValues.Get("maxexactdistinct"); maxValuesStr != "" {
+		maxValues, err := strconv.Atoi(maxValuesStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maxexactdistinct: %v", err)
+		}
+
+		key.opts += ";maxexactdistinct=" + maxValuesStr
+		opts = append(opts, updog.WithMaxExactDistinctValues(maxValues))
+	}
+
 	d.fileConnMtx.RLock()
 	conn, ok := d.fileConnCache[key]
 	d.fileConnMtx.RUnlock()
@@ -191,6 +204,10 @@ func (c *fileConn) QueryContext(ctx context.Context, query string, args []driver
 		return nil, err
 	}
 
+	return stmt.query(ctx, namedValuesToArgs(args))
+}
+
+func namedValuesToArgs(args []driver.NamedValue) []any {
 	size := 0
 
 	for _, a := range args {
@@ -199,26 +216,81 @@ func (c *fileConn) QueryContext(ctx context.Context, query string, args []driver
 		}
 	}
 
-	values := make([]string, size)
+	values := make([]any, size)
+
+	for _, a := range args {
+		values[a.Ordinal-1] = a.Value
+	}
+
+	return values
+}
+
+func valuesToArgs(args []driver.Value) []any {
+	values := make([]any, 0, len(args))
 
 	for _, a := range args {
-		values[a.Ordinal-1] = fmt.Sprint(a.Value)
+		values = append(values, a)
 	}
 
-	return stmt.query(values)
+	return values
+}
+
+// checkNamedValue allows slices as query arguments, which are converted to lists of
+// strings, e.g. for use with placeholders in IN expressions. All other values are
+// converted using the default parameter converter.
+func checkNamedValue(nv *driver.NamedValue) error {
+	rv := reflect.ValueOf(nv.Value)
+
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		list := make([]string, 0, rv.Len())
+
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, fmt.Sprint(rv.Index(i).Interface()))
+		}
+
+		nv.Value = list
+
+		return nil
+	}
+
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+
+	nv.Value = v
+
+	return nil
 }
 
-func (stmt *fileStmt) query(values []string) (driver.Rows, error) {
-	q := queryparser.ReplacePlaceholders(stmt.q, values)
+func (c *fileConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
+func (stmt *fileStmt) query(ctx context.Context, values []any) (driver.Rows, error) {
+	q, err := queryparser.ReplacePlaceholders(stmt.q, values)
+	if err != nil {
+		return nil, err
+	}
 
 	qq := convert.ToQuery(q)
 
-	result, err := stmt.c.idx.Execute(qq)
+	result, err := stmt.c.idx.ExecuteContext(ctx, qq)
 	if err != nil {
 		return nil, err
 	}
 
-	return newRows(result, q.GroupBy), nil
+	fieldTypes := make([]updog.ColumnType, 0, len(qq.GroupBy))
+
+	for _, colName := range qq.GroupBy {
+		colType, err := stmt.c.idx.ColumnType(colName)
+		if err != nil {
+			return nil, err
+		}
+		fieldTypes = append(fieldTypes, colType)
+	}
+
+	return newRows(result, qq, fieldTypes), nil
 }
 
 func (c *fileConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
@@ -234,23 +306,8 @@ func (stmt *fileStmt) Close() error {
 	return nil
 }
 
-func numInput(q *updogv1.Query) int {
-	maxPlaceholder := int32(0)
-
-	queryparser.Walk(q, func(e *updogv1.Query_Expression) bool {
-		if v, ok := e.Value.(*updogv1.Query_Expression_Eq); ok {
-			if v.Eq.Placeholder > maxPlaceholder {
-				maxPlaceholder = v.Eq.Placeholder
-			}
-		}
-		return true
-	})
-
-	return int(maxPlaceholder)
-}
-
 func (stmt *fileStmt) NumInput() int {
-	return numInput(stmt.q)
+	return queryparser.MaxPlaceholder(stmt.q)
 }
 
 func (stmt *fileStmt) Exec(args []driver.Value) (driver.Result, error) {
@@ -258,42 +315,75 @@ func (stmt *fileStmt) Exec(args []driver.Value) (driver.Result, error) {
 }
 
 func (stmt *fileStmt) Query(args []driver.Value) (driver.Rows, error) {
-	var values []string
-
-	for _, a := range args {
-		values = append(values, fmt.Sprint(a))
-	}
+	return stmt.query(context.Background(), valuesToArgs(args))
+}
 
-	return stmt.query(values)
+func (stmt *fileStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return stmt.query(ctx, namedValuesToArgs(args))
 }
 
-func newRows(result *updog.Result, groupBy []string) *rows {
+// newRows returns the rows of a query result. fieldTypes contains the types of the columns
+// that the query groups by. If it is incomplete, e.g. because it was returned by a server that
+// doesn't report them, the types are taken from the first group of the result instead.
+func newRows(result *updog.Result, q *updog.Query, fieldTypes []updog.ColumnType) *rows {
 	r := &rows{
-		cols: append(groupBy, "count"),
+		cols:           append(slices.Clone(q.GroupBy), "count"),
+		numFields:      len(q.GroupBy),
+		fieldTypes:     fieldTypes,
+		firstAggregate: len(q.GroupBy) + 1 + 2*len(q.CountDistinct),
 	}
 
-	if len(result.Groups) > 0 {
-		for _, rr := range result.Groups {
-			fields := []string{}
-			for _, f := range rr.Fields {
-				fields = append(fields, f.Value)
+	if len(r.fieldTypes) != len(q.GroupBy) {
+		r.fieldTypes = make([]updog.ColumnType, len(q.GroupBy))
+
+		if len(result.Groups) > 0 {
+			for idx, f := range result.Groups[0].Fields {
+				if idx < len(r.fieldTypes) {
+					r.fieldTypes[idx] = f.Type
+				}
 			}
-			r.rows = append(r.rows, row{count: rr.Count, fields: fields})
+		}
+	}
+
+	for _, col := range q.CountDistinct {
+		r.cols = append(r.cols, "count_distinct_"+col, "count_distinct_"+col+"_approximate")
+	}
+
+	for _, agg := range q.Aggregates {
+		r.cols = append(r.cols, strings.ToLower(agg.Func.String())+"_"+agg.Column)
+		r.aggregates = append(r.aggregates, agg.Func)
+	}
+
+	if len(q.GroupBy) > 0 {
+		for _, rr := range result.Groups {
+			r.rows = append(r.rows, row{count: rr.Count, fields: rr.Fields, distinct: rr.Distinct, aggregates: rr.Aggregates})
 		}
 	} else {
-		r.rows = []row{{count: result.Count}}
+		r.rows = []row{{count: result.Count, distinct: result.Distinct, aggregates: result.Aggregates}}
 	}
 
 	return r
 }
 
 type row struct {
-	fields []string
-	count  uint64
+	fields     []updog.ResultField
+	count      uint64
+	distinct   []updog.DistinctCount
+	aggregates []updog.AggregateValue
 }
 
 type rows struct {
-	cols   []string
+	cols      []string
+	numFields int
+
+	// fieldTypes contains the types of the columns that the result is grouped by.
+	fieldTypes []updog.ColumnType
+
+	// firstAggregate is the index of the first column that contains the result of an
+	// aggregate function, and aggregates contains the functions of these columns.
+	firstAggregate int
+	aggregates     []updog.AggregateFunc
+
 	rows   []row
 	closed bool
 	idx    int
@@ -314,34 +404,107 @@ func (r *rows) Next(values []driver.Value) error {
 	}
 
 	for idx, f := range r.rows[r.idx].fields {
-		values[idx] = f
+		values[idx] = f.TypedValue()
+	}
+
+	values[r.numFields] = int64(r.rows[r.idx].count)
+
+	for idx, d := range r.rows[r.idx].distinct {
+		values[r.numFields+1+2*idx] = int64(d.Count)
+		values[r.numFields+2+2*idx] = d.Approximate
 	}
 
-	values[len(r.rows[r.idx].fields)] = int64(r.rows[r.idx].count)
+	for idx, a := range r.rows[r.idx].aggregates {
+		switch {
+		case !a.Valid:
+			values[r.firstAggregate+idx] = nil
+		case a.Func == updog.AggregateAvg:
+			values[r.firstAggregate+idx] = a.Float
+		default:
+			values[r.firstAggregate+idx] = a.Int
+		}
+	}
 
 	r.idx++
 
 	return nil
 }
 
+// approximate returns true if the column contains whether a distinct count is approximate.
+// Each distinct count column is followed by such a column.
+func (r *rows) approximate(index int) bool {
+	return index > r.numFields && index < r.firstAggregate && (index-r.numFields)%2 == 0
+}
+
+// aggregate returns the aggregate function of the column, if the column contains the
+// result of an aggregate function.
+func (r *rows) aggregate(index int) (updog.AggregateFunc, bool) {
+	if index < r.firstAggregate {
+		return 0, false
+	}
+
+	return r.aggregates[index-r.firstAggregate], true
+}
+
 func (r *rows) ColumnTypeScanType(index int) reflect.Type {
-	if index < len(r.cols)-1 {
-		return reflect.TypeOf("")
+	if index < r.numFields {
+		switch r.fieldTypes[index] {
+		case updog.ColumnTypeInteger:
+			return reflect.TypeOf(int64(0))
+		case updog.ColumnTypeFloat:
+			return reflect.TypeOf(float64(0))
+		case updog.ColumnTypeBoolean:
+			return reflect.TypeOf(false)
+		case updog.ColumnTypeTimestamp:
+			return reflect.TypeOf(time.Time{})
+		default:
+			return reflect.TypeOf("")
+		}
+	}
+
+	if f, ok := r.aggregate(index); ok {
+		if f == updog.AggregateAvg {
+			return reflect.TypeOf(sql.NullFloat64{})
+		}
+		return reflect.TypeOf(sql.NullInt64{})
+	}
+
+	if r.approximate(index) {
+		return reflect.TypeOf(false)
 	}
 
 	return reflect.TypeOf(int64(0))
 }
 
 func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
-	if index < len(r.cols)-1 {
-		return "TEXT"
+	if index < r.numFields {
+		switch r.fieldTypes[index] {
+		case updog.ColumnTypeInteger:
+			return "BIGINT"
+		case updog.ColumnTypeFloat:
+			return "DOUBLE"
+		case updog.ColumnTypeBoolean:
+			return "BOOLEAN"
+		case updog.ColumnTypeTimestamp:
+			return "TIMESTAMP"
+		default:
+			return "TEXT"
+		}
+	}
+
+	if f, ok := r.aggregate(index); ok && f == updog.AggregateAvg {
+		return "DOUBLE"
+	}
+
+	if r.approximate(index) {
+		return "BOOLEAN"
 	}
 
 	return "BIGINT"
 }
 
 func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
-	if index < len(r.cols)-1 {
+	if index < r.numFields && r.fieldTypes[index] == updog.ColumnTypeString {
 		return math.MaxInt64, true
 	}
 
@@ -349,7 +512,9 @@ func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
 }
 
 func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
-	return false, true
+	// aggregates are NULL if none of the rows has a value in the column.
+	_, nullable = r.aggregate(index)
+	return nullable, true
 }
 
 func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
@@ -377,6 +542,19 @@ func (c *grpcConn) prepare(query string) (*grpcStmt, error) {
 	}, nil
 }
 
+func (c *grpcConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	stmt, err := c.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.query(ctx, namedValuesToArgs(args))
+}
+
+func (c *grpcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv)
+}
+
 func (c *grpcConn) Close() error {
 	return c.conn.Close()
 }
@@ -423,19 +601,20 @@ func (stmt *grpcStmt) Exec(args []driver.Value) (driver.Result, error) {
 }
 
 func (stmt *grpcStmt) Query(args []driver.Value) (driver.Rows, error) {
-	var values []string
-
-	for _, a := range args {
-		values = append(values, fmt.Sprint(a))
-	}
+	return stmt.query(context.Background(), valuesToArgs(args))
+}
 
-	return stmt.query(values)
+func (stmt *grpcStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return stmt.query(ctx, namedValuesToArgs(args))
 }
 
-func (stmt *grpcStmt) query(values []string) (driver.Rows, error) {
-	q := queryparser.ReplacePlaceholders(stmt.q, values)
+func (stmt *grpcStmt) query(ctx context.Context, values []any) (driver.Rows, error) {
+	q, err := queryparser.ReplacePlaceholders(stmt.q, values)
+	if err != nil {
+		return nil, err
+	}
 
-	result, err := stmt.c.client.Query(context.Background(), &updogv1.QueryRequest{
+	result, err := stmt.c.client.Query(ctx, &updogv1.QueryRequest{
 		Queries: []*updogv1.Query{q},
 	})
 	if err != nil {
@@ -446,9 +625,19 @@ func (stmt *grpcStmt) query(values []string) (driver.Rows, error) {
 		return nil, fmt.Errorf("expected 1 result, got %d", len(result.Results))
 	}
 
-	return newRows(convert.ToResult(result.Results[0]), q.GroupBy), nil
+	if errMsg := result.Results[0].Error; errMsg != "" {
+		return nil, errors.New(errMsg)
+	}
+
+	var fieldTypes []updog.ColumnType
+
+	for _, t := range result.Results[0].GroupByTypes {
+		fieldTypes = append(fieldTypes, updog.ColumnType(t))
+	}
+
+	return newRows(convert.ToResult(result.Results[0]), convert.ToQuery(q), fieldTypes), nil
 }
 
 func (stmt *grpcStmt) NumInput() int {
-	return numInput(stmt.q)
+	return queryparser.MaxPlaceholder(stmt.q)
 }
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/akrennmair/updog"
 	"github.com/stretchr/testify/require"
@@ -127,3 +129,354 @@ func TestDriverPrepare(t *testing.T) {
 
 	require.NoError(t, db.Close())
 }
+
+func TestDriverQueryIn(t *testing.T) {
+	filename := fmt.Sprintf("driver_test_%x.updog", rand.Int31())
+	defer os.Remove(filename)
+
+	writer := updog.NewIndexWriter(filename)
+
+	testData := []map[string]string{
+		{"country": "DE", "c": "foo"},
+		{"country": "AT", "c": "bar"},
+		{"country": "CH", "c": "foo"},
+		{"country": "FR", "c": "quux"},
+	}
+
+	for _, row := range testData {
+		_, err := writer.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Flush())
+
+	db, err := sql.Open("updog", "file:"+filename)
+	require.NoError(t, err)
+
+	rows, err := db.Query(`country IN $1 ; c`, []string{"DE", "AT", "CH"})
+	require.NoError(t, err)
+
+	var cValues []string
+	var counts []int64
+
+	for rows.Next() {
+		var (
+			c     string
+			count int64
+		)
+
+		require.NoError(t, rows.Scan(&c, &count))
+
+		cValues = append(cValues, c)
+		counts = append(counts, count)
+	}
+	require.NoError(t, rows.Close())
+
+	require.Equal(t, []string{"bar", "foo"}, cValues)
+	require.Equal(t, []int64{1, 2}, counts)
+
+	require.NoError(t, db.Close())
+}
+
+func TestDriverCountDistinct(t *testing.T) {
+	filename := fmt.Sprintf("driver_test_%x.updog", rand.Int31())
+	defer os.Remove(filename)
+
+	writer := updog.NewIndexWriter(filename)
+
+	testData := []map[string]string{
+		{"country": "AT", "user": "1"},
+		{"country": "AT", "user": "1"},
+		{"country": "AT", "user": "2"},
+		{"country": "DE", "user": "3"},
+	}
+
+	for _, row := range testData {
+		_, err := writer.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Flush())
+
+	db, err := sql.Open("updog", "file:"+filename)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(`country != "" ; country COUNT DISTINCT user`)
+	require.NoError(t, err)
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"country", "count", "count_distinct_user", "count_distinct_user_approximate"}, columns)
+
+	colTypes, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	require.Equal(t, "BIGINT", colTypes[2].DatabaseTypeName())
+	require.Equal(t, "BOOLEAN", colTypes[3].DatabaseTypeName())
+	require.Equal(t, reflect.TypeOf(false), colTypes[3].ScanType())
+
+	var (
+		countries []string
+		counts    []int64
+		distinct  []int64
+	)
+
+	for rows.Next() {
+		var (
+			country      string
+			count, users int64
+			approximate  bool
+		)
+
+		require.NoError(t, rows.Scan(&country, &count, &users, &approximate))
+		require.False(t, approximate)
+
+		countries = append(countries, country)
+		counts = append(counts, count)
+		distinct = append(distinct, users)
+	}
+	require.NoError(t, rows.Close())
+
+	require.Equal(t, []string{"AT", "DE"}, countries)
+	require.Equal(t, []int64{3, 1}, counts)
+	require.Equal(t, []int64{2, 1}, distinct)
+
+	var (
+		count, users int64
+		approximate  bool
+	)
+
+	require.NoError(t, db.QueryRow(`country != "" ; COUNT DISTINCT user`).Scan(&count, &users, &approximate))
+	require.Equal(t, int64(4), count)
+	require.Equal(t, int64(3), users)
+	require.False(t, approximate)
+
+	// the index file can only be opened once at a time.
+	require.NoError(t, db.Close())
+
+	approxDB, err := sql.Open("updog", "file:"+filename+"?maxexactdistinct=1")
+	require.NoError(t, err)
+	defer approxDB.Close()
+
+	require.NoError(t, approxDB.QueryRow(`country != "" ; COUNT DISTINCT user`).Scan(&count, &users, &approximate))
+	require.Equal(t, int64(4), count)
+	require.True(t, approximate)
+}
+
+func TestDriverAggregates(t *testing.T) {
+	filename := fmt.Sprintf("driver_test_%x.updog", rand.Int31())
+	defer os.Remove(filename)
+
+	writer := updog.NewIndexWriter(filename)
+
+	require.NoError(t, writer.SetNumericColumn("bytes"))
+
+	testData := []map[string]string{
+		{"country": "AT", "bytes": "100"},
+		{"country": "AT", "bytes": "50"},
+		{"country": "DE"},
+	}
+
+	for _, row := range testData {
+		_, err := writer.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Flush())
+
+	db, err := sql.Open("updog", "file:"+filename)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(`country != "" ; country SUM(bytes), AVG(bytes)`)
+	require.NoError(t, err)
+
+	columns, err := rows.ColumnTypes()
+	require.NoError(t, err)
+
+	var (
+		names     []string
+		typeNames []string
+	)
+	for _, col := range columns {
+		names = append(names, col.Name())
+		typeNames = append(typeNames, col.DatabaseTypeName())
+	}
+	require.Equal(t, []string{"country", "count", "sum_bytes", "avg_bytes"}, names)
+	require.Equal(t, []string{"TEXT", "BIGINT", "BIGINT", "DOUBLE"}, typeNames)
+
+	nullable, ok := columns[2].Nullable()
+	require.True(t, ok)
+	require.True(t, nullable)
+
+	var (
+		sums []sql.NullInt64
+		avgs []sql.NullFloat64
+	)
+
+	for rows.Next() {
+		var (
+			country string
+			count   int64
+			sum     sql.NullInt64
+			avg     sql.NullFloat64
+		)
+
+		require.NoError(t, rows.Scan(&country, &count, &sum, &avg))
+
+		sums = append(sums, sum)
+		avgs = append(avgs, avg)
+	}
+	require.NoError(t, rows.Close())
+
+	require.Equal(t, []sql.NullInt64{{Int64: 150, Valid: true}, {}}, sums)
+	require.Equal(t, []sql.NullFloat64{{Float64: 75, Valid: true}, {}}, avgs)
+
+	var count, minBytes, maxBytes int64
+
+	require.NoError(t, db.QueryRow(`country != "" ; MIN(bytes), MAX(bytes)`).Scan(&count, &minBytes, &maxBytes))
+	require.Equal(t, int64(3), count)
+	require.Equal(t, int64(50), minBytes)
+	require.Equal(t, int64(100), maxBytes)
+}
+
+func TestDriverColumnTypes(t *testing.T) {
+	filename := fmt.Sprintf("driver_test_%x.updog", rand.Int31())
+	defer os.Remove(filename)
+
+	writer := updog.NewIndexWriter(filename)
+
+	require.NoError(t, writer.SetColumnType("n", updog.ColumnTypeInteger))
+	require.NoError(t, writer.SetColumnType("b", updog.ColumnTypeBoolean))
+	require.NoError(t, writer.SetColumnType("ts", updog.ColumnTypeTimestamp))
+
+	testData := []map[string]string{
+		{"n": "10", "b": "true", "ts": "2024-01-02", "s": "x"},
+		{"n": "9", "b": "false", "ts": "2024-01-03", "s": "y"},
+	}
+
+	for _, row := range testData {
+		_, err := writer.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Flush())
+
+	db, err := sql.Open("updog", "file:"+filename)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(`n != "0" ; n, b, ts, s`)
+	require.NoError(t, err)
+
+	columns, err := rows.ColumnTypes()
+	require.NoError(t, err)
+
+	var (
+		typeNames []string
+		scanTypes []reflect.Type
+	)
+	for _, col := range columns {
+		typeNames = append(typeNames, col.DatabaseTypeName())
+		scanTypes = append(scanTypes, col.ScanType())
+	}
+	require.Equal(t, []string{"BIGINT", "BOOLEAN", "TIMESTAMP", "TEXT", "BIGINT"}, typeNames)
+	require.Equal(t, []reflect.Type{reflect.TypeOf(int64(0)), reflect.TypeOf(false), reflect.TypeOf(time.Time{}), reflect.TypeOf(""), reflect.TypeOf(int64(0))}, scanTypes)
+
+	var ns []int64
+
+	for rows.Next() {
+		var (
+			n     int64
+			b     bool
+			ts    time.Time
+			s     string
+			count int64
+		)
+
+		require.NoError(t, rows.Scan(&n, &b, &ts, &s, &count))
+
+		switch n {
+		case 9:
+			require.False(t, b)
+			require.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), ts)
+		case 10:
+			require.True(t, b)
+			require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), ts)
+		}
+
+		ns = append(ns, n)
+	}
+	require.NoError(t, rows.Close())
+
+	require.Equal(t, []int64{9, 10}, ns)
+
+	// the types are known even if no rows match.
+	rows, err = db.Query(`n = "0" ; n, b, ts, s`)
+	require.NoError(t, err)
+
+	columns, err = rows.ColumnTypes()
+	require.NoError(t, err)
+
+	typeNames = nil
+	for _, col := range columns {
+		typeNames = append(typeNames, col.DatabaseTypeName())
+	}
+	require.Equal(t, []string{"BIGINT", "BOOLEAN", "TIMESTAMP", "TEXT", "BIGINT"}, typeNames)
+
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+}
+
+func TestDriverTimeBuckets(t *testing.T) {
+	filename := fmt.Sprintf("driver_test_%x.updog", rand.Int31())
+	defer os.Remove(filename)
+
+	writer := updog.NewIndexWriter(filename)
+
+	require.NoError(t, writer.SetColumnType("time", updog.ColumnTypeTimestamp))
+
+	for _, ts := range []string{"2024-01-01T08:00:00Z", "2024-01-01T17:30:00Z", "2024-01-02T09:15:00Z", "2024-01-05T00:00:00Z"} {
+		_, err := writer.AddRow(map[string]string{"time": ts})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Flush())
+
+	db, err := sql.Open("updog", "file:"+filename)
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows, err := db.Query(`time BETWEEN $1 AND $2 ; date_trunc('day', time)`, "2024-01-01T12:00:00Z", "2024-01-05")
+	require.NoError(t, err)
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"date_trunc('day', time)", "count"}, columns)
+
+	var (
+		days   []time.Time
+		counts []int64
+	)
+
+	for rows.Next() {
+		var (
+			day   time.Time
+			count int64
+		)
+
+		require.NoError(t, rows.Scan(&day, &count))
+
+		days = append(days, day)
+		counts = append(counts, count)
+	}
+	require.NoError(t, rows.Close())
+
+	require.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	}, days)
+	require.Equal(t, []int64{1, 1, 1}, counts)
+}
@@ -3,8 +3,11 @@ package updog
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/RoaringBitmap/roaring"
@@ -30,20 +33,29 @@ func OpenIndexFromBoltDatabase(db *bbolt.DB, opts ...IndexOption) (*Index, error
 	idx.db = db
 
 	err := db.View(func(tx *bbolt.Tx) error {
-		bucket := tx.Bucket([]byte("data"))
-		schemaItem := bucket.Get(keySchema)
+		state, err := readIndexState(tx)
+		if err != nil {
+			return err
+		}
 
-		var sch schema
+		idx.schema = state.schema
+		idx.nextRowID = state.nextRowID
+		idx.metadata = state.metadata
 
-		if err := gob.NewDecoder(bytes.NewReader(schemaItem)).Decode(&sch); err != nil {
-			return err
+		bucket := tx.Bucket([]byte("data"))
+
+		if indexID := bucket.Get(keyIndexID); indexID != nil {
+			idx.id = hex.EncodeToString(indexID)
 		}
 
-		idx.schema = &sch
+		idx.deleted = roaring.New()
 
-		rowsItem := bucket.Get(keyNextRowID)
+		if deletedRows := bucket.Get(keyDeletedRows); deletedRows != nil {
+			if err := idx.deleted.UnmarshalBinary(deletedRows); err != nil {
+				return fmt.Errorf("failed to decode deleted rows: %w", err)
+			}
+		}
 
-		idx.nextRowID = binary.BigEndian.Uint32(rowsItem)
 		return nil
 	})
 
@@ -52,8 +64,23 @@ func OpenIndexFromBoltDatabase(db *bbolt.DB, opts ...IndexOption) (*Index, error
 		return nil, err
 	}
 
+	// index files written by older versions don't contain an index ID. As a fallback,
+	// a random ID is generated, which ensures that cache keys are not shared with
+	// other indexes.
+	if idx.id == "" {
+		indexID, err := newIndexID()
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		idx.id = hex.EncodeToString(indexID)
+	}
+
 	idx.cache = &nullCache{}
 	idx.metrics = &IndexMetrics{}
+	idx.maxMatchValues = DefaultMaxMatchValues
+	idx.maxExactDistinctValues = DefaultMaxExactDistinctValues
+	idx.maxRowValuesSize = DefaultMaxRowValuesSize
 
 	for _, opt := range opts {
 		if err := opt(idx); err != nil {
@@ -75,13 +102,49 @@ type Index struct {
 
 	schema    *schema
 	nextRowID uint32
+	id        string
+	metadata  *Metadata
+
+	// deleted contains the IDs of all rows that have been deleted.
+	deleted *roaring.Bitmap
 
 	db *bbolt.DB
 
 	values colGetter
 
+	numericMtx  sync.Mutex
+	numericCols map[string][]numericValue
+	timeCols    map[string][]timeValue
+
+	rowValuesMtx     sync.Mutex
+	rowValuesCols    map[string]*rowValues
+	rowValuesOrder   []string
+	rowValuesSize    uint64
+	maxRowValuesSize uint64
+
 	cache   Cache
 	metrics *IndexMetrics
+
+	maxMatchValues int
+
+	maxExactDistinctValues int
+
+	readErrorPolicy ReadErrorPolicy
+
+	allowMissingColumns bool
+}
+
+// ColumnType returns the type of the column with the provided name.
+func (idx *Index) ColumnType(colName string) (ColumnType, error) {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+
+	col, ok := idx.schema.Columns[colName]
+	if !ok {
+		return 0, fmt.Errorf("column %q not found in schema", colName)
+	}
+
+	return col.Type, nil
 }
 
 func (idx *Index) GetSchema() *Schema {
@@ -92,14 +155,16 @@ func (idx *Index) GetSchema() *Schema {
 
 	for colName, col := range idx.schema.Columns {
 		schCol := SchemaColumn{
-			Name: colName,
+			Name:    colName,
+			Type:    col.Type,
+			Numeric: col.Numeric,
 		}
 
 		for v := range col.Values {
 			schCol.Values = append(schCol.Values, SchemaColumnValue{Value: v})
 		}
 
-		sort.Slice(schCol.Values, func(i, j int) bool { return schCol.Values[i].Value < schCol.Values[j].Value })
+		sortByType(col.Type, schCol.Values, func(v SchemaColumnValue) string { return v.Value })
 
 		cols = append(cols, schCol)
 	}
@@ -116,14 +181,109 @@ type Schema struct {
 }
 
 type SchemaColumn struct {
-	Name   string
+	Name string
+	Type ColumnType
+
+	// Values contains the values of the column, ordered according to the type of the column.
 	Values []SchemaColumnValue
+
+	// Numeric is set if the column is a numeric column. Numeric columns don't have a list
+	// of values.
+	Numeric bool
 }
 
 type SchemaColumnValue struct {
 	Value string
 }
 
+// column returns the column with the provided name from the schema. If the index is
+// a segment of a SegmentedIndex, a missing column is not an error, as other segments
+// may contain it, and an empty column is returned instead.
+func (idx *Index) column(colName string) (*column, error) {
+	col, ok := idx.schema.Columns[colName]
+	if !ok {
+		if idx.allowMissingColumns {
+			return &column{}, nil
+		}
+		return nil, fmt.Errorf("column %q not found in schema", colName)
+	}
+
+	return col, nil
+}
+
+// valueColumn returns the column with the provided name like column, but returns an error
+// if the column is numeric, as numeric columns don't have a bitmap per value that could be
+// used to match or group by values.
+func (idx *Index) valueColumn(colName string) (*column, error) {
+	col, err := idx.column(colName)
+	if err != nil {
+		return nil, err
+	}
+
+	if col.Numeric {
+		return nil, fmt.Errorf("column %q is numeric and can only be used in aggregates, ranges and presence checks", colName)
+	}
+
+	return col, nil
+}
+
+// valueIndex returns the value index key of a value of the column, if the column has the
+// value. Values of typed columns are looked up in their canonical form, so that e.g. "07"
+// matches the value 7 of an integer column.
+func (col *column) valueIndex(colName, v string) (uint64, bool, error) {
+	canonical, err := col.Type.canonical(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid value %q of %s column %q: %w", v, col.Type, colName, err)
+	}
+
+	valueIdx, ok := col.Values[canonical]
+
+	return valueIdx, ok, nil
+}
+
+type numericValue struct {
+	num float64
+	idx uint64
+}
+
+// numericValues returns all values of a column that are numbers, sorted in ascending
+// numerical order. The result is computed on first use and then kept for later use.
+func (idx *Index) numericValues(colName string) ([]numericValue, error) {
+	col, err := idx.valueColumn(colName)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.numericMtx.Lock()
+	defer idx.numericMtx.Unlock()
+
+	if values, ok := idx.numericCols[colName]; ok {
+		return values, nil
+	}
+
+	values := []numericValue{}
+
+	for v, valueIdx := range col.Values {
+		// NaN can't be ordered, so it is never within a range.
+		num, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(num) {
+			continue
+		}
+
+		values = append(values, numericValue{num: num, idx: valueIdx})
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].num < values[j].num })
+
+	if idx.numericCols == nil {
+		idx.numericCols = make(map[string][]numericValue)
+	}
+
+	idx.numericCols[colName] = values
+
+	return values, nil
+}
+
 type colGetter interface {
 	GetCol(key uint64) (*roaring.Bitmap, error)
 }
@@ -131,7 +291,8 @@ type colGetter interface {
 type IndexOption func(idx *Index) error
 
 // WithCache is an option for OpenIndex and OpenIndexFromBoltDatabase to set
-// a cache for caching queries, including partial queries.
+// a cache for caching queries, including partial queries. The same cache can
+// be shared between multiple indexes, as cache keys are scoped per index.
 func WithCache(cache Cache) IndexOption {
 	return func(idx *Index) error {
 		idx.cache = cache
@@ -186,6 +347,71 @@ func (g *onDemandColGetter) GetCol(key uint64) (*roaring.Bitmap, error) {
 	return bm, nil
 }
 
+// DefaultMaxMatchValues is the default maximum number of values a single ExprMatch
+// expression may expand to.
+const DefaultMaxMatchValues = 10000
+
+// WithMaxMatchValues is an option for OpenIndex and OpenIndexFromBoltDatabase to set
+// the maximum number of values a single ExprMatch expression may expand to. Queries
+// with patterns that match more values fail with an error.
+func WithMaxMatchValues(maxValues int) IndexOption {
+	return func(idx *Index) error {
+		idx.maxMatchValues = maxValues
+		return nil
+	}
+}
+
+// DefaultMaxExactDistinctValues is the default maximum number of values of a column whose
+// distinct values are counted exactly.
+const DefaultMaxExactDistinctValues = 10000
+
+// WithMaxExactDistinctValues is an option for OpenIndex and OpenIndexFromBoltDatabase to set
+// the maximum number of values of a column whose distinct values are counted exactly. The
+// distinct values of columns with more values are estimated using HyperLogLog instead.
+func WithMaxExactDistinctValues(maxValues int) IndexOption {
+	return func(idx *Index) error {
+		idx.maxExactDistinctValues = maxValues
+		return nil
+	}
+}
+
+// DefaultMaxRowValuesSize is the default maximum number of bytes of memory used to keep the
+// mappings of row IDs to values that distinct counts of columns with many values are estimated from.
+const DefaultMaxRowValuesSize = 64 * 1024 * 1024
+
+// WithMaxRowValuesSize is an option for OpenIndex and OpenIndexFromBoltDatabase to set the
+// maximum number of bytes of memory used to keep the mappings of row IDs to values that are
+// built to estimate the distinct counts of columns with more values than are counted exactly.
+// Each mapping takes about 4 bytes per row of the index. If the mappings of all columns don't
+// fit, the least recently used ones are dropped and rebuilt when they are needed again.
+func WithMaxRowValuesSize(maxSizeBytes uint64) IndexOption {
+	return func(idx *Index) error {
+		idx.maxRowValuesSize = maxSizeBytes
+		return nil
+	}
+}
+
+// ReadErrorPolicy determines how queries handle bitmaps that can't be read while grouping results.
+type ReadErrorPolicy int
+
+const (
+	// FailOnReadError makes queries fail if a bitmap can't be read. This is the default policy.
+	FailOnReadError ReadErrorPolicy = iota
+
+	// PartialResultOnReadError makes queries leave out all groups whose bitmaps can't be read,
+	// and report a warning for each of these bitmaps in the query result.
+	PartialResultOnReadError
+)
+
+// WithReadErrorPolicy is an option for OpenIndex and OpenIndexFromBoltDatabase to set
+// the policy how queries handle bitmaps that can't be read while grouping results.
+func WithReadErrorPolicy(policy ReadErrorPolicy) IndexOption {
+	return func(idx *Index) error {
+		idx.readErrorPolicy = policy
+		return nil
+	}
+}
+
 // WithPreloadedData is an option for OpenIndex and OpenIndexFromBoltDatabase to preload
 // all data into memory to allow for faster queries. Only use this if all data from
 // the index file will fit into the available memory.
@@ -219,6 +445,38 @@ type HistogramMetric interface {
 	Observe(float64)
 }
 
+// presence returns the bitmap of all rows that have a value in the column. For index files
+// that were written before presence bitmaps were introduced, it is the union of the bitmaps
+// of all values of the column.
+func (idx *Index) presence(colName string, col *column) (*roaring.Bitmap, error) {
+	if col.Presence == 0 {
+		elems := make([]*roaring.Bitmap, 0, len(col.Values))
+
+		for v, valueIdx := range col.Values {
+			bm, err := idx.values.GetCol(valueIdx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read bitmap of value %q of column %q: %w", v, colName, err)
+			}
+			if bm != nil {
+				elems = append(elems, bm)
+			}
+		}
+
+		return roaring.FastOr(elems...), nil
+	}
+
+	bm, err := idx.values.GetCol(col.Presence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presence bitmap of column %q: %w", colName, err)
+	}
+
+	if bm == nil {
+		return nil, fmt.Errorf("presence bitmap of column %q not found", colName)
+	}
+
+	return bm, nil
+}
+
 func newPreloadedColGetter(db *bbolt.DB) (colGetter, error) {
 	cg := &preloadedColGetter{
 		values: map[uint64]*roaring.Bitmap{},
@@ -7,8 +7,69 @@ import (
 
 func ToQuery(pbq *proto.Query) *updog.Query {
 	return &updog.Query{
-		Expr:    toExpr(pbq.Expr),
-		GroupBy: pbq.GroupBy,
+		Expr:     toExpr(pbq.Expr),
+		GroupBy:  pbq.GroupBy,
+		MinCount: pbq.MinCount,
+		OrderBy:  toOrderBy(pbq.OrderBy),
+		Limit:    int(pbq.Limit),
+		Offset:   int(pbq.Offset),
+
+		CountDistinct: pbq.CountDistinct,
+		Aggregates:    toAggregates(pbq.Aggregates),
+	}
+}
+
+func toAggregates(pba []*proto.Query_Aggregate) []updog.Aggregate {
+	var aggs []updog.Aggregate
+
+	for _, a := range pba {
+		aggs = append(aggs, updog.Aggregate{
+			Func:   toAggregateFunc(a.Function),
+			Column: a.Column,
+		})
+	}
+
+	return aggs
+}
+
+func toAggregateFunc(f proto.Query_Aggregate_Function) updog.AggregateFunc {
+	switch f {
+	case proto.Query_Aggregate_FUNCTION_SUM:
+		return updog.AggregateSum
+	case proto.Query_Aggregate_FUNCTION_AVG:
+		return updog.AggregateAvg
+	case proto.Query_Aggregate_FUNCTION_MIN:
+		return updog.AggregateMin
+	case proto.Query_Aggregate_FUNCTION_MAX:
+		return updog.AggregateMax
+	default:
+		return 0
+	}
+}
+
+func toProtobufAggregateFunc(f updog.AggregateFunc) proto.Query_Aggregate_Function {
+	switch f {
+	case updog.AggregateSum:
+		return proto.Query_Aggregate_FUNCTION_SUM
+	case updog.AggregateAvg:
+		return proto.Query_Aggregate_FUNCTION_AVG
+	case updog.AggregateMin:
+		return proto.Query_Aggregate_FUNCTION_MIN
+	case updog.AggregateMax:
+		return proto.Query_Aggregate_FUNCTION_MAX
+	default:
+		return proto.Query_Aggregate_FUNCTION_UNSPECIFIED
+	}
+}
+
+func toOrderBy(pbo *proto.Query_OrderBy) *updog.OrderBy {
+	if pbo == nil {
+		return nil
+	}
+
+	return &updog.OrderBy{
+		Column:     pbo.Column,
+		Descending: pbo.Descending,
 	}
 }
 
@@ -19,6 +80,21 @@ func toExpr(pbe *proto.Query_Expression) updog.Expression {
 			Column: v.Eq.Column,
 			Value:  v.Eq.Value,
 		}
+	case *proto.Query_Expression_Ne:
+		// like in SQL, rows without a value in the column don't match.
+		return &updog.ExprAnd{
+			Exprs: []updog.Expression{
+				&updog.ExprPresent{
+					Column: v.Ne.Column,
+				},
+				&updog.ExprNot{
+					Expr: &updog.ExprEqual{
+						Column: v.Ne.Column,
+						Value:  v.Ne.Value,
+					},
+				},
+			},
+		}
 	case *proto.Query_Expression_Not_:
 		return &updog.ExprNot{
 			Expr: toExpr(v.Not.Expr),
@@ -35,13 +111,68 @@ func toExpr(pbe *proto.Query_Expression) updog.Expression {
 			e.Exprs = append(e.Exprs, toExpr(ee))
 		}
 		return e
+	case *proto.Query_Expression_In_:
+		return &updog.ExprIn{
+			Column: v.In.Column,
+			Values: v.In.Values,
+		}
+	case *proto.Query_Expression_Match_:
+		return &updog.ExprMatch{
+			Column:  v.Match.Column,
+			Type:    toMatchType(v.Match.Type),
+			Pattern: v.Match.Pattern,
+		}
+	case *proto.Query_Expression_Missing_:
+		return &updog.ExprMissing{
+			Column: v.Missing.Column,
+		}
+	case *proto.Query_Expression_Present_:
+		return &updog.ExprPresent{
+			Column: v.Present.Column,
+		}
+	case *proto.Query_Expression_Range_:
+		return &updog.ExprRange{
+			Column: v.Range.Column,
+			Lower:  toRangeBound(v.Range.Lower),
+			Upper:  toRangeBound(v.Range.Upper),
+		}
 	default:
 		return nil
 	}
 }
 
+func toRangeBound(pbb *proto.Query_Expression_Range_Bound) *updog.RangeBound {
+	if pbb == nil {
+		return nil
+	}
+
+	return &updog.RangeBound{
+		Value:     pbb.Value,
+		Exclusive: pbb.Exclusive,
+	}
+}
+
+func toMatchType(t proto.Query_Expression_Match_Type) updog.MatchType {
+	switch t {
+	case proto.Query_Expression_Match_TYPE_PREFIX:
+		return updog.MatchPrefix
+	case proto.Query_Expression_Match_TYPE_SUFFIX:
+		return updog.MatchSuffix
+	case proto.Query_Expression_Match_TYPE_REGEXP:
+		return updog.MatchRegexp
+	default:
+		return 0
+	}
+}
+
 func ToProtobufResult(result *updog.Result, qid int32) *proto.Result {
-	pbr := &proto.Result{QueryId: qid, TotalCount: result.Count}
+	pbr := &proto.Result{
+		QueryId:    qid,
+		TotalCount: result.Count,
+		Warnings:   result.Warnings,
+		Distinct:   toProtobufDistinct(result.Distinct),
+		Aggregates: toProtobufAggregates(result.Aggregates),
+	}
 
 	for _, g := range result.Groups {
 		fields := []*proto.Result_Group_ResultField{}
@@ -50,30 +181,71 @@ func ToProtobufResult(result *updog.Result, qid int32) *proto.Result {
 			fields = append(fields, &proto.Result_Group_ResultField{
 				Column: f.Column,
 				Value:  f.Value,
+				Type:   proto.ColumnType(f.Type),
 			})
 		}
 
 		pbr.Groups = append(pbr.Groups, &proto.Result_Group{
-			Count:  g.Count,
-			Fields: fields,
+			Count:      g.Count,
+			Fields:     fields,
+			Distinct:   toProtobufDistinct(g.Distinct),
+			Aggregates: toProtobufAggregates(g.Aggregates),
 		})
 	}
 
 	return pbr
 }
 
+func toProtobufDistinct(counts []updog.DistinctCount) []*proto.Result_DistinctCount {
+	var pbc []*proto.Result_DistinctCount
+
+	for _, c := range counts {
+		pbc = append(pbc, &proto.Result_DistinctCount{
+			Column:      c.Column,
+			Count:       c.Count,
+			Approximate: c.Approximate,
+		})
+	}
+
+	return pbc
+}
+
+func toProtobufAggregates(values []updog.AggregateValue) []*proto.Result_AggregateValue {
+	var pbv []*proto.Result_AggregateValue
+
+	for _, v := range values {
+		pbv = append(pbv, &proto.Result_AggregateValue{
+			Function:   toProtobufAggregateFunc(v.Func),
+			Column:     v.Column,
+			Valid:      v.Valid,
+			IntValue:   v.Int,
+			FloatValue: v.Float,
+		})
+	}
+
+	return pbv
+}
+
 func ToResult(pr *proto.Result) *updog.Result {
-	r := &updog.Result{Count: pr.TotalCount}
+	r := &updog.Result{
+		Count:      pr.TotalCount,
+		Warnings:   pr.Warnings,
+		Distinct:   toDistinct(pr.Distinct),
+		Aggregates: toAggregateValues(pr.Aggregates),
+	}
 
 	for _, g := range pr.Groups {
 		gg := updog.ResultGroup{
-			Count: g.Count,
+			Count:      g.Count,
+			Distinct:   toDistinct(g.Distinct),
+			Aggregates: toAggregateValues(g.Aggregates),
 		}
 
 		for _, f := range g.Fields {
 			gg.Fields = append(gg.Fields, updog.ResultField{
 				Column: f.Column,
 				Value:  f.Value,
+				Type:   updog.ColumnType(f.Type),
 			})
 		}
 
@@ -82,3 +254,33 @@ func ToResult(pr *proto.Result) *updog.Result {
 
 	return r
 }
+
+func toDistinct(pbc []*proto.Result_DistinctCount) []updog.DistinctCount {
+	var counts []updog.DistinctCount
+
+	for _, c := range pbc {
+		counts = append(counts, updog.DistinctCount{
+			Column:      c.Column,
+			Count:       c.Count,
+			Approximate: c.Approximate,
+		})
+	}
+
+	return counts
+}
+
+func toAggregateValues(pbv []*proto.Result_AggregateValue) []updog.AggregateValue {
+	var values []updog.AggregateValue
+
+	for _, v := range pbv {
+		values = append(values, updog.AggregateValue{
+			Func:   toAggregateFunc(v.Function),
+			Column: v.Column,
+			Valid:  v.Valid,
+			Int:    v.IntValue,
+			Float:  v.FloatValue,
+		})
+	}
+
+	return values
+}
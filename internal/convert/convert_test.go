@@ -0,0 +1,51 @@
+package convert_test
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/akrennmair/updog"
+	"github.com/akrennmair/updog/internal/convert"
+	"github.com/akrennmair/updog/internal/queryparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotEqual(t *testing.T) {
+	filename := fmt.Sprintf("convert_test_%x.updog", rand.Int31())
+	defer os.Remove(filename)
+
+	writer := updog.NewIndexWriter(filename)
+
+	testData := []map[string]string{
+		{"a": "1", "b": "x"},
+		{"a": "2", "b": "x"},
+		{"b": "y"},
+	}
+
+	for _, row := range testData {
+		_, err := writer.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, writer.Flush())
+
+	idx, err := updog.OpenIndex(filename)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	pq, err := queryparser.ParseQuery(`a != "1"`)
+	require.NoError(t, err)
+
+	result, err := idx.Execute(convert.ToQuery(pq))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count, "rows without a value in the column shouldn't match")
+
+	pq, err = queryparser.ParseQuery(`^ a = "1"`)
+	require.NoError(t, err)
+
+	result, err = idx.Execute(convert.ToQuery(pq))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+}
@@ -7,37 +7,188 @@ import (
 	proto "github.com/akrennmair/updog/proto/updog/v1"
 )
 
-func QueryToString(q *proto.Query) string {
+// QueryToString formats a query in the query syntax, so that parsing the result returns
+// the same query. It returns an error if the query contains a range without bounds, as
+// there is no syntax for it.
+func QueryToString(q *proto.Query) (string, error) {
+	var err error
+
+	_ = Walk(q, func(e *proto.Query_Expression) bool {
+		if r := e.GetRange(); r != nil && r.Lower == nil && r.Upper == nil {
+			err = fmt.Errorf("range on column %s has no bounds", r.Column)
+			return false
+		}
+		return true
+	})
+
+	if err != nil {
+		return "", err
+	}
+
 	var b strings.Builder
 
 	exprToString(&b, q.Expr)
 
+	if len(q.GroupBy) == 0 && (len(q.CountDistinct) > 0 || len(q.Aggregates) > 0) {
+		b.WriteString(" ;")
+		aggregatesToString(&b, q)
+	}
+
 	if len(q.GroupBy) > 0 {
 		fmt.Fprintf(&b, " ; %s", strings.Join(q.GroupBy, ", "))
+
+		aggregatesToString(&b, q)
+
+		if q.MinCount > 0 {
+			fmt.Fprintf(&b, " HAVING COUNT >= %d", q.MinCount)
+		}
+
+		if q.OrderBy != nil {
+			orderBy := q.OrderBy.Column
+			switch {
+			case orderBy == "":
+				orderBy = "COUNT"
+			case strings.EqualFold(orderBy, "COUNT"):
+				orderBy = formatString(orderBy)
+			}
+
+			fmt.Fprintf(&b, " ORDER BY %s", orderBy)
+
+			if q.OrderBy.Descending {
+				b.WriteString(" DESC")
+			}
+		}
+
+		if q.Limit > 0 {
+			fmt.Fprintf(&b, " LIMIT %d", q.Limit)
+		}
+
+		if q.Offset > 0 {
+			fmt.Fprintf(&b, " OFFSET %d", q.Offset)
+		}
 	}
 
-	return b.String()
+	return b.String(), nil
+}
+
+func aggregatesToString(b *strings.Builder, q *proto.Query) {
+	if len(q.CountDistinct) > 0 {
+		fmt.Fprintf(b, " COUNT DISTINCT %s", strings.Join(q.CountDistinct, ", "))
+	}
+
+	for i, agg := range q.Aggregates {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		fmt.Fprintf(b, " %s(%s)", strings.TrimPrefix(agg.Function.String(), "FUNCTION_"), agg.Column)
+	}
 }
 
 func exprToString(b *strings.Builder, expr *proto.Query_Expression) {
 	switch v := expr.Value.(type) {
 	case *proto.Query_Expression_Eq:
 		equalExprToString(b, v.Eq)
+	case *proto.Query_Expression_Ne:
+		notEqualExprToString(b, v.Ne)
 	case *proto.Query_Expression_Not_:
 		notExprToString(b, v.Not)
 	case *proto.Query_Expression_And_:
 		andExprToString(b, v.And)
 	case *proto.Query_Expression_Or_:
 		orExprToString(b, v.Or)
+	case *proto.Query_Expression_Range_:
+		rangeExprToString(b, v.Range)
+	case *proto.Query_Expression_In_:
+		inExprToString(b, v.In)
+	case *proto.Query_Expression_Match_:
+		matchExprToString(b, v.Match)
+	case *proto.Query_Expression_Missing_:
+		fmt.Fprintf(b, "%s IS MISSING", v.Missing.Column)
+	case *proto.Query_Expression_Present_:
+		fmt.Fprintf(b, "%s IS PRESENT", v.Present.Column)
 	}
 }
 
 func equalExprToString(b *strings.Builder, expr *proto.Query_Expression_Equal) {
+	fmt.Fprintf(b, "%s = %s", expr.Column, formatOperand(expr.Value, expr.Placeholder))
+}
+
+func rangeExprToString(b *strings.Builder, expr *proto.Query_Expression_Range) {
+	lower, upper := expr.Lower, expr.Upper
+
+	switch {
+	case lower != nil && upper != nil && !lower.Exclusive && !upper.Exclusive:
+		fmt.Fprintf(b, "%s BETWEEN %s AND %s", expr.Column, formatOperand(lower.Value, lower.Placeholder), formatOperand(upper.Value, upper.Placeholder))
+	case lower != nil && upper != nil:
+		// the lower bound precedes the column, so its operator is the one of an upper bound.
+		fmt.Fprintf(b, "%s %s %s %s %s",
+			formatOperand(lower.Value, lower.Placeholder), upperBoundOperator(lower), expr.Column,
+			upperBoundOperator(upper), formatOperand(upper.Value, upper.Placeholder))
+	case lower != nil:
+		fmt.Fprintf(b, "%s %s %s", expr.Column, lowerBoundOperator(lower), formatOperand(lower.Value, lower.Placeholder))
+	case upper != nil:
+		fmt.Fprintf(b, "%s %s %s", expr.Column, upperBoundOperator(upper), formatOperand(upper.Value, upper.Placeholder))
+	}
+}
+
+func inExprToString(b *strings.Builder, expr *proto.Query_Expression_In) {
 	if expr.Placeholder > 0 {
-		fmt.Fprintf(b, "%s = $%d", expr.Column, expr.Placeholder)
+		fmt.Fprintf(b, "%s IN $%d", expr.Column, expr.Placeholder)
 		return
 	}
-	fmt.Fprintf(b, "%s = %s", expr.Column, formatString(expr.Value))
+
+	fmt.Fprintf(b, "%s IN (", expr.Column)
+
+	for idx, v := range expr.Values {
+		if idx > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(formatString(v))
+	}
+
+	b.WriteString(")")
+}
+
+func matchExprToString(b *strings.Builder, expr *proto.Query_Expression_Match) {
+	var op string
+
+	switch expr.Type {
+	case proto.Query_Expression_Match_TYPE_SUFFIX:
+		op = "$="
+	case proto.Query_Expression_Match_TYPE_REGEXP:
+		op = "~"
+	default:
+		// there is no syntax for TYPE_UNSPECIFIED, so it is formatted like TYPE_PREFIX.
+		op = "^="
+	}
+
+	fmt.Fprintf(b, "%s %s %s", expr.Column, op, formatOperand(expr.Pattern, expr.Placeholder))
+}
+
+func lowerBoundOperator(bound *proto.Query_Expression_Range_Bound) string {
+	if bound.Exclusive {
+		return ">"
+	}
+	return ">="
+}
+
+func upperBoundOperator(bound *proto.Query_Expression_Range_Bound) string {
+	if bound.Exclusive {
+		return "<"
+	}
+	return "<="
+}
+
+func formatOperand(value string, placeholder int32) string {
+	if placeholder > 0 {
+		return fmt.Sprintf("$%d", placeholder)
+	}
+	return formatString(value)
+}
+
+func notEqualExprToString(b *strings.Builder, expr *proto.Query_Expression_NotEqual) {
+	fmt.Fprintf(b, "%s != %s", expr.Column, formatOperand(expr.Value, expr.Placeholder))
 }
 
 func formatString(s string) string {
@@ -66,7 +217,9 @@ func andExprToString(b *strings.Builder, expr *proto.Query_Expression_And) {
 			fmt.Fprintf(b, " & ")
 		}
 
-		requiresParens := expr.GetOr() != nil
+		// nested AND expressions need parentheses, otherwise they would be merged
+		// into this AND expression when parsing.
+		requiresParens := expr.GetOr() != nil || expr.GetAnd() != nil
 
 		if requiresParens {
 			b.WriteString("( ")
@@ -86,7 +239,11 @@ func orExprToString(b *strings.Builder, expr *proto.Query_Expression_Or) {
 			fmt.Fprintf(b, " | ")
 		}
 
-		requiresParens := expr.GetAnd() != nil
+		// AND binds tighter than OR, so parentheses around AND expressions are
+		// not strictly required, but they make the query easier to read. Nested
+		// OR expressions need parentheses, otherwise they would be merged into
+		// this OR expression when parsing.
+		requiresParens := expr.GetAnd() != nil || expr.GetOr() != nil
 
 		if requiresParens {
 			b.WriteString("( ")
@@ -14,20 +14,46 @@ import (
 )
 
 // query syntax:
-// query ::= expr [ ';' field-list ]
-// expr ::= simple-expr | and-expr | or-expr
-// simple-expr ::= grouped-expr | not-expr | comparison.
+// query ::= expr [ ';' ( aggregates | field-list [ aggregates ] [ having-clause ] [ order-clause ] [ limit-clause ] [ offset-clause ] ) ] .
+// expr ::= or-expr .
+// or-expr ::= and-expr { or-op and-expr } .
+// and-expr ::= simple-expr { and-op simple-expr } .
+// simple-expr ::= grouped-expr | not-expr | comparison | range.
 // grouped-expr ::= '(' expr ')'.
-// and-expr ::= simple-expr { '&' simple-expr }.
-// or-expr ::= simple-expr { '|' simple-expr }.
-// not-expr ::= '^' simple-expr.
-// comparison ::= field '=' ( value | placeholder ).
+// not-expr ::= not-op simple-expr.
+// or-op ::= '|' | 'OR' .
+// and-op ::= '&' | 'AND' .
+// not-op ::= '^' | 'NOT' .
+// comparison ::= field ( '=' operand | '!=' operand | range-op operand | match-op operand | 'BETWEEN' operand 'AND' operand | 'IN' ( value-list | placeholder ) | 'IS' ( 'MISSING' | 'PRESENT' ) ).
+// range-op ::= '<' | '<=' | '>' | '>=' .
+// range ::= operand ( '<' | '<=' ) field ( '<' | '<=' ) operand .
+// match-op ::= '^=' | '$=' | '~' .
+// operand ::= value | placeholder .
+// value-list ::= '(' [ value { ',' value } ] ')' .
 // field-list ::= field { ',' field } .
-// value ::= '"' { string-character } '"' .
-// string-character ::= any-character-except-quote | """" .
+// aggregates ::= distinct-clause [ function-list ] | function-list .
+// distinct-clause ::= 'COUNT' 'DISTINCT' field-list .
+// function-list ::= function { ',' function } .
+// function ::= ( 'SUM' | 'AVG' | 'MIN' | 'MAX' ) '(' name ')' .
+// having-clause ::= 'HAVING' 'COUNT' '>=' number .
+// order-clause ::= 'ORDER' 'BY' ( 'COUNT' | field | value ) [ 'ASC' | 'DESC' ] .
+// limit-clause ::= 'LIMIT' number .
+// offset-clause ::= 'OFFSET' number .
+// field ::= name | date-trunc .
+// date-trunc ::= 'DATE_TRUNC' '(' value ',' name ')' .
+// value ::= '"' { string-character } '"' | "'" { string-character } "'" .
+// string-character ::= any-character-except-quote | """" | "''" .
 // placeholder ::= '$' number .
 // number ::= digit { digit } .
 // digit ::= "0" | "1" | "2" | "3" | "4" | "5" | "6" | "7" | "8" | "9" .
+//
+// NOT binds tighter than AND, and AND binds tighter than OR. The keywords AND, OR, NOT,
+// BETWEEN, IN, IS, MISSING, PRESENT, DISTINCT, SUM, AVG, MIN, MAX, DATE_TRUNC, HAVING, ORDER,
+// BY, COUNT, ASC, DESC, LIMIT and OFFSET are case-insensitive. AND, OR and NOT are reserved and
+// can't be used as field names. ORDER BY COUNT orders by the count of the groups rather than by
+// a field named count, which can be ordered by as a quoted name, e.g. ORDER BY "count". SUM, AVG, MIN, MAX and DATE_TRUNC are only functions if they are
+// followed by '('. DATE_TRUNC refers to the time bucket column of a timestamp column with the granularity
+// minute, hour, day or month, e.g. date_trunc('day', ts).
 
 func ParseQuery(q string) (pq *proto.Query, err error) {
 	p := newParser(q)
@@ -71,24 +97,217 @@ func (p *parser) errorf(fmtstr string, args ...interface{}) {
 func (p *parser) parse() (pq *proto.Query, err error) {
 	defer p.recover(&err)
 
-	// query ::= expr [ ';' field-list ]
+	// query ::= expr [ ';' ( aggregates | field-list [ aggregates ] [ having-clause ] [ order-clause ] [ limit-clause ] [ offset-clause ] ) ] .
 
-	expr := p.parseExpr()
-
-	var groupBy []string
+	query := &proto.Query{
+		Expr: p.parseExpr(),
+	}
 
 	if p.peek().typ == itemSemicolon {
 		p.next()
-		groupBy = p.parseFieldList()
 
+		if p.peekDistinctClause() || p.peekFunction() {
+			p.parseAggregates(query)
+		} else {
+			p.parseGroupByClauses(query)
+		}
+	}
+
+	if p.peek().typ != itemEOF {
+		p.errorf("unexpected token %s", p.next())
+	}
+
+	return query, nil
+}
+
+func (p *parser) parseGroupByClauses(query *proto.Query) {
+	// field-list [ aggregates ] [ having-clause ] [ order-clause ] [ limit-clause ] [ offset-clause ]
+
+	query.GroupBy = p.parseFieldList()
+
+	if p.peekDistinctClause() || p.peekFunction() {
+		p.parseAggregates(query)
+	}
+
+	if p.peekKeyword("HAVING") {
+		query.MinCount = p.parseHavingClause()
+	}
+
+	if p.peekKeyword("ORDER") {
+		query.OrderBy = p.parseOrderClause()
+	}
+
+	if p.peekKeyword("LIMIT") {
+		p.next()
+		query.Limit = uint32(p.parseNumber(32))
+	}
+
+	if p.peekKeyword("OFFSET") {
+		p.next()
+		query.Offset = uint32(p.parseNumber(32))
+	}
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.typ == itemField && strings.EqualFold(tok.val, keyword)
+}
+
+// peekDistinctClause returns whether the next tokens are COUNT DISTINCT. As COUNT is also
+// a valid field name, this requires looking ahead two tokens.
+func (p *parser) peekDistinctClause() bool {
+	if !p.peekKeyword("COUNT") {
+		return false
+	}
+
+	count := p.next()
+	distinct := p.peekKeyword("DISTINCT")
+	p.backup2(count)
+
+	return distinct
+}
+
+func (p *parser) parseDistinctClause() []string {
+	// distinct-clause ::= 'COUNT' 'DISTINCT' field-list .
+
+	p.next()
+	p.next()
+
+	return p.parseFieldList()
+}
+
+func (p *parser) parseAggregates(query *proto.Query) {
+	// aggregates ::= distinct-clause [ function-list ] | function-list .
+
+	if p.peekDistinctClause() {
+		query.CountDistinct = p.parseDistinctClause()
+	}
+
+	if !p.peekFunction() {
+		return
+	}
+
+	query.Aggregates = append(query.Aggregates, p.parseFunction())
+
+	for p.peek().typ == itemComma {
+		p.next()
+
+		if !p.peekFunction() {
+			p.errorf("expected SUM, AVG, MIN or MAX, got %s instead", p.next())
+		}
+
+		query.Aggregates = append(query.Aggregates, p.parseFunction())
 	}
+}
 
-	pq = &proto.Query{
-		Expr:    expr,
-		GroupBy: groupBy,
+var aggregateFunctions = map[string]proto.Query_Aggregate_Function{
+	"SUM": proto.Query_Aggregate_FUNCTION_SUM,
+	"AVG": proto.Query_Aggregate_FUNCTION_AVG,
+	"MIN": proto.Query_Aggregate_FUNCTION_MIN,
+	"MAX": proto.Query_Aggregate_FUNCTION_MAX,
+}
+
+// peekFunction returns whether the next tokens are an aggregate function followed by '('.
+// As the names of functions are also valid field names, this requires looking ahead two tokens.
+func (p *parser) peekFunction() bool {
+	tok := p.peek()
+	if _, ok := aggregateFunctions[strings.ToUpper(tok.val)]; tok.typ != itemField || !ok {
+		return false
+	}
+
+	name := p.next()
+	openParen := p.peek().typ == itemOpenParen
+	p.backup2(name)
+
+	return openParen
+}
+
+func (p *parser) parseFunction() *proto.Query_Aggregate {
+	// function ::= ( 'SUM' | 'AVG' | 'MIN' | 'MAX' ) '(' name ')' .
+
+	agg := &proto.Query_Aggregate{
+		Function: aggregateFunctions[strings.ToUpper(p.next().val)],
 	}
 
-	return pq, nil
+	p.next()
+
+	tok := p.next()
+	if tok.typ != itemField {
+		p.errorf("expected field, got %s instead", tok)
+	}
+	agg.Column = tok.val
+
+	if tok := p.next(); tok.typ != itemCloseParen {
+		p.errorf("expected ), got %s instead", tok)
+	}
+
+	return agg
+}
+
+func (p *parser) parseHavingClause() uint64 {
+	// having-clause ::= 'HAVING' 'COUNT' '>=' number .
+
+	p.next()
+
+	if !p.peekKeyword("COUNT") {
+		p.errorf("expected COUNT, got %s instead", p.next())
+	}
+	p.next()
+
+	if tok := p.next(); tok.typ != itemGreaterEqual {
+		p.errorf("expected >=, got %s instead", tok)
+	}
+
+	return p.parseNumber(64)
+}
+
+func (p *parser) parseOrderClause() *proto.Query_OrderBy {
+	// order-clause ::= 'ORDER' 'BY' ( 'COUNT' | field | value ) [ 'ASC' | 'DESC' ] .
+
+	p.next()
+
+	if !p.peekKeyword("BY") {
+		p.errorf("expected BY, got %s instead", p.next())
+	}
+	p.next()
+
+	orderBy := &proto.Query_OrderBy{}
+
+	switch {
+	case p.peek().typ == itemValue:
+		// a quoted name is always a field, so that fields named count can be ordered by.
+		orderBy.Column = decodeString(p.next().val)
+	case p.peek().typ != itemField:
+		p.errorf("expected COUNT or field, got %s instead", p.next())
+	case p.peekKeyword("COUNT"):
+		p.next()
+	default:
+		orderBy.Column = p.parseField()
+	}
+
+	switch {
+	case p.peekKeyword("ASC"):
+		p.next()
+	case p.peekKeyword("DESC"):
+		p.next()
+		orderBy.Descending = true
+	}
+
+	return orderBy
+}
+
+func (p *parser) parseNumber(bitSize int) uint64 {
+	tok := p.next()
+	if tok.typ != itemNumber {
+		p.errorf("expected number, got %s instead", tok)
+	}
+
+	n, err := strconv.ParseUint(tok.val, 10, bitSize)
+	if err != nil {
+		p.errorf("invalid number %s: %v", tok, err)
+	}
+
+	return n
 }
 
 func (p *parser) peek() item {
@@ -100,6 +319,13 @@ func (p *parser) peek() item {
 	return p.token[0]
 }
 
+// backup2 backs up two tokens, the first of which is t1, and the second of which has
+// been peeked already.
+func (p *parser) backup2(t1 item) {
+	p.token[1] = t1
+	p.peekCount = 2
+}
+
 func (p *parser) next() item {
 	if p.peekCount > 0 {
 		p.peekCount--
@@ -111,48 +337,51 @@ func (p *parser) next() item {
 }
 
 func (p *parser) parseExpr() *proto.Query_Expression {
-	// expr ::= simple-expr | and-expr | or-expr
+	// expr ::= or-expr .
 
-	expr := p.parseSimpleExpr()
+	return p.parseOrExpr()
+}
 
-	switch p.peek().typ {
-	case itemAnd:
-		return p.parseAndExpr(expr)
-	case itemOr:
-		return p.parseOrExpr(expr)
-	}
+func (p *parser) parseOrExpr() *proto.Query_Expression {
+	// or-expr ::= and-expr { or-op and-expr } .
 
-	return expr
-}
+	expr := p.parseAndExpr()
 
-func (p *parser) parseAndExpr(firstExpr *proto.Query_Expression) *proto.Query_Expression {
-	// and-expr ::= simple-expr { '&' simple-expr }.
+	if p.peek().typ != itemOr {
+		return expr
+	}
 
-	exprs := []*proto.Query_Expression{firstExpr}
+	exprs := []*proto.Query_Expression{expr}
 
-	for p.peek().typ == itemAnd {
+	for p.peek().typ == itemOr {
 		p.next()
 
-		expr := p.parseSimpleExpr()
+		expr := p.parseAndExpr()
 
 		exprs = append(exprs, expr)
 	}
 
 	return &proto.Query_Expression{
-		Value: &proto.Query_Expression_And_{
-			And: &proto.Query_Expression_And{
+		Value: &proto.Query_Expression_Or_{
+			Or: &proto.Query_Expression_Or{
 				Exprs: exprs,
 			},
 		},
 	}
 }
 
-func (p *parser) parseOrExpr(firstExpr *proto.Query_Expression) *proto.Query_Expression {
-	// or-expr ::= simple-expr { '|' simple-expr }.
+func (p *parser) parseAndExpr() *proto.Query_Expression {
+	// and-expr ::= simple-expr { and-op simple-expr } .
 
-	exprs := []*proto.Query_Expression{firstExpr}
+	expr := p.parseSimpleExpr()
 
-	for p.peek().typ == itemOr {
+	if p.peek().typ != itemAnd {
+		return expr
+	}
+
+	exprs := []*proto.Query_Expression{expr}
+
+	for p.peek().typ == itemAnd {
 		p.next()
 
 		expr := p.parseSimpleExpr()
@@ -161,8 +390,8 @@ func (p *parser) parseOrExpr(firstExpr *proto.Query_Expression) *proto.Query_Exp
 	}
 
 	return &proto.Query_Expression{
-		Value: &proto.Query_Expression_Or_{
-			Or: &proto.Query_Expression_Or{
+		Value: &proto.Query_Expression_And_{
+			And: &proto.Query_Expression_And{
 				Exprs: exprs,
 			},
 		},
@@ -174,7 +403,7 @@ func (p *parser) parseSimpleExpr() *proto.Query_Expression {
 	case itemOpenParen:
 		return p.parseGroupedExpr()
 	case itemNot:
-		// not-expr ::= '^' simple-expr.
+		// not-expr ::= not-op simple-expr.
 		p.next()
 		return &proto.Query_Expression{
 			Value: &proto.Query_Expression_Not_{
@@ -185,6 +414,8 @@ func (p *parser) parseSimpleExpr() *proto.Query_Expression {
 		}
 	case itemField:
 		return p.parseComparison()
+	case itemValue, itemPlaceholder:
+		return p.parseRange()
 	default:
 		p.errorf("unexpected token %q", p.peek())
 		return nil
@@ -206,18 +437,213 @@ func (p *parser) parseGroupedExpr() *proto.Query_Expression {
 	return expr
 }
 
+func (p *parser) parseRange() *proto.Query_Expression {
+	// range ::= operand ( '<' | '<=' ) field ( '<' | '<=' ) operand .
+
+	lower := &proto.Query_Expression_Range_Bound{}
+
+	value, placeholder := p.parseOperand()
+	lower.Value, lower.Placeholder = value, int32(placeholder)
+
+	switch op := p.next(); op.typ {
+	case itemLess:
+		lower.Exclusive = true
+	case itemLessEqual:
+	default:
+		p.errorf("expected < or <=, got %s instead", op)
+	}
+
+	column := p.parseField()
+
+	upper := &proto.Query_Expression_Range_Bound{}
+
+	switch op := p.next(); op.typ {
+	case itemLess:
+		upper.Exclusive = true
+	case itemLessEqual:
+	default:
+		p.errorf("expected < or <=, got %s instead", op)
+	}
+
+	value, placeholder = p.parseOperand()
+	upper.Value, upper.Placeholder = value, int32(placeholder)
+
+	return &proto.Query_Expression{
+		Value: &proto.Query_Expression_Range_{
+			Range: &proto.Query_Expression_Range{
+				Column: column,
+				Lower:  lower,
+				Upper:  upper,
+			},
+		},
+	}
+}
+
 func (p *parser) parseComparison() *proto.Query_Expression {
-	column := p.next()
+	column := p.parseField()
+
+	switch op := p.next(); {
+	case op.typ == itemEqual:
+		value, placeholder := p.parseOperand()
+
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Eq{
+				Eq: &proto.Query_Expression_Equal{
+					Column:      column,
+					Value:       value,
+					Placeholder: int32(placeholder),
+				},
+			},
+		}
+	case op.typ == itemNotEqual:
+		value, placeholder := p.parseOperand()
+
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Ne{
+				Ne: &proto.Query_Expression_NotEqual{
+					Column:      column,
+					Value:       value,
+					Placeholder: int32(placeholder),
+				},
+			},
+		}
+	case op.typ == itemLess || op.typ == itemLessEqual || op.typ == itemGreater || op.typ == itemGreaterEqual:
+		value, placeholder := p.parseOperand()
+
+		bound := &proto.Query_Expression_Range_Bound{
+			Value:       value,
+			Placeholder: int32(placeholder),
+			Exclusive:   op.typ == itemLess || op.typ == itemGreater,
+		}
+
+		rangeExpr := &proto.Query_Expression_Range{Column: column}
+
+		if op.typ == itemLess || op.typ == itemLessEqual {
+			rangeExpr.Upper = bound
+		} else {
+			rangeExpr.Lower = bound
+		}
+
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Range_{
+				Range: rangeExpr,
+			},
+		}
+	case op.typ == itemPrefixMatch || op.typ == itemSuffixMatch || op.typ == itemRegexpMatch:
+		pattern, placeholder := p.parseOperand()
+
+		matchType := map[itemType]proto.Query_Expression_Match_Type{
+			itemPrefixMatch: proto.Query_Expression_Match_TYPE_PREFIX,
+			itemSuffixMatch: proto.Query_Expression_Match_TYPE_SUFFIX,
+			itemRegexpMatch: proto.Query_Expression_Match_TYPE_REGEXP,
+		}[op.typ]
+
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Match_{
+				Match: &proto.Query_Expression_Match{
+					Column:      column,
+					Type:        matchType,
+					Pattern:     pattern,
+					Placeholder: int32(placeholder),
+				},
+			},
+		}
+	case op.typ == itemField && strings.EqualFold(op.val, "BETWEEN"):
+		lowerValue, lowerPlaceholder := p.parseOperand()
+
+		// unlike in a conjunction, the bounds can only be separated by the AND keyword.
+		if tok := p.next(); tok.typ != itemAnd || !strings.EqualFold(tok.val, "AND") {
+			p.errorf("expected AND, got %s instead", tok)
+		}
+
+		upperValue, upperPlaceholder := p.parseOperand()
+
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Range_{
+				Range: &proto.Query_Expression_Range{
+					Column: column,
+					Lower: &proto.Query_Expression_Range_Bound{
+						Value:       lowerValue,
+						Placeholder: int32(lowerPlaceholder),
+					},
+					Upper: &proto.Query_Expression_Range_Bound{
+						Value:       upperValue,
+						Placeholder: int32(upperPlaceholder),
+					},
+				},
+			},
+		}
+	case op.typ == itemField && strings.EqualFold(op.val, "IN"):
+		in := &proto.Query_Expression_In{Column: column}
+
+		if p.peek().typ == itemPlaceholder {
+			_, placeholder := p.parseOperand()
+			in.Placeholder = int32(placeholder)
+		} else {
+			in.Values = p.parseValueList()
+		}
+
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_In_{
+				In: in,
+			},
+		}
+	case op.typ == itemField && strings.EqualFold(op.val, "IS"):
+		switch {
+		case p.peekKeyword("MISSING"):
+			p.next()
+			return &proto.Query_Expression{
+				Value: &proto.Query_Expression_Missing_{
+					Missing: &proto.Query_Expression_Missing{Column: column},
+				},
+			}
+		case p.peekKeyword("PRESENT"):
+			p.next()
+			return &proto.Query_Expression{
+				Value: &proto.Query_Expression_Present_{
+					Present: &proto.Query_Expression_Present{Column: column},
+				},
+			}
+		default:
+			p.errorf("expected MISSING or PRESENT, got %s instead", p.next())
+			return nil
+		}
+	default:
+		p.errorf("expected comparison operator, got %s instead", op)
+		return nil
+	}
+}
+
+func (p *parser) parseValueList() []string {
+	// value-list ::= '(' [ value { ',' value } ] ')' .
+
+	if tok := p.next(); tok.typ != itemOpenParen {
+		p.errorf("expected (, got %s instead", tok)
+	}
+
+	values := []string{}
+
+	for p.peek().typ != itemCloseParen {
+		if len(values) > 0 {
+			if tok := p.next(); tok.typ != itemComma {
+				p.errorf("expected , or ), got %s instead", tok)
+			}
+		}
 
-	if p.peek().typ != itemEqual {
-		p.errorf("expected =, got %s instead", p.next())
+		if p.peek().typ != itemValue {
+			p.errorf("expected value, got %s instead", p.next())
+		}
+
+		values = append(values, decodeString(p.next().val))
 	}
+
 	p.next()
 
-	var (
-		value       string
-		placeholder int
-	)
+	return values
+}
+
+func (p *parser) parseOperand() (value string, placeholder int) {
+	// operand ::= value | placeholder .
 
 	switch p.peek().typ {
 	case itemPlaceholder:
@@ -231,16 +657,7 @@ func (p *parser) parseComparison() *proto.Query_Expression {
 		p.errorf("expected value or placeholder, got %s instead", p.next())
 	}
 
-	return &proto.Query_Expression{
-		Value: &proto.Query_Expression_Eq{
-			Eq: &proto.Query_Expression_Equal{
-				Column:      column.val,
-				Value:       value,
-				Placeholder: int32(placeholder),
-			},
-		},
-	}
-
+	return value, placeholder
 }
 
 func decodeString(s string) string {
@@ -248,15 +665,18 @@ func decodeString(s string) string {
 		return s
 	}
 
-	if s[0] == '"' {
-		s = s[1:]
+	quote := s[0]
+	if quote != '"' && quote != '\'' {
+		return s
 	}
 
-	if s[len(s)-1] == '"' {
+	s = s[1:]
+
+	if s[len(s)-1] == quote {
 		s = s[:len(s)-1]
 	}
 
-	return strings.ReplaceAll(s, `""`, `"`)
+	return strings.ReplaceAll(s, string([]byte{quote, quote}), string(quote))
 }
 
 func decodePlaceholder(s string) int {
@@ -269,27 +689,67 @@ func decodePlaceholder(s string) int {
 }
 
 func (p *parser) parseFieldList() []string {
-	if p.peek().typ != itemField {
-		p.errorf("expected field, got %s instead", p.next())
-	}
-
 	var fields []string
 
-	fields = append(fields, p.next().val)
+	fields = append(fields, p.parseField())
 
 	for p.peek().typ == itemComma {
 		p.next()
 
-		if p.peek().typ != itemField {
-			p.errorf("expected field, got %s instead", p.next())
-		}
-
-		fields = append(fields, p.next().val)
+		fields = append(fields, p.parseField())
 	}
 
 	return fields
 }
 
+var dateTruncGranularities = map[string]bool{
+	"minute": true,
+	"hour":   true,
+	"day":    true,
+	"month":  true,
+}
+
+func (p *parser) parseField() string {
+	// field ::= name | date-trunc .
+	// date-trunc ::= 'DATE_TRUNC' '(' value ',' name ')' .
+
+	tok := p.next()
+	if tok.typ != itemField {
+		p.errorf("expected field, got %s instead", tok)
+	}
+
+	if !strings.EqualFold(tok.val, "DATE_TRUNC") || p.peek().typ != itemOpenParen {
+		return tok.val
+	}
+
+	p.next()
+
+	if p.peek().typ != itemValue {
+		p.errorf("expected granularity, got %s instead", p.next())
+	}
+
+	granularity := strings.ToLower(decodeString(p.next().val))
+	if !dateTruncGranularities[granularity] {
+		p.errorf("unknown granularity %q, expected minute, hour, day or month", granularity)
+	}
+
+	if tok := p.next(); tok.typ != itemComma {
+		p.errorf("expected , got %s instead", tok)
+	}
+
+	name := p.next()
+	if name.typ != itemField {
+		p.errorf("expected field, got %s instead", name)
+	}
+
+	if tok := p.next(); tok.typ != itemCloseParen {
+		p.errorf("expected ), got %s instead", tok)
+	}
+
+	// this is the name of the time bucket column as returned by updog.DateTrunc.
+	return fmt.Sprintf("date_trunc('%s', %s)", granularity, name.val)
+}
+
 type lexer struct {
 	input   string
 	state   stateFn
@@ -333,11 +793,20 @@ const (
 	itemOr
 	itemNot
 	itemEqual
+	itemNotEqual
+	itemLess
+	itemLessEqual
+	itemGreater
+	itemGreaterEqual
+	itemPrefixMatch
+	itemSuffixMatch
+	itemRegexpMatch
 	itemComma
 	itemSemicolon
 	itemField
 	itemValue
 	itemPlaceholder
+	itemNumber
 )
 
 func lex(input string) *lexer {
@@ -380,7 +849,16 @@ func lexText(l *lexer) stateFn {
 		return lexText
 	case r == '^':
 		l.next()
-		l.emit(itemNot)
+		if l.peek() == '=' {
+			l.next()
+			l.emit(itemPrefixMatch)
+		} else {
+			l.emit(itemNot)
+		}
+		return lexText
+	case r == '~':
+		l.next()
+		l.emit(itemRegexpMatch)
 		return lexText
 	case r == ',':
 		l.next()
@@ -394,11 +872,45 @@ func lexText(l *lexer) stateFn {
 		l.next()
 		l.emit(itemEqual)
 		return lexText
+	case r == '!':
+		l.next()
+		if l.next() != '=' {
+			return l.errorf("expected = after !")
+		}
+		l.emit(itemNotEqual)
+		return lexText
+	case r == '<':
+		l.next()
+		if l.peek() == '=' {
+			l.next()
+			l.emit(itemLessEqual)
+		} else {
+			l.emit(itemLess)
+		}
+		return lexText
+	case r == '>':
+		l.next()
+		if l.peek() == '=' {
+			l.next()
+			l.emit(itemGreaterEqual)
+		} else {
+			l.emit(itemGreater)
+		}
+		return lexText
 	case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
 		return lexField
-	case r == '"':
+	case r == '"' || r == '\'':
 		return lexValue
+	case r >= '0' && r <= '9':
+		l.acceptRun("0123456789")
+		l.emit(itemNumber)
+		return lexText
 	case r == '$':
+		if strings.HasPrefix(l.input[l.pos:], "$=") {
+			l.pos += pos(len("$="))
+			l.emit(itemSuffixMatch)
+			return lexText
+		}
 		return lexPlaceholder
 	case r == eof:
 		l.emit(itemEOF)
@@ -409,20 +921,32 @@ func lexText(l *lexer) stateFn {
 
 func lexField(l *lexer) stateFn {
 	l.acceptRun("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_")
-	l.emit(itemField)
+
+	switch word := l.input[l.start:l.pos]; {
+	case strings.EqualFold(word, "AND"):
+		l.emit(itemAnd)
+	case strings.EqualFold(word, "OR"):
+		l.emit(itemOr)
+	case strings.EqualFold(word, "NOT"):
+		l.emit(itemNot)
+	default:
+		l.emit(itemField)
+	}
+
 	return lexText
 }
 
 func lexValue(l *lexer) stateFn {
-	seenFinalQuote := false // this is only there in case the closing " is the final character in the text to parse; mostly necessary for expression parsing testing.
-	r := l.next()
-	if r != '"' {
-		return l.errorf("expected \", got %c instead", r)
+	seenFinalQuote := false // this is only there in case the closing quote is the final character in the text to parse; mostly necessary for expression parsing testing.
+	quote := l.next()
+	if quote != '"' && quote != '\'' {
+		return l.errorf("expected \" or ', got %c instead", quote)
 	}
+	r := quote
 	for r = l.next(); r != eof; r = l.next() {
-		if r == '"' { // if the current character is ", then we peek to the next one.
+		if r == quote { // if the current character is the quote, then we peek to the next one.
 			r = l.peek()
-			if r != '"' { // if it also a ", then we just go to next one, otherwise we've hit the final " of a string.
+			if r != quote { // if it also a quote, then we just go to next one, otherwise we've hit the final quote of a string.
 				seenFinalQuote = true
 				break
 			}
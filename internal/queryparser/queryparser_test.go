@@ -340,6 +340,373 @@ func TestValidQueryStrings(t *testing.T) {
 				},
 			},
 		},
+		{
+			QueryString: `foo > "10"`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Range_{
+						Range: &proto.Query_Expression_Range{
+							Column: "foo",
+							Lower: &proto.Query_Expression_Range_Bound{
+								Value:     "10",
+								Exclusive: true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `foo <= $1`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Range_{
+						Range: &proto.Query_Expression_Range{
+							Column: "foo",
+							Upper: &proto.Query_Expression_Range_Bound{
+								Placeholder: 1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `"10" < foo <= $1`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Range_{
+						Range: &proto.Query_Expression_Range{
+							Column: "foo",
+							Lower: &proto.Query_Expression_Range_Bound{
+								Value:     "10",
+								Exclusive: true,
+							},
+							Upper: &proto.Query_Expression_Range_Bound{
+								Placeholder: 1,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `foo BETWEEN "30" AND "40" & bar = "baz"`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_And_{
+						And: &proto.Query_Expression_And{
+							Exprs: []*proto.Query_Expression{
+								{
+									Value: &proto.Query_Expression_Range_{
+										Range: &proto.Query_Expression_Range{
+											Column: "foo",
+											Lower: &proto.Query_Expression_Range_Bound{
+												Value: "30",
+											},
+											Upper: &proto.Query_Expression_Range_Bound{
+												Value: "40",
+											},
+										},
+									},
+								},
+								{
+									Value: &proto.Query_Expression_Eq{
+										Eq: &proto.Query_Expression_Equal{
+											Column: "bar",
+											Value:  "baz",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `country IN ("DE", "AT", "CH") ; country`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_In_{
+						In: &proto.Query_Expression_In{
+							Column: "country",
+							Values: []string{"DE", "AT", "CH"},
+						},
+					},
+				},
+				GroupBy: []string{"country"},
+			},
+		},
+		{
+			QueryString: `country IN $1`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_In_{
+						In: &proto.Query_Expression_In{
+							Column:      "country",
+							Placeholder: 1,
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `foo != "bar" & bar != $1`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_And_{
+						And: &proto.Query_Expression_And{
+							Exprs: []*proto.Query_Expression{
+								{
+									Value: &proto.Query_Expression_Ne{
+										Ne: &proto.Query_Expression_NotEqual{
+											Column: "foo",
+											Value:  "bar",
+										},
+									},
+								},
+								{
+									Value: &proto.Query_Expression_Ne{
+										Ne: &proto.Query_Expression_NotEqual{
+											Column:      "bar",
+											Placeholder: 1,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `url ^= "/api/" | url $= ".json" | user_agent ~ $1`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Or_{
+						Or: &proto.Query_Expression_Or{
+							Exprs: []*proto.Query_Expression{
+								{
+									Value: &proto.Query_Expression_Match_{
+										Match: &proto.Query_Expression_Match{
+											Column:  "url",
+											Type:    proto.Query_Expression_Match_TYPE_PREFIX,
+											Pattern: "/api/",
+										},
+									},
+								},
+								{
+									Value: &proto.Query_Expression_Match_{
+										Match: &proto.Query_Expression_Match{
+											Column:  "url",
+											Type:    proto.Query_Expression_Match_TYPE_SUFFIX,
+											Pattern: ".json",
+										},
+									},
+								},
+								{
+									Value: &proto.Query_Expression_Match_{
+										Match: &proto.Query_Expression_Match{
+											Column:      "user_agent",
+											Type:        proto.Query_Expression_Match_TYPE_REGEXP,
+											Placeholder: 1,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `a = "b" ; c, d HAVING COUNT >= 100 ORDER BY COUNT DESC LIMIT 10 OFFSET 5`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				GroupBy:  []string{"c", "d"},
+				MinCount: 100,
+				OrderBy: &proto.Query_OrderBy{
+					Descending: true,
+				},
+				Limit:  10,
+				Offset: 5,
+			},
+		},
+		{
+			QueryString: `a = "b" ; c ORDER BY c LIMIT 3`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				GroupBy: []string{"c"},
+				OrderBy: &proto.Query_OrderBy{
+					Column: "c",
+				},
+				Limit: 3,
+			},
+		},
+		{
+			QueryString: `a = "b" ; count, c ORDER BY "count" DESC`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				GroupBy: []string{"count", "c"},
+				OrderBy: &proto.Query_OrderBy{
+					Column:     "count",
+					Descending: true,
+				},
+			},
+		},
+		{
+			QueryString: `a = "b" ; COUNT DISTINCT user`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				CountDistinct: []string{"user"},
+			},
+		},
+		{
+			QueryString: `a = "b" ; count, c COUNT DISTINCT user, session HAVING COUNT >= 2`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				GroupBy:       []string{"count", "c"},
+				CountDistinct: []string{"user", "session"},
+				MinCount:      2,
+			},
+		},
+		{
+			QueryString: `ts BETWEEN "2024-01-01" AND "2024-01-31" ; date_trunc('day', ts), c ORDER BY date_trunc('day', ts) DESC`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Range_{
+						Range: &proto.Query_Expression_Range{
+							Column: "ts",
+							Lower:  &proto.Query_Expression_Range_Bound{Value: "2024-01-01"},
+							Upper:  &proto.Query_Expression_Range_Bound{Value: "2024-01-31"},
+						},
+					},
+				},
+				GroupBy: []string{"date_trunc('day', ts)", "c"},
+				OrderBy: &proto.Query_OrderBy{Column: "date_trunc('day', ts)", Descending: true},
+			},
+		},
+		{
+			QueryString: `date_trunc('month', ts) = "2024-01-01"`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "date_trunc('month', ts)",
+							Value:  "2024-01-01",
+						},
+					},
+				},
+			},
+		},
+		{
+			QueryString: `a = "b" ; SUM(bytes), AVG(duration)`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				Aggregates: []*proto.Query_Aggregate{
+					{Function: proto.Query_Aggregate_FUNCTION_SUM, Column: "bytes"},
+					{Function: proto.Query_Aggregate_FUNCTION_AVG, Column: "duration"},
+				},
+			},
+		},
+		{
+			QueryString: `a = "b" ; sum, c COUNT DISTINCT user MIN(bytes), MAX(bytes) ORDER BY COUNT DESC`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Eq{
+						Eq: &proto.Query_Expression_Equal{
+							Column: "a",
+							Value:  "b",
+						},
+					},
+				},
+				GroupBy:       []string{"sum", "c"},
+				CountDistinct: []string{"user"},
+				Aggregates: []*proto.Query_Aggregate{
+					{Function: proto.Query_Aggregate_FUNCTION_MIN, Column: "bytes"},
+					{Function: proto.Query_Aggregate_FUNCTION_MAX, Column: "bytes"},
+				},
+				OrderBy: &proto.Query_OrderBy{
+					Descending: true,
+				},
+			},
+		},
+		{
+			QueryString: `a IS MISSING | ^ b IS PRESENT`,
+			ExpectedQuery: &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Or_{
+						Or: &proto.Query_Expression_Or{
+							Exprs: []*proto.Query_Expression{
+								{
+									Value: &proto.Query_Expression_Missing_{
+										Missing: &proto.Query_Expression_Missing{
+											Column: "a",
+										},
+									},
+								},
+								{
+									Value: &proto.Query_Expression_Not_{
+										Not: &proto.Query_Expression_Not{
+											Expr: &proto.Query_Expression{
+												Value: &proto.Query_Expression_Present_{
+													Present: &proto.Query_Expression_Present{
+														Column: "b",
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range testData {
@@ -349,7 +716,8 @@ func TestValidQueryStrings(t *testing.T) {
 			require.NotNil(t, q)
 			require.Equal(t, tt.ExpectedQuery, q)
 
-			newQueryString := queryparser.QueryToString(q)
+			newQueryString, err := queryparser.QueryToString(q)
+			require.NoError(t, err)
 			require.Equal(t, tt.QueryString, newQueryString)
 		})
 	}
@@ -367,6 +735,55 @@ func TestInvalidQueryStrings(t *testing.T) {
 		{`!`},
 		{"a = $fart"},
 		{"b = $0"},
+		{`a < `},
+		{`a >= "1" "2"`},
+		{`a BETWEEN "1" "2"`},
+		{`a BETWEEN "1" OR "2"`},
+		{`a BETWEEN "1" & "5"`},
+		{`a BETWEEN "1"`},
+		{`"1" < a`},
+		{`"1" > a > "2"`},
+		{`"1" < a = "2"`},
+		{`"1" <= "2" < "3"`},
+		{`a IN "b"`},
+		{`a IN ("b" "c")`},
+		{`a IN ("b", )`},
+		{`a IN ("b", $1)`},
+		{`a IN ("b"`},
+		{`a ! "b"`},
+		{`a = "b" AND`},
+		{`a = "b" OR OR b = "c"`},
+		{`and = "b"`},
+		{`a ~`},
+		{`a ^= ^= "b"`},
+		{`a $ "b"`},
+		{`a = "b" ORDER BY COUNT`},
+		{`a = "b" ; c ORDER COUNT`},
+		{`a = "b" ; c ORDER BY`},
+		{`a = "b" ; c LIMIT`},
+		{`a = "b" ; c LIMIT "10"`},
+		{`a = "b" ; c LIMIT 99999999999`},
+		{`a = "b" ; c OFFSET 1 LIMIT 2`},
+		{`a = 1`},
+		{`a = "b" ; c HAVING COUNT > 1`},
+		{`a = "b" ; c HAVING 1`},
+		{`a = "b" ; c ORDER BY COUNT HAVING COUNT >= 1`},
+		{`a IS`},
+		{`a = "b" ; COUNT DISTINCT`},
+		{`a = "b" ; COUNT DISTINCT c LIMIT 1`},
+		{`a = "b" ; c COUNT d`},
+		{`a = "b" ; SUM(bytes`},
+		{`a = "b" ; SUM()`},
+		{`a = "b" ; SUM(a), b`},
+		{`a = "b" ; c SUM(a) COUNT DISTINCT d`},
+		{`a IS "b"`},
+		{`a IS NULL`},
+		{`a = "b" ; date_trunc('week', ts)`},
+		{`a = "b" ; date_trunc(day, ts)`},
+		{`a = "b" ; date_trunc('day' ts)`},
+		{`a = "b" ; date_trunc('day', ts`},
+		{`a = "b" ; date_trunc('day', "ts")`},
+		{`a = 'b`},
 	}
 
 	for _, tt := range testData {
@@ -379,3 +796,181 @@ func TestInvalidQueryStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestAlternativeSpellings(t *testing.T) {
+	testData := []struct {
+		Query         string
+		ExpectedQuery string
+	}{
+		{`a = 'b'`, `a = "b"`},
+		{`a = 'it''s "quoted" here'`, `a = "it's ""quoted"" here"`},
+		{`a = "b" ; DATE_TRUNC("Hour", ts)`, `a = "b" ; date_trunc('hour', ts)`},
+		{`a = "b" ; date_trunc ( 'minute' , ts )`, `a = "b" ; date_trunc('minute', ts)`},
+		{`a = "b" ; date_trunc`, `a = "b" ; date_trunc`},
+		{`a = "b" ; c ORDER BY 'c'`, `a = "b" ; c ORDER BY c`},
+		{`a = "b" ; Count ORDER BY 'Count'`, `a = "b" ; Count ORDER BY "Count"`},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.Query, func(t *testing.T) {
+			q, err := queryparser.ParseQuery(tt.Query)
+			require.NoError(t, err)
+
+			expected, err := queryparser.ParseQuery(tt.ExpectedQuery)
+			require.NoError(t, err)
+
+			require.Equal(t, expected, q)
+			formatted, err := queryparser.QueryToString(q)
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectedQuery, formatted)
+		})
+	}
+}
+
+func TestFormatRanges(t *testing.T) {
+	bound := func(value string, exclusive bool) *proto.Query_Expression_Range_Bound {
+		return &proto.Query_Expression_Range_Bound{Value: value, Exclusive: exclusive}
+	}
+
+	testData := []struct {
+		Lower, Upper    *proto.Query_Expression_Range_Bound
+		FormattedString string
+	}{
+		{bound("1", false), bound("5", false), `a BETWEEN "1" AND "5"`},
+		{bound("1", true), bound("5", false), `"1" < a <= "5"`},
+		{bound("1", false), bound("5", true), `"1" <= a < "5"`},
+		{bound("1", true), bound("5", true), `"1" < a < "5"`},
+		{bound("1", true), nil, `a > "1"`},
+		{nil, bound("5", false), `a <= "5"`},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.FormattedString, func(t *testing.T) {
+			q := &proto.Query{
+				Expr: &proto.Query_Expression{
+					Value: &proto.Query_Expression_Range_{
+						Range: &proto.Query_Expression_Range{Column: "a", Lower: tt.Lower, Upper: tt.Upper},
+					},
+				},
+			}
+
+			formatted, err := queryparser.QueryToString(q)
+			require.NoError(t, err)
+			require.Equal(t, tt.FormattedString, formatted)
+
+			q2, err := queryparser.ParseQuery(formatted)
+			require.NoError(t, err)
+			require.Equal(t, q, q2)
+		})
+	}
+
+	t.Run("unbounded", func(t *testing.T) {
+		q := &proto.Query{
+			Expr: &proto.Query_Expression{
+				Value: &proto.Query_Expression_Not_{
+					Not: &proto.Query_Expression_Not{
+						Expr: &proto.Query_Expression{
+							Value: &proto.Query_Expression_Range_{
+								Range: &proto.Query_Expression_Range{Column: "a"},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		_, err := queryparser.QueryToString(q)
+		require.Error(t, err)
+	})
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	eq := func(column, value string) *proto.Query_Expression {
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Eq{
+				Eq: &proto.Query_Expression_Equal{
+					Column: column,
+					Value:  value,
+				},
+			},
+		}
+	}
+
+	and := func(exprs ...*proto.Query_Expression) *proto.Query_Expression {
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_And_{
+				And: &proto.Query_Expression_And{
+					Exprs: exprs,
+				},
+			},
+		}
+	}
+
+	or := func(exprs ...*proto.Query_Expression) *proto.Query_Expression {
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Or_{
+				Or: &proto.Query_Expression_Or{
+					Exprs: exprs,
+				},
+			},
+		}
+	}
+
+	not := func(expr *proto.Query_Expression) *proto.Query_Expression {
+		return &proto.Query_Expression{
+			Value: &proto.Query_Expression_Not_{
+				Not: &proto.Query_Expression_Not{
+					Expr: expr,
+				},
+			},
+		}
+	}
+
+	testData := []struct {
+		QueryString     string
+		ExpectedExpr    *proto.Query_Expression
+		FormattedString string
+	}{
+		{
+			QueryString:     `a = "1" & b = "2" | c = "3"`,
+			ExpectedExpr:    or(and(eq("a", "1"), eq("b", "2")), eq("c", "3")),
+			FormattedString: `( a = "1" & b = "2" ) | c = "3"`,
+		},
+		{
+			QueryString:     `a = "1" | b = "2" & c = "3"`,
+			ExpectedExpr:    or(eq("a", "1"), and(eq("b", "2"), eq("c", "3"))),
+			FormattedString: `a = "1" | ( b = "2" & c = "3" )`,
+		},
+		{
+			QueryString:     `NOT a = "1" and b = "2" Or c = "3" OR d = "4"`,
+			ExpectedExpr:    or(and(not(eq("a", "1")), eq("b", "2")), eq("c", "3"), eq("d", "4")),
+			FormattedString: `( ^ a = "1" & b = "2" ) | c = "3" | d = "4"`,
+		},
+		{
+			QueryString:     `not (a = "1" | b = "2") AND c = "3"`,
+			ExpectedExpr:    and(not(or(eq("a", "1"), eq("b", "2"))), eq("c", "3")),
+			FormattedString: `^ ( a = "1" | b = "2" ) & c = "3"`,
+		},
+		{
+			QueryString:     `(a = "1" & b = "2") & c = "3"`,
+			ExpectedExpr:    and(and(eq("a", "1"), eq("b", "2")), eq("c", "3")),
+			FormattedString: `( a = "1" & b = "2" ) & c = "3"`,
+		},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.QueryString, func(t *testing.T) {
+			q, err := queryparser.ParseQuery(tt.QueryString)
+			require.NoError(t, err)
+			require.Equal(t, &proto.Query{Expr: tt.ExpectedExpr}, q)
+
+			formatted, err := queryparser.QueryToString(q)
+			require.NoError(t, err)
+			require.Equal(t, tt.FormattedString, formatted)
+
+			q2, err := queryparser.ParseQuery(formatted)
+			require.NoError(t, err)
+			require.Equal(t, q, q2)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package queryparser
 
 import (
+	"fmt"
+
 	updogv1 "github.com/akrennmair/updog/proto/updog/v1"
 	"google.golang.org/protobuf/proto"
 )
@@ -31,25 +33,117 @@ func walk(e *updogv1.Query_Expression, f func(e *updogv1.Query_Expression) bool)
 		if !walk(v.Not.Expr, f) {
 			return false
 		}
-	case *updogv1.Query_Expression_Eq:
+	case *updogv1.Query_Expression_Eq, *updogv1.Query_Expression_Ne, *updogv1.Query_Expression_Range_, *updogv1.Query_Expression_In_, *updogv1.Query_Expression_Match_,
+		*updogv1.Query_Expression_Missing_, *updogv1.Query_Expression_Present_:
 		// nothing
 	}
 
 	return true
 }
 
-func ReplacePlaceholders(query *updogv1.Query, values []string) *updogv1.Query {
+// MaxPlaceholder returns the highest placeholder number used in the query, or 0
+// if the query doesn't contain any placeholders.
+func MaxPlaceholder(query *updogv1.Query) int {
+	maxPlaceholder := int32(0)
+
+	_ = Walk(query, func(e *updogv1.Query_Expression) bool {
+		switch v := e.Value.(type) {
+		case *updogv1.Query_Expression_Eq:
+			maxPlaceholder = max(maxPlaceholder, v.Eq.Placeholder)
+		case *updogv1.Query_Expression_Ne:
+			maxPlaceholder = max(maxPlaceholder, v.Ne.Placeholder)
+		case *updogv1.Query_Expression_Match_:
+			maxPlaceholder = max(maxPlaceholder, v.Match.Placeholder)
+		case *updogv1.Query_Expression_Range_:
+			maxPlaceholder = max(maxPlaceholder, v.Range.Lower.GetPlaceholder(), v.Range.Upper.GetPlaceholder())
+		case *updogv1.Query_Expression_In_:
+			maxPlaceholder = max(maxPlaceholder, v.In.Placeholder)
+		}
+		return true
+	})
+
+	return int(maxPlaceholder)
+}
+
+// ReplacePlaceholders returns a copy of the query where all placeholders are replaced by
+// the provided values, i.e. placeholder $n is replaced by values[n-1]. The placeholder of an
+// IN expression accepts a []string as list of values, all other placeholders require a single
+// value. Single values are converted to strings using fmt.Sprint.
+func ReplacePlaceholders(query *updogv1.Query, values []any) (*updogv1.Query, error) {
 	q := proto.Clone(query).(*updogv1.Query)
 
+	var err error
+
 	_ = Walk(q, func(e *updogv1.Query_Expression) bool {
-		if v, ok := e.Value.(*updogv1.Query_Expression_Eq); ok {
+		switch v := e.Value.(type) {
+		case *updogv1.Query_Expression_Eq:
 			if v.Eq.Placeholder > 0 {
-				v.Eq.Value = values[v.Eq.Placeholder-1]
+				v.Eq.Value, err = singlePlaceholderValue(values, v.Eq.Placeholder)
 				v.Eq.Placeholder = 0
 			}
+		case *updogv1.Query_Expression_Ne:
+			if v.Ne.Placeholder > 0 {
+				v.Ne.Value, err = singlePlaceholderValue(values, v.Ne.Placeholder)
+				v.Ne.Placeholder = 0
+			}
+		case *updogv1.Query_Expression_Match_:
+			if v.Match.Placeholder > 0 {
+				v.Match.Pattern, err = singlePlaceholderValue(values, v.Match.Placeholder)
+				v.Match.Placeholder = 0
+			}
+		case *updogv1.Query_Expression_Range_:
+			for _, b := range []*updogv1.Query_Expression_Range_Bound{v.Range.Lower, v.Range.Upper} {
+				if b != nil && b.Placeholder > 0 && err == nil {
+					b.Value, err = singlePlaceholderValue(values, b.Placeholder)
+					b.Placeholder = 0
+				}
+			}
+		case *updogv1.Query_Expression_In_:
+			if v.In.Placeholder > 0 {
+				v.In.Values, err = listPlaceholderValue(values, v.In.Placeholder)
+				v.In.Placeholder = 0
+			}
 		}
-		return true
+		return err == nil
 	})
 
-	return q
+	if err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func placeholderValue(values []any, placeholder int32) (any, error) {
+	if int(placeholder) > len(values) {
+		return nil, fmt.Errorf("no value provided for placeholder $%d", placeholder)
+	}
+
+	return values[placeholder-1], nil
+}
+
+func singlePlaceholderValue(values []any, placeholder int32) (string, error) {
+	v, err := placeholderValue(values, placeholder)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := v.([]string); ok {
+		return "", fmt.Errorf("placeholder $%d requires a single value, got a list", placeholder)
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+func listPlaceholderValue(values []any, placeholder int32) ([]string, error) {
+	v, err := placeholderValue(values, placeholder)
+	if err != nil {
+		return nil, err
+	}
+
+	if list, ok := v.([]string); ok {
+		return list, nil
+	}
+
+	return []string{fmt.Sprint(v)}, nil
 }
@@ -36,7 +36,30 @@ func TestReplacePlaceholders(t *testing.T) {
 	q, err := queryparser.ParseQuery(`foo = $1 | ( bar = $2 & ^ baz = $3 )`)
 	require.NoError(t, err)
 
-	q2 := queryparser.ReplacePlaceholders(q, []string{"1", "2", "3"})
+	q2, err := queryparser.ReplacePlaceholders(q, []any{"1", "2", "3"})
+	require.NoError(t, err)
+
+	formatted, err := queryparser.QueryToString(q2)
+	require.NoError(t, err)
+	require.Equal(t, `foo = "1" | ( bar = "2" & ^ baz = "3" )`, formatted)
+}
+
+func TestReplacePlaceholdersIn(t *testing.T) {
+	q, err := queryparser.ParseQuery(`foo IN $1 & bar = $2 & baz IN $3`)
+	require.NoError(t, err)
+
+	require.Equal(t, 3, queryparser.MaxPlaceholder(q))
+
+	q2, err := queryparser.ReplacePlaceholders(q, []any{[]string{"a", "b"}, 42, "c"})
+	require.NoError(t, err)
+
+	formatted, err := queryparser.QueryToString(q2)
+	require.NoError(t, err)
+	require.Equal(t, `foo IN ("a", "b") & bar = "42" & baz IN ("c")`, formatted)
+
+	_, err = queryparser.ReplacePlaceholders(q, []any{[]string{"a", "b"}, []string{"c"}, "d"})
+	require.Error(t, err)
 
-	require.Equal(t, `foo = "1" | ( bar = "2" & ^ baz = "3" )`, queryparser.QueryToString(q2))
+	_, err = queryparser.ReplacePlaceholders(q, []any{[]string{"a", "b"}})
+	require.Error(t, err)
 }
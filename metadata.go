@@ -0,0 +1,145 @@
+package updog
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Metadata describes an index file. It is stored as JSON in the data bucket, so that it
+// can always be read, independent of the format version of the remaining index data.
+type Metadata struct {
+	// FormatVersion is the version of the format of the index data.
+	FormatVersion int `json:"format_version"`
+
+	// WriterVersion is the version of updog that wrote the index file. It is empty
+	// for index files that were written before the metadata was introduced.
+	WriterVersion string `json:"writer_version,omitempty"`
+
+	// CreatedAt is the time when the index file was created. It is zero for index
+	// files that were written before the metadata was introduced.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Rows is the number of rows in the index file, including deleted rows.
+	Rows uint32 `json:"rows"`
+
+	// Columns is the sorted list of column names.
+	Columns []string `json:"columns"`
+}
+
+const (
+	// formatVersionLegacy is the format version of index files that were written
+	// before the metadata was introduced. These index files don't contain metadata.
+	formatVersionLegacy = 1
+
+	// formatVersionMetadata is the format version that introduced the metadata.
+	formatVersionMetadata = 2
+
+	// formatVersionProtobufSchema is the format version that replaced the gob-encoded
+	// schema with a protobuf Schema message.
+	formatVersionProtobufSchema = 3
+
+	// formatVersionChecksums is the format version that introduced checksums of bitmaps.
+	formatVersionChecksums = 4
+
+	// formatVersionPresence is the format version that introduced presence bitmaps of columns.
+	formatVersionPresence = 5
+
+	// formatVersionNumeric is the format version that introduced numeric columns stored
+	// as bit-sliced indexes.
+	formatVersionNumeric = 6
+
+	// formatVersionColumnTypes is the format version that introduced types of columns.
+	formatVersionColumnTypes = 7
+
+	// formatVersionTimeBuckets is the format version that introduced time bucket columns
+	// of timestamp columns.
+	formatVersionTimeBuckets = 8
+
+	// formatVersionCurrent is the format version of index files written by this version
+	// of updog.
+	formatVersionCurrent = formatVersionTimeBuckets
+)
+
+const modulePath = "github.com/akrennmair/updog"
+
+// writerVersion returns the version of the updog module that writes the index file.
+func writerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+
+	return "unknown"
+}
+
+func newMetadata(sch *schema, rows uint32, createdAt time.Time) *Metadata {
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	return &Metadata{
+		FormatVersion: formatVersionCurrent,
+		WriterVersion: writerVersion(),
+		CreatedAt:     createdAt,
+		Rows:          rows,
+		Columns:       sch.columnNames(),
+	}
+}
+
+// readMetadata reads the metadata from the data bucket and checks whether the format
+// version is supported. For index files without metadata, only the format version of
+// the returned metadata is set.
+func readMetadata(bucket *bbolt.Bucket) (*Metadata, error) {
+	data := bucket.Get(keyMetadata)
+	if data == nil {
+		return &Metadata{FormatVersion: formatVersionLegacy}, nil
+	}
+
+	var md Metadata
+
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+
+	if md.FormatVersion < formatVersionLegacy || md.FormatVersion > formatVersionCurrent {
+		return nil, fmt.Errorf("unsupported index format version %d, this version of updog supports format versions %d to %d", md.FormatVersion, formatVersionLegacy, formatVersionCurrent)
+	}
+
+	return &md, nil
+}
+
+func writeMetadata(bucket *bbolt.Bucket, md *Metadata) error {
+	data, err := json.Marshal(md)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	return bucket.Put(keyMetadata, data)
+}
+
+// GetMetadata returns the metadata of the index file. For index files that were written
+// before the metadata was introduced, the metadata is derived from the index data.
+func (idx *Index) GetMetadata() *Metadata {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+
+	md := *idx.metadata
+	md.Columns = slices.Clone(md.Columns)
+
+	return &md
+}
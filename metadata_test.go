@@ -0,0 +1,135 @@
+package updog
+
+import (
+	"bytes"
+	"encoding/gob"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestMetadata(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := NewIndexWriter(filename)
+	w.AddRow(map[string]string{"b": "1", "a": "2"})
+	w.AddRow(map[string]string{"c": "3"})
+	require.NoError(t, w.Flush())
+
+	idx, err := OpenIndex(filename)
+	require.NoError(t, err)
+
+	md := idx.GetMetadata()
+	require.Equal(t, formatVersionCurrent, md.FormatVersion)
+	require.NotEmpty(t, md.WriterVersion)
+	require.WithinDuration(t, time.Now(), md.CreatedAt, time.Minute)
+	require.Equal(t, uint32(2), md.Rows)
+	require.Equal(t, []string{"a", "b", "c"}, md.Columns)
+
+	require.NoError(t, idx.Close())
+
+	w, err = OpenIndexWriterForAppend(filename)
+	require.NoError(t, err)
+	w.AddRow(map[string]string{"d": "4"})
+	require.NoError(t, w.Flush())
+
+	idx, err = OpenIndex(filename)
+	require.NoError(t, err)
+
+	appendedMD := idx.GetMetadata()
+	require.Equal(t, md.CreatedAt, appendedMD.CreatedAt)
+	require.Equal(t, uint32(3), appendedMD.Rows)
+	require.Equal(t, []string{"a", "b", "c", "d"}, appendedMD.Columns)
+
+	require.NoError(t, idx.Close())
+}
+
+func TestMetadataVersions(t *testing.T) {
+	testData := []struct {
+		name             string
+		metadata         []byte
+		gobSchema        bool
+		expectedMetadata *Metadata
+		expectedErr      string
+	}{
+		{
+			name:      "legacy",
+			metadata:  nil,
+			gobSchema: true,
+			expectedMetadata: &Metadata{
+				FormatVersion: formatVersionLegacy,
+				Rows:          1,
+				Columns:       []string{"a"},
+			},
+		},
+		{
+			name:      "gob schema",
+			metadata:  []byte(`{"format_version":2,"rows":1,"columns":["a"]}`),
+			gobSchema: true,
+			expectedMetadata: &Metadata{
+				FormatVersion: formatVersionMetadata,
+				Rows:          1,
+				Columns:       []string{"a"},
+			},
+		},
+		{
+			name:        "unknown version",
+			metadata:    []byte(`{"format_version":99}`),
+			expectedErr: "unsupported index format version 99",
+		},
+		{
+			name:        "invalid",
+			metadata:    []byte(`{`),
+			expectedErr: "failed to decode metadata",
+		},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "index.updog")
+
+			w := NewIndexWriter(filename)
+			w.AddRow(map[string]string{"a": "1"})
+			require.NoError(t, w.Flush())
+
+			db, err := bbolt.Open(filename, 0644, nil)
+			require.NoError(t, err)
+
+			require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+				bucket := tx.Bucket([]byte("data"))
+
+				if tt.gobSchema {
+					var buf bytes.Buffer
+					if err := gob.NewEncoder(&buf).Encode(w.schema); err != nil {
+						return err
+					}
+					if err := bucket.Put(keySchema, buf.Bytes()); err != nil {
+						return err
+					}
+				}
+
+				if tt.metadata == nil {
+					return bucket.Delete(keyMetadata)
+				}
+				return bucket.Put(keyMetadata, tt.metadata)
+			}))
+
+			idx, err := OpenIndexFromBoltDatabase(db)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			defer idx.Close()
+
+			require.Equal(t, tt.expectedMetadata, idx.GetMetadata())
+
+			result, err := idx.Execute(&Query{Expr: &ExprEqual{Column: "a", Value: "1"}})
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), result.Count)
+		})
+	}
+}
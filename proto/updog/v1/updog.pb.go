@@ -20,6 +20,173 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ColumnType is the type of the values of a column. Columns written before column types were
+// introduced are string columns.
+type ColumnType int32
+
+const (
+	ColumnType_COLUMN_TYPE_STRING    ColumnType = 0
+	ColumnType_COLUMN_TYPE_INTEGER   ColumnType = 1
+	ColumnType_COLUMN_TYPE_FLOAT     ColumnType = 2
+	ColumnType_COLUMN_TYPE_BOOLEAN   ColumnType = 3
+	ColumnType_COLUMN_TYPE_TIMESTAMP ColumnType = 4
+)
+
+// Enum value maps for ColumnType.
+var (
+	ColumnType_name = map[int32]string{
+		0: "COLUMN_TYPE_STRING",
+		1: "COLUMN_TYPE_INTEGER",
+		2: "COLUMN_TYPE_FLOAT",
+		3: "COLUMN_TYPE_BOOLEAN",
+		4: "COLUMN_TYPE_TIMESTAMP",
+	}
+	ColumnType_value = map[string]int32{
+		"COLUMN_TYPE_STRING":    0,
+		"COLUMN_TYPE_INTEGER":   1,
+		"COLUMN_TYPE_FLOAT":     2,
+		"COLUMN_TYPE_BOOLEAN":   3,
+		"COLUMN_TYPE_TIMESTAMP": 4,
+	}
+)
+
+func (x ColumnType) Enum() *ColumnType {
+	p := new(ColumnType)
+	*p = x
+	return p
+}
+
+func (x ColumnType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ColumnType) Descriptor() protoreflect.EnumDescriptor {
+	return file_updog_v1_updog_proto_enumTypes[0].Descriptor()
+}
+
+func (ColumnType) Type() protoreflect.EnumType {
+	return &file_updog_v1_updog_proto_enumTypes[0]
+}
+
+func (x ColumnType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ColumnType.Descriptor instead.
+func (ColumnType) EnumDescriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{0}
+}
+
+type Query_Expression_Match_Type int32
+
+const (
+	Query_Expression_Match_TYPE_UNSPECIFIED Query_Expression_Match_Type = 0
+	// the value starts with the pattern.
+	Query_Expression_Match_TYPE_PREFIX Query_Expression_Match_Type = 1
+	// the value ends with the pattern.
+	Query_Expression_Match_TYPE_SUFFIX Query_Expression_Match_Type = 2
+	// the value matches the pattern as regular expression.
+	Query_Expression_Match_TYPE_REGEXP Query_Expression_Match_Type = 3
+)
+
+// Enum value maps for Query_Expression_Match_Type.
+var (
+	Query_Expression_Match_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_PREFIX",
+		2: "TYPE_SUFFIX",
+		3: "TYPE_REGEXP",
+	}
+	Query_Expression_Match_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_PREFIX":      1,
+		"TYPE_SUFFIX":      2,
+		"TYPE_REGEXP":      3,
+	}
+)
+
+func (x Query_Expression_Match_Type) Enum() *Query_Expression_Match_Type {
+	p := new(Query_Expression_Match_Type)
+	*p = x
+	return p
+}
+
+func (x Query_Expression_Match_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Query_Expression_Match_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_updog_v1_updog_proto_enumTypes[1].Descriptor()
+}
+
+func (Query_Expression_Match_Type) Type() protoreflect.EnumType {
+	return &file_updog_v1_updog_proto_enumTypes[1]
+}
+
+func (x Query_Expression_Match_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Query_Expression_Match_Type.Descriptor instead.
+func (Query_Expression_Match_Type) EnumDescriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 7, 0}
+}
+
+type Query_Aggregate_Function int32
+
+const (
+	Query_Aggregate_FUNCTION_UNSPECIFIED Query_Aggregate_Function = 0
+	Query_Aggregate_FUNCTION_SUM         Query_Aggregate_Function = 1
+	Query_Aggregate_FUNCTION_AVG         Query_Aggregate_Function = 2
+	Query_Aggregate_FUNCTION_MIN         Query_Aggregate_Function = 3
+	Query_Aggregate_FUNCTION_MAX         Query_Aggregate_Function = 4
+)
+
+// Enum value maps for Query_Aggregate_Function.
+var (
+	Query_Aggregate_Function_name = map[int32]string{
+		0: "FUNCTION_UNSPECIFIED",
+		1: "FUNCTION_SUM",
+		2: "FUNCTION_AVG",
+		3: "FUNCTION_MIN",
+		4: "FUNCTION_MAX",
+	}
+	Query_Aggregate_Function_value = map[string]int32{
+		"FUNCTION_UNSPECIFIED": 0,
+		"FUNCTION_SUM":         1,
+		"FUNCTION_AVG":         2,
+		"FUNCTION_MIN":         3,
+		"FUNCTION_MAX":         4,
+	}
+)
+
+func (x Query_Aggregate_Function) Enum() *Query_Aggregate_Function {
+	p := new(Query_Aggregate_Function)
+	*p = x
+	return p
+}
+
+func (x Query_Aggregate_Function) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Query_Aggregate_Function) Descriptor() protoreflect.EnumDescriptor {
+	return file_updog_v1_updog_proto_enumTypes[2].Descriptor()
+}
+
+func (Query_Aggregate_Function) Type() protoreflect.EnumType {
+	return &file_updog_v1_updog_proto_enumTypes[2]
+}
+
+func (x Query_Aggregate_Function) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Query_Aggregate_Function.Descriptor instead.
+func (Query_Aggregate_Function) EnumDescriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 2, 0}
+}
+
 type QueryRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -122,6 +289,15 @@ type Query struct {
 	Id      int32             `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Expr    *Query_Expression `protobuf:"bytes,2,opt,name=expr,proto3" json:"expr,omitempty"`
 	GroupBy []string          `protobuf:"bytes,3,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	OrderBy *Query_OrderBy    `protobuf:"bytes,4,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	Limit   uint32            `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset  uint32            `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`
+	// min_count is the minimum count of a group to be included in the result.
+	MinCount uint64 `protobuf:"varint,7,opt,name=min_count,json=minCount,proto3" json:"min_count,omitempty"`
+	// count_distinct lists the columns whose number of distinct values is counted.
+	CountDistinct []string `protobuf:"bytes,8,rep,name=count_distinct,json=countDistinct,proto3" json:"count_distinct,omitempty"`
+	// aggregates lists the aggregate functions that are computed.
+	Aggregates []*Query_Aggregate `protobuf:"bytes,9,rep,name=aggregates,proto3" json:"aggregates,omitempty"`
 }
 
 func (x *Query) Reset() {
@@ -177,6 +353,48 @@ func (x *Query) GetGroupBy() []string {
 	return nil
 }
 
+func (x *Query) GetOrderBy() *Query_OrderBy {
+	if x != nil {
+		return x.OrderBy
+	}
+	return nil
+}
+
+func (x *Query) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Query) GetOffset() uint32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Query) GetMinCount() uint64 {
+	if x != nil {
+		return x.MinCount
+	}
+	return 0
+}
+
+func (x *Query) GetCountDistinct() []string {
+	if x != nil {
+		return x.CountDistinct
+	}
+	return nil
+}
+
+func (x *Query) GetAggregates() []*Query_Aggregate {
+	if x != nil {
+		return x.Aggregates
+	}
+	return nil
+}
+
 type Result struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -185,6 +403,18 @@ type Result struct {
 	QueryId    int32           `protobuf:"varint,1,opt,name=query_id,json=queryId,proto3" json:"query_id,omitempty"`
 	TotalCount uint64          `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
 	Groups     []*Result_Group `protobuf:"bytes,3,rep,name=groups,proto3" json:"groups,omitempty"`
+	// error is set if the query failed. In that case, total_count and groups are empty.
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// warnings lists problems that caused groups to be left out of the result.
+	Warnings []string `protobuf:"bytes,5,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	// distinct contains the distinct counts of all rows that matched the query.
+	Distinct []*Result_DistinctCount `protobuf:"bytes,6,rep,name=distinct,proto3" json:"distinct,omitempty"`
+	// aggregates contains the results of the aggregate functions over all rows that
+	// matched the query.
+	Aggregates []*Result_AggregateValue `protobuf:"bytes,7,rep,name=aggregates,proto3" json:"aggregates,omitempty"`
+	// group_by_types contains the types of the columns that the query groups by, in the
+	// order of the query's group_by, so that they are known even if there are no groups.
+	GroupByTypes []ColumnType `protobuf:"varint,8,rep,packed,name=group_by_types,json=groupByTypes,proto3,enum=updog.v1.ColumnType" json:"group_by_types,omitempty"`
 }
 
 func (x *Result) Reset() {
@@ -240,6 +470,90 @@ func (x *Result) GetGroups() []*Result_Group {
 	return nil
 }
 
+func (x *Result) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Result) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *Result) GetDistinct() []*Result_DistinctCount {
+	if x != nil {
+		return x.Distinct
+	}
+	return nil
+}
+
+func (x *Result) GetAggregates() []*Result_AggregateValue {
+	if x != nil {
+		return x.Aggregates
+	}
+	return nil
+}
+
+func (x *Result) GetGroupByTypes() []ColumnType {
+	if x != nil {
+		return x.GroupByTypes
+	}
+	return nil
+}
+
+// Schema describes the columns of an index and their values. It is stored in the data
+// bucket of index files under the key "S".
+type Schema struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Columns []*Schema_Column `protobuf:"bytes,1,rep,name=columns,proto3" json:"columns,omitempty"`
+}
+
+func (x *Schema) Reset() {
+	*x = Schema{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Schema) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Schema) ProtoMessage() {}
+
+func (x *Schema) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Schema.ProtoReflect.Descriptor instead.
+func (*Schema) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Schema) GetColumns() []*Schema_Column {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
 type Query_Expression struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -251,13 +565,19 @@ type Query_Expression struct {
 	//	*Query_Expression_Not_
 	//	*Query_Expression_And_
 	//	*Query_Expression_Or_
+	//	*Query_Expression_Range_
+	//	*Query_Expression_In_
+	//	*Query_Expression_Ne
+	//	*Query_Expression_Match_
+	//	*Query_Expression_Missing_
+	//	*Query_Expression_Present_
 	Value isQuery_Expression_Value `protobuf_oneof:"value"`
 }
 
 func (x *Query_Expression) Reset() {
 	*x = Query_Expression{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[4]
+		mi := &file_updog_v1_updog_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -270,7 +590,7 @@ func (x *Query_Expression) String() string {
 func (*Query_Expression) ProtoMessage() {}
 
 func (x *Query_Expression) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[4]
+	mi := &file_updog_v1_updog_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -321,6 +641,48 @@ func (x *Query_Expression) GetOr() *Query_Expression_Or {
 	return nil
 }
 
+func (x *Query_Expression) GetRange() *Query_Expression_Range {
+	if x, ok := x.GetValue().(*Query_Expression_Range_); ok {
+		return x.Range
+	}
+	return nil
+}
+
+func (x *Query_Expression) GetIn() *Query_Expression_In {
+	if x, ok := x.GetValue().(*Query_Expression_In_); ok {
+		return x.In
+	}
+	return nil
+}
+
+func (x *Query_Expression) GetNe() *Query_Expression_NotEqual {
+	if x, ok := x.GetValue().(*Query_Expression_Ne); ok {
+		return x.Ne
+	}
+	return nil
+}
+
+func (x *Query_Expression) GetMatch() *Query_Expression_Match {
+	if x, ok := x.GetValue().(*Query_Expression_Match_); ok {
+		return x.Match
+	}
+	return nil
+}
+
+func (x *Query_Expression) GetMissing() *Query_Expression_Missing {
+	if x, ok := x.GetValue().(*Query_Expression_Missing_); ok {
+		return x.Missing
+	}
+	return nil
+}
+
+func (x *Query_Expression) GetPresent() *Query_Expression_Present {
+	if x, ok := x.GetValue().(*Query_Expression_Present_); ok {
+		return x.Present
+	}
+	return nil
+}
+
 type isQuery_Expression_Value interface {
 	isQuery_Expression_Value()
 }
@@ -341,6 +703,30 @@ type Query_Expression_Or_ struct {
 	Or *Query_Expression_Or `protobuf:"bytes,4,opt,name=or,proto3,oneof"`
 }
 
+type Query_Expression_Range_ struct {
+	Range *Query_Expression_Range `protobuf:"bytes,5,opt,name=range,proto3,oneof"`
+}
+
+type Query_Expression_In_ struct {
+	In *Query_Expression_In `protobuf:"bytes,6,opt,name=in,proto3,oneof"`
+}
+
+type Query_Expression_Ne struct {
+	Ne *Query_Expression_NotEqual `protobuf:"bytes,7,opt,name=ne,proto3,oneof"`
+}
+
+type Query_Expression_Match_ struct {
+	Match *Query_Expression_Match `protobuf:"bytes,8,opt,name=match,proto3,oneof"`
+}
+
+type Query_Expression_Missing_ struct {
+	Missing *Query_Expression_Missing `protobuf:"bytes,9,opt,name=missing,proto3,oneof"`
+}
+
+type Query_Expression_Present_ struct {
+	Present *Query_Expression_Present `protobuf:"bytes,10,opt,name=present,proto3,oneof"`
+}
+
 func (*Query_Expression_Eq) isQuery_Expression_Value() {}
 
 func (*Query_Expression_Not_) isQuery_Expression_Value() {}
@@ -349,33 +735,46 @@ func (*Query_Expression_And_) isQuery_Expression_Value() {}
 
 func (*Query_Expression_Or_) isQuery_Expression_Value() {}
 
-type Query_Expression_Equal struct {
+func (*Query_Expression_Range_) isQuery_Expression_Value() {}
+
+func (*Query_Expression_In_) isQuery_Expression_Value() {}
+
+func (*Query_Expression_Ne) isQuery_Expression_Value() {}
+
+func (*Query_Expression_Match_) isQuery_Expression_Value() {}
+
+func (*Query_Expression_Missing_) isQuery_Expression_Value() {}
+
+func (*Query_Expression_Present_) isQuery_Expression_Value() {}
+
+// OrderBy determines the order of the groups. If column is empty, the groups are
+// ordered by their count.
+type Query_OrderBy struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Column      string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
-	Value       string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	Placeholder int32  `protobuf:"varint,3,opt,name=placeholder,proto3" json:"placeholder,omitempty"`
+	Column     string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Descending bool   `protobuf:"varint,2,opt,name=descending,proto3" json:"descending,omitempty"`
 }
 
-func (x *Query_Expression_Equal) Reset() {
-	*x = Query_Expression_Equal{}
+func (x *Query_OrderBy) Reset() {
+	*x = Query_OrderBy{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[5]
+		mi := &file_updog_v1_updog_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Query_Expression_Equal) String() string {
+func (x *Query_OrderBy) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Query_Expression_Equal) ProtoMessage() {}
+func (*Query_OrderBy) ProtoMessage() {}
 
-func (x *Query_Expression_Equal) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[5]
+func (x *Query_OrderBy) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -386,57 +785,52 @@ func (x *Query_Expression_Equal) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Query_Expression_Equal.ProtoReflect.Descriptor instead.
-func (*Query_Expression_Equal) Descriptor() ([]byte, []int) {
-	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 0}
+// Deprecated: Use Query_OrderBy.ProtoReflect.Descriptor instead.
+func (*Query_OrderBy) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 1}
 }
 
-func (x *Query_Expression_Equal) GetColumn() string {
+func (x *Query_OrderBy) GetColumn() string {
 	if x != nil {
 		return x.Column
 	}
 	return ""
 }
 
-func (x *Query_Expression_Equal) GetValue() string {
-	if x != nil {
-		return x.Value
-	}
-	return ""
-}
-
-func (x *Query_Expression_Equal) GetPlaceholder() int32 {
+func (x *Query_OrderBy) GetDescending() bool {
 	if x != nil {
-		return x.Placeholder
+		return x.Descending
 	}
-	return 0
+	return false
 }
 
-type Query_Expression_Not struct {
+// Aggregate is an aggregate function over the values of a numeric column.
+type Query_Aggregate struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Expr *Query_Expression `protobuf:"bytes,1,opt,name=expr,proto3" json:"expr,omitempty"`
+	Function Query_Aggregate_Function `protobuf:"varint,1,opt,name=function,proto3,enum=updog.v1.Query_Aggregate_Function" json:"function,omitempty"`
+	Column   string                   `protobuf:"bytes,2,opt,name=column,proto3" json:"column,omitempty"`
 }
 
-func (x *Query_Expression_Not) Reset() {
-	*x = Query_Expression_Not{}
+func (x *Query_Aggregate) Reset() {
+	*x = Query_Aggregate{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[6]
+		mi := &file_updog_v1_updog_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Query_Expression_Not) String() string {
+func (x *Query_Aggregate) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Query_Expression_Not) ProtoMessage() {}
+func (*Query_Aggregate) ProtoMessage() {}
 
-func (x *Query_Expression_Not) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[6]
+func (x *Query_Aggregate) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -447,30 +841,147 @@ func (x *Query_Expression_Not) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Query_Expression_Not.ProtoReflect.Descriptor instead.
-func (*Query_Expression_Not) Descriptor() ([]byte, []int) {
-	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 1}
+// Deprecated: Use Query_Aggregate.ProtoReflect.Descriptor instead.
+func (*Query_Aggregate) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 2}
 }
 
-func (x *Query_Expression_Not) GetExpr() *Query_Expression {
+func (x *Query_Aggregate) GetFunction() Query_Aggregate_Function {
 	if x != nil {
-		return x.Expr
+		return x.Function
 	}
-	return nil
+	return Query_Aggregate_FUNCTION_UNSPECIFIED
 }
 
-type Query_Expression_And struct {
+func (x *Query_Aggregate) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+type Query_Expression_Equal struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Exprs []*Query_Expression `protobuf:"bytes,1,rep,name=exprs,proto3" json:"exprs,omitempty"`
+	Column      string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Value       string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Placeholder int32  `protobuf:"varint,3,opt,name=placeholder,proto3" json:"placeholder,omitempty"`
+}
+
+func (x *Query_Expression_Equal) Reset() {
+	*x = Query_Expression_Equal{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Equal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Equal) ProtoMessage() {}
+
+func (x *Query_Expression_Equal) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Equal.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Equal) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 0}
+}
+
+func (x *Query_Expression_Equal) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Query_Expression_Equal) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Query_Expression_Equal) GetPlaceholder() int32 {
+	if x != nil {
+		return x.Placeholder
+	}
+	return 0
+}
+
+type Query_Expression_Not struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Expr *Query_Expression `protobuf:"bytes,1,opt,name=expr,proto3" json:"expr,omitempty"`
+}
+
+func (x *Query_Expression_Not) Reset() {
+	*x = Query_Expression_Not{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Not) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Not) ProtoMessage() {}
+
+func (x *Query_Expression_Not) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Not.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Not) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 1}
+}
+
+func (x *Query_Expression_Not) GetExpr() *Query_Expression {
+	if x != nil {
+		return x.Expr
+	}
+	return nil
+}
+
+type Query_Expression_And struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Exprs []*Query_Expression `protobuf:"bytes,1,rep,name=exprs,proto3" json:"exprs,omitempty"`
 }
 
 func (x *Query_Expression_And) Reset() {
 	*x = Query_Expression_And{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[7]
+		mi := &file_updog_v1_updog_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -483,7 +994,7 @@ func (x *Query_Expression_And) String() string {
 func (*Query_Expression_And) ProtoMessage() {}
 
 func (x *Query_Expression_And) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[7]
+	mi := &file_updog_v1_updog_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -511,26 +1022,721 @@ type Query_Expression_Or struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Exprs []*Query_Expression `protobuf:"bytes,1,rep,name=exprs,proto3" json:"exprs,omitempty"`
+	Exprs []*Query_Expression `protobuf:"bytes,1,rep,name=exprs,proto3" json:"exprs,omitempty"`
+}
+
+func (x *Query_Expression_Or) Reset() {
+	*x = Query_Expression_Or{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Or) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Or) ProtoMessage() {}
+
+func (x *Query_Expression_Or) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Or.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Or) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 3}
+}
+
+func (x *Query_Expression_Or) GetExprs() []*Query_Expression {
+	if x != nil {
+		return x.Exprs
+	}
+	return nil
+}
+
+// Range matches all rows where the value of column is numerically
+// within the range. A missing bound means that the range is unbounded
+// on that side.
+type Query_Expression_Range struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column string                        `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Lower  *Query_Expression_Range_Bound `protobuf:"bytes,2,opt,name=lower,proto3" json:"lower,omitempty"`
+	Upper  *Query_Expression_Range_Bound `protobuf:"bytes,3,opt,name=upper,proto3" json:"upper,omitempty"`
+}
+
+func (x *Query_Expression_Range) Reset() {
+	*x = Query_Expression_Range{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Range) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Range) ProtoMessage() {}
+
+func (x *Query_Expression_Range) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Range.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Range) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 4}
+}
+
+func (x *Query_Expression_Range) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Query_Expression_Range) GetLower() *Query_Expression_Range_Bound {
+	if x != nil {
+		return x.Lower
+	}
+	return nil
+}
+
+func (x *Query_Expression_Range) GetUpper() *Query_Expression_Range_Bound {
+	if x != nil {
+		return x.Upper
+	}
+	return nil
+}
+
+// In matches all rows where the value of column is one of the values.
+// If placeholder is set, the values are provided as a single list
+// argument in place of the placeholder.
+type Query_Expression_In struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column      string   `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Values      []string `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+	Placeholder int32    `protobuf:"varint,3,opt,name=placeholder,proto3" json:"placeholder,omitempty"`
+}
+
+func (x *Query_Expression_In) Reset() {
+	*x = Query_Expression_In{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_In) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_In) ProtoMessage() {}
+
+func (x *Query_Expression_In) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_In.ProtoReflect.Descriptor instead.
+func (*Query_Expression_In) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 5}
+}
+
+func (x *Query_Expression_In) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Query_Expression_In) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *Query_Expression_In) GetPlaceholder() int32 {
+	if x != nil {
+		return x.Placeholder
+	}
+	return 0
+}
+
+// NotEqual matches all rows where the value of column is not value.
+type Query_Expression_NotEqual struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column      string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Value       string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Placeholder int32  `protobuf:"varint,3,opt,name=placeholder,proto3" json:"placeholder,omitempty"`
+}
+
+func (x *Query_Expression_NotEqual) Reset() {
+	*x = Query_Expression_NotEqual{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_NotEqual) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_NotEqual) ProtoMessage() {}
+
+func (x *Query_Expression_NotEqual) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_NotEqual.ProtoReflect.Descriptor instead.
+func (*Query_Expression_NotEqual) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 6}
+}
+
+func (x *Query_Expression_NotEqual) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Query_Expression_NotEqual) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Query_Expression_NotEqual) GetPlaceholder() int32 {
+	if x != nil {
+		return x.Placeholder
+	}
+	return 0
+}
+
+// Match matches all rows where the value of column matches the pattern.
+type Query_Expression_Match struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column      string                      `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Type        Query_Expression_Match_Type `protobuf:"varint,2,opt,name=type,proto3,enum=updog.v1.Query_Expression_Match_Type" json:"type,omitempty"`
+	Pattern     string                      `protobuf:"bytes,3,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Placeholder int32                       `protobuf:"varint,4,opt,name=placeholder,proto3" json:"placeholder,omitempty"`
+}
+
+func (x *Query_Expression_Match) Reset() {
+	*x = Query_Expression_Match{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Match) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Match) ProtoMessage() {}
+
+func (x *Query_Expression_Match) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Match.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Match) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 7}
+}
+
+func (x *Query_Expression_Match) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Query_Expression_Match) GetType() Query_Expression_Match_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Query_Expression_Match_TYPE_UNSPECIFIED
+}
+
+func (x *Query_Expression_Match) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *Query_Expression_Match) GetPlaceholder() int32 {
+	if x != nil {
+		return x.Placeholder
+	}
+	return 0
+}
+
+// Missing matches all rows that have no value in column.
+type Query_Expression_Missing struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+}
+
+func (x *Query_Expression_Missing) Reset() {
+	*x = Query_Expression_Missing{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Missing) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Missing) ProtoMessage() {}
+
+func (x *Query_Expression_Missing) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Missing.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Missing) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 8}
+}
+
+func (x *Query_Expression_Missing) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+// Present matches all rows that have a value in column.
+type Query_Expression_Present struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+}
+
+func (x *Query_Expression_Present) Reset() {
+	*x = Query_Expression_Present{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Present) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Present) ProtoMessage() {}
+
+func (x *Query_Expression_Present) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Present.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Present) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 9}
+}
+
+func (x *Query_Expression_Present) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+type Query_Expression_Range_Bound struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Value       string `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Placeholder int32  `protobuf:"varint,2,opt,name=placeholder,proto3" json:"placeholder,omitempty"`
+	Exclusive   bool   `protobuf:"varint,3,opt,name=exclusive,proto3" json:"exclusive,omitempty"`
+}
+
+func (x *Query_Expression_Range_Bound) Reset() {
+	*x = Query_Expression_Range_Bound{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Query_Expression_Range_Bound) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Query_Expression_Range_Bound) ProtoMessage() {}
+
+func (x *Query_Expression_Range_Bound) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Query_Expression_Range_Bound.ProtoReflect.Descriptor instead.
+func (*Query_Expression_Range_Bound) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 4, 0}
+}
+
+func (x *Query_Expression_Range_Bound) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Query_Expression_Range_Bound) GetPlaceholder() int32 {
+	if x != nil {
+		return x.Placeholder
+	}
+	return 0
+}
+
+func (x *Query_Expression_Range_Bound) GetExclusive() bool {
+	if x != nil {
+		return x.Exclusive
+	}
+	return false
+}
+
+// DistinctCount is the number of distinct values of a column. approximate is set if
+// the count was estimated using HyperLogLog.
+type Result_DistinctCount struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column      string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Count       uint64 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Approximate bool   `protobuf:"varint,3,opt,name=approximate,proto3" json:"approximate,omitempty"`
+}
+
+func (x *Result_DistinctCount) Reset() {
+	*x = Result_DistinctCount{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Result_DistinctCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result_DistinctCount) ProtoMessage() {}
+
+func (x *Result_DistinctCount) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result_DistinctCount.ProtoReflect.Descriptor instead.
+func (*Result_DistinctCount) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{3, 0}
+}
+
+func (x *Result_DistinctCount) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Result_DistinctCount) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Result_DistinctCount) GetApproximate() bool {
+	if x != nil {
+		return x.Approximate
+	}
+	return false
+}
+
+// AggregateValue is the result of an aggregate function. int_value contains the result
+// of SUM, MIN and MAX, float_value the result of AVG. valid is false if none of the rows
+// has a value in the column.
+type Result_AggregateValue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Function   Query_Aggregate_Function `protobuf:"varint,1,opt,name=function,proto3,enum=updog.v1.Query_Aggregate_Function" json:"function,omitempty"`
+	Column     string                   `protobuf:"bytes,2,opt,name=column,proto3" json:"column,omitempty"`
+	Valid      bool                     `protobuf:"varint,3,opt,name=valid,proto3" json:"valid,omitempty"`
+	IntValue   int64                    `protobuf:"varint,4,opt,name=int_value,json=intValue,proto3" json:"int_value,omitempty"`
+	FloatValue float64                  `protobuf:"fixed64,5,opt,name=float_value,json=floatValue,proto3" json:"float_value,omitempty"`
+}
+
+func (x *Result_AggregateValue) Reset() {
+	*x = Result_AggregateValue{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Result_AggregateValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result_AggregateValue) ProtoMessage() {}
+
+func (x *Result_AggregateValue) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result_AggregateValue.ProtoReflect.Descriptor instead.
+func (*Result_AggregateValue) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{3, 1}
+}
+
+func (x *Result_AggregateValue) GetFunction() Query_Aggregate_Function {
+	if x != nil {
+		return x.Function
+	}
+	return Query_Aggregate_FUNCTION_UNSPECIFIED
+}
+
+func (x *Result_AggregateValue) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Result_AggregateValue) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *Result_AggregateValue) GetIntValue() int64 {
+	if x != nil {
+		return x.IntValue
+	}
+	return 0
+}
+
+func (x *Result_AggregateValue) GetFloatValue() float64 {
+	if x != nil {
+		return x.FloatValue
+	}
+	return 0
+}
+
+type Result_Group struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Fields     []*Result_Group_ResultField `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty"`
+	Count      uint64                      `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Distinct   []*Result_DistinctCount     `protobuf:"bytes,3,rep,name=distinct,proto3" json:"distinct,omitempty"`
+	Aggregates []*Result_AggregateValue    `protobuf:"bytes,4,rep,name=aggregates,proto3" json:"aggregates,omitempty"`
+}
+
+func (x *Result_Group) Reset() {
+	*x = Result_Group{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_updog_v1_updog_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Result_Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Result_Group) ProtoMessage() {}
+
+func (x *Result_Group) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Result_Group.ProtoReflect.Descriptor instead.
+func (*Result_Group) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{3, 2}
+}
+
+func (x *Result_Group) GetFields() []*Result_Group_ResultField {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *Result_Group) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Result_Group) GetDistinct() []*Result_DistinctCount {
+	if x != nil {
+		return x.Distinct
+	}
+	return nil
+}
+
+func (x *Result_Group) GetAggregates() []*Result_AggregateValue {
+	if x != nil {
+		return x.Aggregates
+	}
+	return nil
+}
+
+type Result_Group_ResultField struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Column string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Value  string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// type is the type of the column, which determines how value is interpreted.
+	Type ColumnType `protobuf:"varint,3,opt,name=type,proto3,enum=updog.v1.ColumnType" json:"type,omitempty"`
 }
 
-func (x *Query_Expression_Or) Reset() {
-	*x = Query_Expression_Or{}
+func (x *Result_Group_ResultField) Reset() {
+	*x = Result_Group_ResultField{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[8]
+		mi := &file_updog_v1_updog_proto_msgTypes[22]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Query_Expression_Or) String() string {
+func (x *Result_Group_ResultField) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Query_Expression_Or) ProtoMessage() {}
+func (*Result_Group_ResultField) ProtoMessage() {}
 
-func (x *Query_Expression_Or) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[8]
+func (x *Result_Group_ResultField) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[22]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -541,44 +1747,60 @@ func (x *Query_Expression_Or) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Query_Expression_Or.ProtoReflect.Descriptor instead.
-func (*Query_Expression_Or) Descriptor() ([]byte, []int) {
-	return file_updog_v1_updog_proto_rawDescGZIP(), []int{2, 0, 3}
+// Deprecated: Use Result_Group_ResultField.ProtoReflect.Descriptor instead.
+func (*Result_Group_ResultField) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{3, 2, 0}
 }
 
-func (x *Query_Expression_Or) GetExprs() []*Query_Expression {
+func (x *Result_Group_ResultField) GetColumn() string {
 	if x != nil {
-		return x.Exprs
+		return x.Column
 	}
-	return nil
+	return ""
 }
 
-type Result_Group struct {
+func (x *Result_Group_ResultField) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Result_Group_ResultField) GetType() ColumnType {
+	if x != nil {
+		return x.Type
+	}
+	return ColumnType_COLUMN_TYPE_STRING
+}
+
+type Schema_Value struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Fields []*Result_Group_ResultField `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty"`
-	Count  uint64                      `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Value string `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	// key is the key of the value's bitmap, which is stored in the data bucket under
+	// the key "V" followed by the key as 64-bit big-endian integer.
+	Key uint64 `protobuf:"varint,2,opt,name=key,proto3" json:"key,omitempty"`
 }
 
-func (x *Result_Group) Reset() {
-	*x = Result_Group{}
+func (x *Schema_Value) Reset() {
+	*x = Schema_Value{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[9]
+		mi := &file_updog_v1_updog_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Result_Group) String() string {
+func (x *Schema_Value) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Result_Group) ProtoMessage() {}
+func (*Schema_Value) ProtoMessage() {}
 
-func (x *Result_Group) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[9]
+func (x *Schema_Value) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[23]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -589,51 +1811,63 @@ func (x *Result_Group) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Result_Group.ProtoReflect.Descriptor instead.
-func (*Result_Group) Descriptor() ([]byte, []int) {
-	return file_updog_v1_updog_proto_rawDescGZIP(), []int{3, 0}
+// Deprecated: Use Schema_Value.ProtoReflect.Descriptor instead.
+func (*Schema_Value) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{4, 0}
 }
 
-func (x *Result_Group) GetFields() []*Result_Group_ResultField {
+func (x *Schema_Value) GetValue() string {
 	if x != nil {
-		return x.Fields
+		return x.Value
 	}
-	return nil
+	return ""
 }
 
-func (x *Result_Group) GetCount() uint64 {
+func (x *Schema_Value) GetKey() uint64 {
 	if x != nil {
-		return x.Count
+		return x.Key
 	}
 	return 0
 }
 
-type Result_Group_ResultField struct {
+type Schema_Column struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Column string `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
-	Value  string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-}
-
-func (x *Result_Group_ResultField) Reset() {
-	*x = Result_Group_ResultField{}
+	Name   string          `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Values []*Schema_Value `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+	// presence_key is the key of the bitmap of all rows that have a value in the
+	// column, which is stored like the bitmaps of values. It is 0 for index files
+	// written before presence bitmaps were introduced.
+	PresenceKey uint64 `protobuf:"varint,3,opt,name=presence_key,json=presenceKey,proto3" json:"presence_key,omitempty"`
+	// numeric is set if the column is stored as bit-sliced index: bit_keys contains
+	// the keys of the bitmaps of all 64 bits of the encoded values, where a key of 0
+	// means that no row has the bit set. Numeric columns don't have any values.
+	Numeric bool     `protobuf:"varint,4,opt,name=numeric,proto3" json:"numeric,omitempty"`
+	BitKeys []uint64 `protobuf:"varint,5,rep,packed,name=bit_keys,json=bitKeys,proto3" json:"bit_keys,omitempty"`
+	// type is the type of the column's values. All values are stored in the canonical
+	// form of the type.
+	Type ColumnType `protobuf:"varint,6,opt,name=type,proto3,enum=updog.v1.ColumnType" json:"type,omitempty"`
+}
+
+func (x *Schema_Column) Reset() {
+	*x = Schema_Column{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_updog_v1_updog_proto_msgTypes[10]
+		mi := &file_updog_v1_updog_proto_msgTypes[24]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *Result_Group_ResultField) String() string {
+func (x *Schema_Column) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Result_Group_ResultField) ProtoMessage() {}
+func (*Schema_Column) ProtoMessage() {}
 
-func (x *Result_Group_ResultField) ProtoReflect() protoreflect.Message {
-	mi := &file_updog_v1_updog_proto_msgTypes[10]
+func (x *Schema_Column) ProtoReflect() protoreflect.Message {
+	mi := &file_updog_v1_updog_proto_msgTypes[24]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -644,23 +1878,51 @@ func (x *Result_Group_ResultField) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Result_Group_ResultField.ProtoReflect.Descriptor instead.
-func (*Result_Group_ResultField) Descriptor() ([]byte, []int) {
-	return file_updog_v1_updog_proto_rawDescGZIP(), []int{3, 0, 0}
+// Deprecated: Use Schema_Column.ProtoReflect.Descriptor instead.
+func (*Schema_Column) Descriptor() ([]byte, []int) {
+	return file_updog_v1_updog_proto_rawDescGZIP(), []int{4, 1}
 }
 
-func (x *Result_Group_ResultField) GetColumn() string {
+func (x *Schema_Column) GetName() string {
 	if x != nil {
-		return x.Column
+		return x.Name
 	}
 	return ""
 }
 
-func (x *Result_Group_ResultField) GetValue() string {
+func (x *Schema_Column) GetValues() []*Schema_Value {
 	if x != nil {
-		return x.Value
+		return x.Values
 	}
-	return ""
+	return nil
+}
+
+func (x *Schema_Column) GetPresenceKey() uint64 {
+	if x != nil {
+		return x.PresenceKey
+	}
+	return 0
+}
+
+func (x *Schema_Column) GetNumeric() bool {
+	if x != nil {
+		return x.Numeric
+	}
+	return false
+}
+
+func (x *Schema_Column) GetBitKeys() []uint64 {
+	if x != nil {
+		return x.BitKeys
+	}
+	return nil
+}
+
+func (x *Schema_Column) GetType() ColumnType {
+	if x != nil {
+		return x.Type
+	}
+	return ColumnType_COLUMN_TYPE_STRING
 }
 
 var File_updog_v1_updog_proto protoreflect.FileDescriptor
@@ -675,75 +1937,246 @@ var file_updog_v1_updog_proto_rawDesc = []byte{
 	0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x07,
 	0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e,
 	0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52,
-	0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0xc8, 0x04, 0x0a, 0x05, 0x51, 0x75, 0x65,
+	0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0xfd, 0x10, 0x0a, 0x05, 0x51, 0x75, 0x65,
 	0x72, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x02,
 	0x69, 0x64, 0x12, 0x2e, 0x0a, 0x04, 0x65, 0x78, 0x70, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
 	0x32, 0x1a, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72,
 	0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x04, 0x65, 0x78,
 	0x70, 0x72, 0x12, 0x19, 0x0a, 0x08, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x62, 0x79, 0x18, 0x03,
-	0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x42, 0x79, 0x1a, 0xe3, 0x03,
-	0x0a, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x32, 0x0a, 0x02,
-	0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67,
-	0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73,
-	0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x48, 0x00, 0x52, 0x02, 0x65, 0x71,
-	0x12, 0x32, 0x0a, 0x03, 0x6e, 0x6f, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e,
-	0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45,
-	0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x48, 0x00, 0x52,
-	0x03, 0x6e, 0x6f, 0x74, 0x12, 0x32, 0x0a, 0x03, 0x61, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x1e, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65,
-	0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x41, 0x6e,
-	0x64, 0x48, 0x00, 0x52, 0x03, 0x61, 0x6e, 0x64, 0x12, 0x2f, 0x0a, 0x02, 0x6f, 0x72, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e,
-	0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
-	0x2e, 0x4f, 0x72, 0x48, 0x00, 0x52, 0x02, 0x6f, 0x72, 0x1a, 0x57, 0x0a, 0x05, 0x45, 0x71, 0x75,
-	0x61, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
-	0x12, 0x20, 0x0a, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64,
-	0x65, 0x72, 0x1a, 0x35, 0x0a, 0x03, 0x4e, 0x6f, 0x74, 0x12, 0x2e, 0x0a, 0x04, 0x65, 0x78, 0x70,
-	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x42, 0x79, 0x12, 0x32, 0x0a,
+	0x08, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x5f, 0x62, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79,
+	0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x42, 0x79, 0x52, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x42,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x12,
+	0x1b, 0x0a, 0x09, 0x6d, 0x69, 0x6e, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x08, 0x6d, 0x69, 0x6e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x0e,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x18, 0x08,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x44, 0x69, 0x73, 0x74, 0x69,
+	0x6e, 0x63, 0x74, 0x12, 0x39, 0x0a, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65,
+	0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e,
+	0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61,
+	0x74, 0x65, 0x52, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x1a, 0xa0,
+	0x0c, 0x0a, 0x0a, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x32, 0x0a,
+	0x02, 0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x75, 0x70, 0x64, 0x6f,
+	0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x48, 0x00, 0x52, 0x02, 0x65,
+	0x71, 0x12, 0x32, 0x0a, 0x03, 0x6e, 0x6f, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e,
+	0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e,
+	0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x4e, 0x6f, 0x74, 0x48, 0x00,
+	0x52, 0x03, 0x6e, 0x6f, 0x74, 0x12, 0x32, 0x0a, 0x03, 0x61, 0x6e, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x41,
+	0x6e, 0x64, 0x48, 0x00, 0x52, 0x03, 0x61, 0x6e, 0x64, 0x12, 0x2f, 0x0a, 0x02, 0x6f, 0x72, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31,
+	0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x2e, 0x4f, 0x72, 0x48, 0x00, 0x52, 0x02, 0x6f, 0x72, 0x12, 0x38, 0x0a, 0x05, 0x72, 0x61,
+	0x6e, 0x67, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x75, 0x70, 0x64, 0x6f,
+	0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x48, 0x00, 0x52, 0x05, 0x72,
+	0x61, 0x6e, 0x67, 0x65, 0x12, 0x2f, 0x0a, 0x02, 0x69, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1d, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x49, 0x6e, 0x48,
+	0x00, 0x52, 0x02, 0x69, 0x6e, 0x12, 0x35, 0x0a, 0x02, 0x6e, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x23, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x4e, 0x6f,
+	0x74, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x48, 0x00, 0x52, 0x02, 0x6e, 0x65, 0x12, 0x38, 0x0a, 0x05,
+	0x6d, 0x61, 0x74, 0x63, 0x68, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x75, 0x70,
+	0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70,
+	0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x48, 0x00, 0x52,
+	0x05, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x12, 0x3e, 0x0a, 0x07, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6e,
+	0x67, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e,
 	0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73,
-	0x69, 0x6f, 0x6e, 0x52, 0x04, 0x65, 0x78, 0x70, 0x72, 0x1a, 0x37, 0x0a, 0x03, 0x41, 0x6e, 0x64,
-	0x12, 0x30, 0x0a, 0x05, 0x65, 0x78, 0x70, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x1a, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79,
-	0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x65, 0x78, 0x70,
-	0x72, 0x73, 0x1a, 0x36, 0x0a, 0x02, 0x4f, 0x72, 0x12, 0x30, 0x0a, 0x05, 0x65, 0x78, 0x70, 0x72,
-	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e,
+	0x69, 0x6f, 0x6e, 0x2e, 0x4d, 0x69, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x48, 0x00, 0x52, 0x07, 0x6d,
+	0x69, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x12, 0x3e, 0x0a, 0x07, 0x70, 0x72, 0x65, 0x73, 0x65, 0x6e,
+	0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e,
 	0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73,
-	0x69, 0x6f, 0x6e, 0x52, 0x05, 0x65, 0x78, 0x70, 0x72, 0x73, 0x42, 0x07, 0x0a, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x22, 0x8d, 0x02, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x19,
-	0x0a, 0x08, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x07, 0x71, 0x75, 0x65, 0x72, 0x79, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x6f, 0x74,
-	0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a,
-	0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x2e, 0x0a, 0x06, 0x67, 0x72,
-	0x6f, 0x75, 0x70, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x75, 0x70, 0x64,
-	0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x47, 0x72, 0x6f,
-	0x75, 0x70, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x1a, 0x96, 0x01, 0x0a, 0x05, 0x47,
+	0x69, 0x6f, 0x6e, 0x2e, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x74, 0x48, 0x00, 0x52, 0x07, 0x70,
+	0x72, 0x65, 0x73, 0x65, 0x6e, 0x74, 0x1a, 0x57, 0x0a, 0x05, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x12,
+	0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x20, 0x0a,
+	0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x1a,
+	0x35, 0x0a, 0x03, 0x4e, 0x6f, 0x74, 0x12, 0x2e, 0x0a, 0x04, 0x65, 0x78, 0x70, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x52, 0x04, 0x65, 0x78, 0x70, 0x72, 0x1a, 0x37, 0x0a, 0x03, 0x41, 0x6e, 0x64, 0x12, 0x30, 0x0a,
+	0x05, 0x65, 0x78, 0x70, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x75,
+	0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x65, 0x78, 0x70, 0x72, 0x73, 0x1a,
+	0x36, 0x0a, 0x02, 0x4f, 0x72, 0x12, 0x30, 0x0a, 0x05, 0x65, 0x78, 0x70, 0x72, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x52, 0x05, 0x65, 0x78, 0x70, 0x72, 0x73, 0x1a, 0xfa, 0x01, 0x0a, 0x05, 0x52, 0x61, 0x6e, 0x67,
+	0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x3c, 0x0a, 0x05, 0x6c, 0x6f, 0x77,
+	0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x42, 0x6f, 0x75, 0x6e, 0x64,
+	0x52, 0x05, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x12, 0x3c, 0x0a, 0x05, 0x75, 0x70, 0x70, 0x65, 0x72,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x26, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x42, 0x6f, 0x75, 0x6e, 0x64, 0x52, 0x05,
+	0x75, 0x70, 0x70, 0x65, 0x72, 0x1a, 0x5d, 0x0a, 0x05, 0x42, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c,
+	0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65,
+	0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x78, 0x63, 0x6c, 0x75, 0x73,
+	0x69, 0x76, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x65, 0x78, 0x63, 0x6c, 0x75,
+	0x73, 0x69, 0x76, 0x65, 0x1a, 0x56, 0x0a, 0x02, 0x49, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f,
+	0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75,
+	0x6d, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x70, 0x6c,
+	0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x1a, 0x5a, 0x0a, 0x08,
+	0x4e, 0x6f, 0x74, 0x45, 0x71, 0x75, 0x61, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75,
+	0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68,
+	0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x70, 0x6c, 0x61,
+	0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x1a, 0xe7, 0x01, 0x0a, 0x05, 0x4d, 0x61, 0x74,
+	0x63, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x39, 0x0a, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x25, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x45, 0x78, 0x70, 0x72, 0x65, 0x73,
+	0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x4d, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x74, 0x74, 0x65, 0x72, 0x6e, 0x12,
+	0x20, 0x0a, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65, 0x72, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x68, 0x6f, 0x6c, 0x64, 0x65,
+	0x72, 0x22, 0x4f, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x54, 0x59, 0x50,
+	0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
+	0x0f, 0x0a, 0x0b, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x50, 0x52, 0x45, 0x46, 0x49, 0x58, 0x10, 0x01,
+	0x12, 0x0f, 0x0a, 0x0b, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x53, 0x55, 0x46, 0x46, 0x49, 0x58, 0x10,
+	0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x52, 0x45, 0x47, 0x45, 0x58, 0x50,
+	0x10, 0x03, 0x1a, 0x21, 0x0a, 0x07, 0x4d, 0x69, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63,
+	0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x1a, 0x21, 0x0a, 0x07, 0x50, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x74,
+	0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x42, 0x07, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x1a, 0x41, 0x0a, 0x07, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x42, 0x79, 0x12, 0x16, 0x0a, 0x06,
+	0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f,
+	0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x64, 0x65, 0x73, 0x63, 0x65, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x64, 0x65, 0x73, 0x63, 0x65, 0x6e,
+	0x64, 0x69, 0x6e, 0x67, 0x1a, 0xd1, 0x01, 0x0a, 0x09, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61,
+	0x74, 0x65, 0x12, 0x3e, 0x0a, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x22, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e,
+	0x51, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x2e,
+	0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x22, 0x6c, 0x0a, 0x08, 0x46, 0x75,
+	0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x14, 0x46, 0x55, 0x4e, 0x43, 0x54, 0x49,
+	0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00,
+	0x12, 0x10, 0x0a, 0x0c, 0x46, 0x55, 0x4e, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x55, 0x4d,
+	0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x46, 0x55, 0x4e, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x41,
+	0x56, 0x47, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x46, 0x55, 0x4e, 0x43, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x4d, 0x49, 0x4e, 0x10, 0x03, 0x12, 0x10, 0x0a, 0x0c, 0x46, 0x55, 0x4e, 0x43, 0x54, 0x49,
+	0x4f, 0x4e, 0x5f, 0x4d, 0x41, 0x58, 0x10, 0x04, 0x22, 0xbf, 0x07, 0x0a, 0x06, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x71, 0x75, 0x65, 0x72, 0x79, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x71, 0x75, 0x65, 0x72, 0x79, 0x49, 0x64, 0x12, 0x1f,
+	0x0a, 0x0b, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0a, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12,
+	0x2e, 0x0a, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x16, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x2e, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x12,
+	0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67,
+	0x73, 0x12, 0x3a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x44, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x12, 0x3f, 0x0a,
+	0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1f, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x56, 0x61, 0x6c,
+	0x75, 0x65, 0x52, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73, 0x12, 0x3a,
+	0x0a, 0x0e, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x62, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x73,
+	0x18, 0x08, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x0c, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x42, 0x79, 0x54, 0x79, 0x70, 0x65, 0x73, 0x1a, 0x5f, 0x0a, 0x0d, 0x44, 0x69,
+	0x73, 0x74, 0x69, 0x6e, 0x63, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63,
+	0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c,
+	0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x61, 0x70, 0x70,
+	0x72, 0x6f, 0x78, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b,
+	0x61, 0x70, 0x70, 0x72, 0x6f, 0x78, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x1a, 0xbc, 0x01, 0x0a, 0x0e,
+	0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x3e,
+	0x0a, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x22, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x2e, 0x46, 0x75, 0x6e, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16,
+	0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x12, 0x1b, 0x0a, 0x09,
+	0x69, 0x6e, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x08, 0x69, 0x6e, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x6c, 0x6f,
+	0x61, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a,
+	0x66, 0x6c, 0x6f, 0x61, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x1a, 0xbd, 0x02, 0x0a, 0x05, 0x47,
 	0x72, 0x6f, 0x75, 0x70, 0x12, 0x3a, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x01,
 	0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e,
 	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x2e, 0x52, 0x65, 0x73,
 	0x75, 0x6c, 0x74, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73,
 	0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
-	0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x1a, 0x3b, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
-	0x46, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a,
-	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61,
-	0x6c, 0x75, 0x65, 0x32, 0x48, 0x0a, 0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x53, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x16, 0x2e, 0x75,
-	0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e,
-	0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x8f, 0x01,
-	0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x42, 0x0a,
-	0x55, 0x70, 0x64, 0x6f, 0x67, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x32, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6b, 0x72, 0x65, 0x6e, 0x6e, 0x6d,
-	0x61, 0x69, 0x72, 0x2f, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f,
-	0x75, 0x70, 0x64, 0x6f, 0x67, 0x2f, 0x76, 0x31, 0x3b, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x76, 0x31,
-	0xa2, 0x02, 0x03, 0x55, 0x58, 0x58, 0xaa, 0x02, 0x08, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x56,
-	0x31, 0xca, 0x02, 0x08, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x5c, 0x56, 0x31, 0xe2, 0x02, 0x14, 0x55,
-	0x70, 0x64, 0x6f, 0x67, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64,
-	0x61, 0x74, 0x61, 0xea, 0x02, 0x09, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x3a, 0x3a, 0x56, 0x31, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x3a, 0x0a, 0x08, 0x64, 0x69, 0x73, 0x74, 0x69, 0x6e,
+	0x63, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x44, 0x69, 0x73, 0x74, 0x69,
+	0x6e, 0x63, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x08, 0x64, 0x69, 0x73, 0x74, 0x69, 0x6e,
+	0x63, 0x74, 0x12, 0x3f, 0x0a, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61, 0x74, 0x65, 0x73,
+	0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x41, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61,
+	0x74, 0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0a, 0x61, 0x67, 0x67, 0x72, 0x65, 0x67, 0x61,
+	0x74, 0x65, 0x73, 0x1a, 0x65, 0x0a, 0x0b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x46, 0x69, 0x65,
+	0x6c, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x12, 0x28, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14,
+	0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0xbd, 0x02, 0x0a, 0x06, 0x53,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x12, 0x31, 0x0a, 0x07, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x52,
+	0x07, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x1a, 0x2f, 0x0a, 0x05, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x1a, 0xce, 0x01, 0x0a, 0x06, 0x43, 0x6f,
+	0x6c, 0x75, 0x6d, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x2e, 0x0a, 0x06, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67,
+	0x2e, 0x76, 0x31, 0x2e, 0x53, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x72, 0x65, 0x73,
+	0x65, 0x6e, 0x63, 0x65, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b,
+	0x70, 0x72, 0x65, 0x73, 0x65, 0x6e, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x6e,
+	0x75, 0x6d, 0x65, 0x72, 0x69, 0x63, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x6e, 0x75,
+	0x6d, 0x65, 0x72, 0x69, 0x63, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x69, 0x74, 0x5f, 0x6b, 0x65, 0x79,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x04, 0x52, 0x07, 0x62, 0x69, 0x74, 0x4b, 0x65, 0x79, 0x73,
+	0x12, 0x28, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14,
+	0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x2a, 0x88, 0x01, 0x0a, 0x0a, 0x43,
+	0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f, 0x4c,
+	0x55, 0x4d, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x53, 0x54, 0x52, 0x49, 0x4e, 0x47, 0x10,
+	0x00, 0x12, 0x17, 0x0a, 0x13, 0x43, 0x4f, 0x4c, 0x55, 0x4d, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45,
+	0x5f, 0x49, 0x4e, 0x54, 0x45, 0x47, 0x45, 0x52, 0x10, 0x01, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f,
+	0x4c, 0x55, 0x4d, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x46, 0x4c, 0x4f, 0x41, 0x54, 0x10,
+	0x02, 0x12, 0x17, 0x0a, 0x13, 0x43, 0x4f, 0x4c, 0x55, 0x4d, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45,
+	0x5f, 0x42, 0x4f, 0x4f, 0x4c, 0x45, 0x41, 0x4e, 0x10, 0x03, 0x12, 0x19, 0x0a, 0x15, 0x43, 0x4f,
+	0x4c, 0x55, 0x4d, 0x4e, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x54, 0x49, 0x4d, 0x45, 0x53, 0x54,
+	0x41, 0x4d, 0x50, 0x10, 0x04, 0x32, 0x48, 0x0a, 0x0c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x16,
+	0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76,
+	0x31, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42,
+	0x8f, 0x01, 0x0a, 0x0c, 0x63, 0x6f, 0x6d, 0x2e, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2e, 0x76, 0x31,
+	0x42, 0x0a, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x32,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6b, 0x72, 0x65, 0x6e,
+	0x6e, 0x6d, 0x61, 0x69, 0x72, 0x2f, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2f, 0x75, 0x70, 0x64, 0x6f, 0x67, 0x2f, 0x76, 0x31, 0x3b, 0x75, 0x70, 0x64, 0x6f, 0x67,
+	0x76, 0x31, 0xa2, 0x02, 0x03, 0x55, 0x58, 0x58, 0xaa, 0x02, 0x08, 0x55, 0x70, 0x64, 0x6f, 0x67,
+	0x2e, 0x56, 0x31, 0xca, 0x02, 0x08, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x5c, 0x56, 0x31, 0xe2, 0x02,
+	0x14, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x5c, 0x56, 0x31, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0xea, 0x02, 0x09, 0x55, 0x70, 0x64, 0x6f, 0x67, 0x3a, 0x3a, 0x56,
+	0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -758,40 +2191,80 @@ func file_updog_v1_updog_proto_rawDescGZIP() []byte {
 	return file_updog_v1_updog_proto_rawDescData
 }
 
-var file_updog_v1_updog_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_updog_v1_updog_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_updog_v1_updog_proto_msgTypes = make([]protoimpl.MessageInfo, 25)
 var file_updog_v1_updog_proto_goTypes = []interface{}{
-	(*QueryRequest)(nil),             // 0: updog.v1.QueryRequest
-	(*QueryResponse)(nil),            // 1: updog.v1.QueryResponse
-	(*Query)(nil),                    // 2: updog.v1.Query
-	(*Result)(nil),                   // 3: updog.v1.Result
-	(*Query_Expression)(nil),         // 4: updog.v1.Query.Expression
-	(*Query_Expression_Equal)(nil),   // 5: updog.v1.Query.Expression.Equal
-	(*Query_Expression_Not)(nil),     // 6: updog.v1.Query.Expression.Not
-	(*Query_Expression_And)(nil),     // 7: updog.v1.Query.Expression.And
-	(*Query_Expression_Or)(nil),      // 8: updog.v1.Query.Expression.Or
-	(*Result_Group)(nil),             // 9: updog.v1.Result.Group
-	(*Result_Group_ResultField)(nil), // 10: updog.v1.Result.Group.ResultField
+	(ColumnType)(0),                      // 0: updog.v1.ColumnType
+	(Query_Expression_Match_Type)(0),     // 1: updog.v1.Query.Expression.Match.Type
+	(Query_Aggregate_Function)(0),        // 2: updog.v1.Query.Aggregate.Function
+	(*QueryRequest)(nil),                 // 3: updog.v1.QueryRequest
+	(*QueryResponse)(nil),                // 4: updog.v1.QueryResponse
+	(*Query)(nil),                        // 5: updog.v1.Query
+	(*Result)(nil),                       // 6: updog.v1.Result
+	(*Schema)(nil),                       // 7: updog.v1.Schema
+	(*Query_Expression)(nil),             // 8: updog.v1.Query.Expression
+	(*Query_OrderBy)(nil),                // 9: updog.v1.Query.OrderBy
+	(*Query_Aggregate)(nil),              // 10: updog.v1.Query.Aggregate
+	(*Query_Expression_Equal)(nil),       // 11: updog.v1.Query.Expression.Equal
+	(*Query_Expression_Not)(nil),         // 12: updog.v1.Query.Expression.Not
+	(*Query_Expression_And)(nil),         // 13: updog.v1.Query.Expression.And
+	(*Query_Expression_Or)(nil),          // 14: updog.v1.Query.Expression.Or
+	(*Query_Expression_Range)(nil),       // 15: updog.v1.Query.Expression.Range
+	(*Query_Expression_In)(nil),          // 16: updog.v1.Query.Expression.In
+	(*Query_Expression_NotEqual)(nil),    // 17: updog.v1.Query.Expression.NotEqual
+	(*Query_Expression_Match)(nil),       // 18: updog.v1.Query.Expression.Match
+	(*Query_Expression_Missing)(nil),     // 19: updog.v1.Query.Expression.Missing
+	(*Query_Expression_Present)(nil),     // 20: updog.v1.Query.Expression.Present
+	(*Query_Expression_Range_Bound)(nil), // 21: updog.v1.Query.Expression.Range.Bound
+	(*Result_DistinctCount)(nil),         // 22: updog.v1.Result.DistinctCount
+	(*Result_AggregateValue)(nil),        // 23: updog.v1.Result.AggregateValue
+	(*Result_Group)(nil),                 // 24: updog.v1.Result.Group
+	(*Result_Group_ResultField)(nil),     // 25: updog.v1.Result.Group.ResultField
+	(*Schema_Value)(nil),                 // 26: updog.v1.Schema.Value
+	(*Schema_Column)(nil),                // 27: updog.v1.Schema.Column
 }
 var file_updog_v1_updog_proto_depIdxs = []int32{
-	2,  // 0: updog.v1.QueryRequest.queries:type_name -> updog.v1.Query
-	3,  // 1: updog.v1.QueryResponse.results:type_name -> updog.v1.Result
-	4,  // 2: updog.v1.Query.expr:type_name -> updog.v1.Query.Expression
-	9,  // 3: updog.v1.Result.groups:type_name -> updog.v1.Result.Group
-	5,  // 4: updog.v1.Query.Expression.eq:type_name -> updog.v1.Query.Expression.Equal
-	6,  // 5: updog.v1.Query.Expression.not:type_name -> updog.v1.Query.Expression.Not
-	7,  // 6: updog.v1.Query.Expression.and:type_name -> updog.v1.Query.Expression.And
-	8,  // 7: updog.v1.Query.Expression.or:type_name -> updog.v1.Query.Expression.Or
-	4,  // 8: updog.v1.Query.Expression.Not.expr:type_name -> updog.v1.Query.Expression
-	4,  // 9: updog.v1.Query.Expression.And.exprs:type_name -> updog.v1.Query.Expression
-	4,  // 10: updog.v1.Query.Expression.Or.exprs:type_name -> updog.v1.Query.Expression
-	10, // 11: updog.v1.Result.Group.fields:type_name -> updog.v1.Result.Group.ResultField
-	0,  // 12: updog.v1.QueryService.Query:input_type -> updog.v1.QueryRequest
-	1,  // 13: updog.v1.QueryService.Query:output_type -> updog.v1.QueryResponse
-	13, // [13:14] is the sub-list for method output_type
-	12, // [12:13] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	5,  // 0: updog.v1.QueryRequest.queries:type_name -> updog.v1.Query
+	6,  // 1: updog.v1.QueryResponse.results:type_name -> updog.v1.Result
+	8,  // 2: updog.v1.Query.expr:type_name -> updog.v1.Query.Expression
+	9,  // 3: updog.v1.Query.order_by:type_name -> updog.v1.Query.OrderBy
+	10, // 4: updog.v1.Query.aggregates:type_name -> updog.v1.Query.Aggregate
+	24, // 5: updog.v1.Result.groups:type_name -> updog.v1.Result.Group
+	22, // 6: updog.v1.Result.distinct:type_name -> updog.v1.Result.DistinctCount
+	23, // 7: updog.v1.Result.aggregates:type_name -> updog.v1.Result.AggregateValue
+	0,  // 8: updog.v1.Result.group_by_types:type_name -> updog.v1.ColumnType
+	27, // 9: updog.v1.Schema.columns:type_name -> updog.v1.Schema.Column
+	11, // 10: updog.v1.Query.Expression.eq:type_name -> updog.v1.Query.Expression.Equal
+	12, // 11: updog.v1.Query.Expression.not:type_name -> updog.v1.Query.Expression.Not
+	13, // 12: updog.v1.Query.Expression.and:type_name -> updog.v1.Query.Expression.And
+	14, // 13: updog.v1.Query.Expression.or:type_name -> updog.v1.Query.Expression.Or
+	15, // 14: updog.v1.Query.Expression.range:type_name -> updog.v1.Query.Expression.Range
+	16, // 15: updog.v1.Query.Expression.in:type_name -> updog.v1.Query.Expression.In
+	17, // 16: updog.v1.Query.Expression.ne:type_name -> updog.v1.Query.Expression.NotEqual
+	18, // 17: updog.v1.Query.Expression.match:type_name -> updog.v1.Query.Expression.Match
+	19, // 18: updog.v1.Query.Expression.missing:type_name -> updog.v1.Query.Expression.Missing
+	20, // 19: updog.v1.Query.Expression.present:type_name -> updog.v1.Query.Expression.Present
+	2,  // 20: updog.v1.Query.Aggregate.function:type_name -> updog.v1.Query.Aggregate.Function
+	8,  // 21: updog.v1.Query.Expression.Not.expr:type_name -> updog.v1.Query.Expression
+	8,  // 22: updog.v1.Query.Expression.And.exprs:type_name -> updog.v1.Query.Expression
+	8,  // 23: updog.v1.Query.Expression.Or.exprs:type_name -> updog.v1.Query.Expression
+	21, // 24: updog.v1.Query.Expression.Range.lower:type_name -> updog.v1.Query.Expression.Range.Bound
+	21, // 25: updog.v1.Query.Expression.Range.upper:type_name -> updog.v1.Query.Expression.Range.Bound
+	1,  // 26: updog.v1.Query.Expression.Match.type:type_name -> updog.v1.Query.Expression.Match.Type
+	2,  // 27: updog.v1.Result.AggregateValue.function:type_name -> updog.v1.Query.Aggregate.Function
+	25, // 28: updog.v1.Result.Group.fields:type_name -> updog.v1.Result.Group.ResultField
+	22, // 29: updog.v1.Result.Group.distinct:type_name -> updog.v1.Result.DistinctCount
+	23, // 30: updog.v1.Result.Group.aggregates:type_name -> updog.v1.Result.AggregateValue
+	0,  // 31: updog.v1.Result.Group.ResultField.type:type_name -> updog.v1.ColumnType
+	26, // 32: updog.v1.Schema.Column.values:type_name -> updog.v1.Schema.Value
+	0,  // 33: updog.v1.Schema.Column.type:type_name -> updog.v1.ColumnType
+	3,  // 34: updog.v1.QueryService.Query:input_type -> updog.v1.QueryRequest
+	4,  // 35: updog.v1.QueryService.Query:output_type -> updog.v1.QueryResponse
+	35, // [35:36] is the sub-list for method output_type
+	34, // [34:35] is the sub-list for method input_type
+	34, // [34:34] is the sub-list for extension type_name
+	34, // [34:34] is the sub-list for extension extendee
+	0,  // [0:34] is the sub-list for field type_name
 }
 
 func init() { file_updog_v1_updog_proto_init() }
@@ -849,7 +2322,7 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Query_Expression); i {
+			switch v := v.(*Schema); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -861,7 +2334,7 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Query_Expression_Equal); i {
+			switch v := v.(*Query_Expression); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -873,7 +2346,7 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Query_Expression_Not); i {
+			switch v := v.(*Query_OrderBy); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -885,7 +2358,7 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Query_Expression_And); i {
+			switch v := v.(*Query_Aggregate); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -897,7 +2370,7 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Query_Expression_Or); i {
+			switch v := v.(*Query_Expression_Equal); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -909,7 +2382,7 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Result_Group); i {
+			switch v := v.(*Query_Expression_Not); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -921,6 +2394,150 @@ func file_updog_v1_updog_proto_init() {
 			}
 		}
 		file_updog_v1_updog_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_And); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_Or); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_Range); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_In); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_NotEqual); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_Match); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_Missing); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_Present); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Query_Expression_Range_Bound); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Result_DistinctCount); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Result_AggregateValue); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Result_Group); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Result_Group_ResultField); i {
 			case 0:
 				return &v.state
@@ -932,25 +2549,56 @@ func file_updog_v1_updog_proto_init() {
 				return nil
 			}
 		}
+		file_updog_v1_updog_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Schema_Value); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_updog_v1_updog_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Schema_Column); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
-	file_updog_v1_updog_proto_msgTypes[4].OneofWrappers = []interface{}{
+	file_updog_v1_updog_proto_msgTypes[5].OneofWrappers = []interface{}{
 		(*Query_Expression_Eq)(nil),
 		(*Query_Expression_Not_)(nil),
 		(*Query_Expression_And_)(nil),
 		(*Query_Expression_Or_)(nil),
+		(*Query_Expression_Range_)(nil),
+		(*Query_Expression_In_)(nil),
+		(*Query_Expression_Ne)(nil),
+		(*Query_Expression_Match_)(nil),
+		(*Query_Expression_Missing_)(nil),
+		(*Query_Expression_Present_)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_updog_v1_updog_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   11,
+			NumEnums:      3,
+			NumMessages:   25,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_updog_v1_updog_proto_goTypes,
 		DependencyIndexes: file_updog_v1_updog_proto_depIdxs,
+		EnumInfos:         file_updog_v1_updog_proto_enumTypes,
 		MessageInfos:      file_updog_v1_updog_proto_msgTypes,
 	}.Build()
 	File_updog_v1_updog_proto = out.File
@@ -1,9 +1,16 @@
 package updog
 
 import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
 	"fmt"
-	"math/bits"
+	"math"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,22 +18,84 @@ import (
 )
 
 // Query describes a count query to execute on an index. updog allows you to run
-// the equivalent of SQL queries like `SELECT x, y, z, COUNT(*) WHERE ... GROUP BY x, y, z`.
+// the equivalent of SQL queries like `SELECT x, y, z, COUNT(*) WHERE ... GROUP BY x, y, z`,
+// optionally with aggregates like `SUM(n)` over numeric columns.
 type Query struct {
-	// Expr is the expression you want to limit your query on. You can use the types ExprEqual, ExprNot,
-	// ExprAnd and ExprOr to construct your expression.
+	// Expr is the expression you want to limit your query on. You can use the types ExprEqual, ExprIn,
+	// ExprRange, ExprMatch, ExprPresent, ExprMissing, ExprNot, ExprAnd and ExprOr to construct your
+	// expression.
 	Expr Expression
 
 	// GroupBy is a list of column names you want to group by. The result will then contain the
 	// results for all available values of all the listed columns for which a non-zero count result
-	// was determined.
+	// was determined. Rows with multiple values in a column (see IndexWriter.AddRowMulti) are
+	// counted in the group of each of their values, so the counts of all groups can add up to
+	// more than the total count.
 	GroupBy []string
 
+	// MinCount is the minimum count a group needs to have to be included in the grouped
+	// results, equivalent to `HAVING COUNT(*) >= MinCount` in SQL.
+	MinCount uint64
+
+	// OrderBy determines the order of the grouped results. If it is nil, the groups are
+	// ordered by their values, in the order of the GroupBy columns.
+	OrderBy *OrderBy
+
+	// Limit is the maximum number of grouped results to return. If it is 0, all grouped
+	// results are returned.
+	Limit int
+
+	// Offset is the number of grouped results to skip before returning results.
+	Offset int
+
+	// CountDistinct is a list of column names whose number of distinct values is counted,
+	// both among all rows that matched the query expression and among the rows of each group,
+	// equivalent to `COUNT(DISTINCT x)` in SQL.
+	CountDistinct []string
+
+	// Aggregates is a list of aggregate functions over numeric columns, which are computed
+	// both among all rows that matched the query expression and among the rows of each group.
+	Aggregates []Aggregate
+
 	groupByFields []groupBy
 }
 
+// OrderBy describes how grouped results are ordered. Groups that are equal according to
+// the OrderBy are ordered by their values, in the order of the GroupBy columns.
+type OrderBy struct {
+	// Column is the name of the column to order by. It must be one of the GroupBy columns.
+	// If it is empty, the groups are ordered by their count.
+	Column string
+
+	// Descending reverses the order from ascending to descending.
+	Descending bool
+}
+
 // Execute runs the provided query on the index and returns the query result.
 func (idx *Index) Execute(q *Query) (*Result, error) {
+	return idx.ExecuteContext(context.Background(), q)
+}
+
+// ExecuteContext runs the provided query on the index and returns the query result.
+// If the context is cancelled or its deadline is exceeded while the query is being
+// evaluated, the evaluation is stopped and the context's error is returned.
+func (idx *Index) ExecuteContext(ctx context.Context, q *Query) (*Result, error) {
+	result, err := idx.executeContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.finish(q); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// executeContext runs the provided query on the index. The distinct counts and aggregates
+// of the result aren't determined yet, so that the sets of distinct values and the states
+// of aggregates of results from multiple indexes can be merged first.
+func (idx *Index) executeContext(ctx context.Context, q *Query) (*Result, error) {
 	if idx.metrics.ExecuteDuration != nil {
 		defer func(t0 time.Time) {
 			idx.metrics.ExecuteDuration.Observe(time.Since(t0).Seconds())
@@ -36,18 +105,54 @@ func (idx *Index) Execute(q *Query) (*Result, error) {
 	idx.mtx.RLock()
 	defer idx.mtx.RUnlock()
 
-	if err := q.populateGroupBy(q.GroupBy, idx.schema); err != nil {
+	if err := q.populateGroupBy(q.GroupBy, idx); err != nil {
+		return nil, err
+	}
+
+	if err := q.checkOrder(); err != nil {
 		return nil, err
 	}
 
-	result, err := q.Expr.eval(idx)
+	result, err := q.Expr.eval(ctx, idx, idx.exprKeys())
 	if err != nil {
 		return nil, err
 	}
 
+	if !idx.deleted.IsEmpty() {
+		result = roaring.AndNot(result, idx.deleted)
+	}
+
+	warnings := &readWarnings{}
+
+	groups, err := q.groupBy(ctx, result, idx, warnings)
+	if err != nil {
+		return nil, err
+	}
+
+	var distinct []*distinctSet
+
+	if len(q.CountDistinct) > 0 {
+		distinct, err = idx.distinctSets(ctx, result, q.CountDistinct)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var aggregates []*aggregateState
+
+	if len(q.Aggregates) > 0 {
+		aggregates, err = idx.aggregateStates(ctx, result, q.Aggregates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Result{
-		Count:  result.GetCardinality(),
-		Groups: q.groupBy(result, idx),
+		Count:      result.GetCardinality(),
+		Groups:     groups,
+		Warnings:   warnings.messages,
+		distinct:   distinct,
+		aggregates: aggregates,
 	}, nil
 }
 
@@ -59,6 +164,62 @@ type Result struct {
 	// Groups contains a list of grouped results. If no GroupBy list was provided
 	// in the query, this list will be empty.
 	Groups []ResultGroup
+
+	// Warnings contains a list of problems that occurred while the query was executed
+	// and that caused groups to be left out of the result. Warnings are only reported
+	// if the index was opened with the PartialResultOnReadError policy.
+	Warnings []string
+
+	// Distinct contains the distinct counts of the CountDistinct columns of the query
+	// among all rows that matched the query expression.
+	Distinct []DistinctCount
+
+	// Aggregates contains the results of the Aggregates of the query among all rows that
+	// matched the query expression.
+	Aggregates []AggregateValue
+
+	distinct   []*distinctSet
+	aggregates []*aggregateState
+}
+
+// finish determines the distinct counts and the aggregates of the result and its groups
+// from the sets of distinct values and the states of the aggregates.
+func (r *Result) finish(q *Query) (err error) {
+	r.Distinct = distinctCounts(r.distinct, q.CountDistinct)
+	r.distinct = nil
+
+	if r.Aggregates, err = aggregateValues(r.aggregates, q.Aggregates); err != nil {
+		return err
+	}
+	r.aggregates = nil
+
+	for i := range r.Groups {
+		g := &r.Groups[i]
+
+		g.Distinct = distinctCounts(g.distinct, q.CountDistinct)
+		g.distinct = nil
+
+		if g.Aggregates, err = aggregateValues(g.aggregates, q.Aggregates); err != nil {
+			return err
+		}
+		g.aggregates = nil
+	}
+
+	return nil
+}
+
+func distinctCounts(sets []*distinctSet, columns []string) []DistinctCount {
+	if len(sets) == 0 {
+		return nil
+	}
+
+	counts := make([]DistinctCount, 0, len(sets))
+
+	for i, set := range sets {
+		counts = append(counts, set.count(columns[i]))
+	}
+
+	return counts
 }
 
 // ResultGroup contains a single grouped result.
@@ -69,28 +230,56 @@ type ResultGroup struct {
 
 	// Count contains the determined count for the list of result fields.
 	Count uint64
+
+	// Distinct contains the distinct counts of the CountDistinct columns of the query
+	// among the rows of the group.
+	Distinct []DistinctCount
+
+	// Aggregates contains the results of the Aggregates of the query among the rows of
+	// the group.
+	Aggregates []AggregateValue
+
+	distinct   []*distinctSet
+	aggregates []*aggregateState
 }
 
 // ResultField contains a single column name and value. It is used in ResultGroup objects.
 type ResultField struct {
 	Column string
 	Value  string
+
+	// Type is the type of the column. Values of typed columns are in the canonical form
+	// of the type.
+	Type ColumnType
+}
+
+// TypedValue returns the value according to the type of the column, i.e. as int64 for integer
+// columns, float64 for float columns, bool for boolean columns, time.Time for timestamp columns
+// and string for string columns.
+func (f ResultField) TypedValue() any {
+	return f.Type.parse(f.Value)
 }
 
 type Expression interface {
-	eval(idx *Index) (*roaring.Bitmap, error)
+	eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error)
 	String() string
-	cacheKey() uint64
+
+	// canonical returns a canonical encoding of the expression that is used to derive
+	// cache keys. Semantically equivalent expressions that only differ in the order or
+	// duplication of sub-expressions of AND and OR expressions have the same canonical
+	// encoding, while all other expressions are guaranteed to have different ones. The
+	// canonical encodings of sub-expressions are obtained from keys.
+	canonical(keys *exprKeys) string
 }
 
-func (q *Query) populateGroupBy(columns []string, sch *schema) error {
+func (q *Query) populateGroupBy(columns []string, idx *Index) error {
 	for _, colName := range columns {
-		col, ok := sch.Columns[colName]
-		if !ok {
-			return fmt.Errorf("column %q not found", colName)
+		col, err := idx.valueColumn(colName)
+		if err != nil {
+			return err
 		}
 
-		gb := groupBy{Column: colName}
+		gb := groupBy{Column: colName, Type: col.Type}
 
 		for v, valueIdx := range col.Values {
 			gb.Values = append(gb.Values, groupByValue{
@@ -99,9 +288,7 @@ func (q *Query) populateGroupBy(columns []string, sch *schema) error {
 			})
 		}
 
-		sort.Slice(gb.Values, func(i, j int) bool {
-			return gb.Values[i].Value < gb.Values[j].Value
-		})
+		sortByType(col.Type, gb.Values, func(v groupByValue) string { return v.Value })
 
 		q.groupByFields = append(q.groupByFields, gb)
 	}
@@ -109,57 +296,342 @@ func (q *Query) populateGroupBy(columns []string, sch *schema) error {
 	return nil
 }
 
+func (q *Query) checkOrder() error {
+	if q.Limit < 0 {
+		return fmt.Errorf("invalid limit %d", q.Limit)
+	}
+
+	if q.Offset < 0 {
+		return fmt.Errorf("invalid offset %d", q.Offset)
+	}
+
+	if q.OrderBy != nil && q.OrderBy.Column != "" && !slices.Contains(q.GroupBy, q.OrderBy.Column) {
+		return fmt.Errorf("can't order by column %q as it is not grouped by", q.OrderBy.Column)
+	}
+
+	return nil
+}
+
 type resultGroup struct {
 	fields []ResultField
 	result *roaring.Bitmap
+	count  uint64
 }
 
-func (q *Query) groupBy(result *roaring.Bitmap, idx *Index) (finalResult []ResultGroup) {
+func (q *Query) groupBy(ctx context.Context, result *roaring.Bitmap, idx *Index, warnings *readWarnings) (finalResult []ResultGroup, err error) {
 	if len(q.groupByFields) == 0 {
+		return nil, nil
+	}
+
+	root := resultGroup{result: result, count: result.GetCardinality()}
+
+	if q.OrderBy != nil && q.OrderBy.Column == "" && q.OrderBy.Descending && q.Limit > 0 {
+		finalResult, err = q.topGroupsByCount(ctx, root, idx, q.Offset+q.Limit, warnings)
+	} else {
+		finalResult, err = q.allGroups(ctx, root, idx, warnings)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return q.orderGroups(finalResult), nil
+}
+
+// orderGroups orders grouped results that are ordered by their values according to the
+// query's OrderBy, and then applies the query's Offset and Limit.
+func (q *Query) orderGroups(groups []ResultGroup) []ResultGroup {
+	if q.OrderBy != nil {
+		sortGroups(groups, q.compareGroups)
+	}
+
+	if q.Offset >= len(groups) {
 		return nil
 	}
 
-	resultGroups := []resultGroup{
-		{result: result},
+	groups = groups[q.Offset:]
+
+	if q.Limit > 0 && q.Limit < len(groups) {
+		groups = groups[:q.Limit]
 	}
 
+	return groups
+}
+
+// allGroups expands the result level by level into all groups with a non-zero count.
+// The groups are returned ordered by their values.
+func (q *Query) allGroups(ctx context.Context, root resultGroup, idx *Index, warnings *readWarnings) (finalResult []ResultGroup, err error) {
+	resultGroups := []resultGroup{root}
+
 	for _, gbf := range q.groupByFields {
 		var newResultGroups []resultGroup
 
 		for _, rg := range resultGroups {
-			for _, v := range gbf.Values {
-				vbm, err := idx.values.GetCol(v.Idx)
-				if err != nil {
-					continue
-				}
-
-				result := roaring.And(rg.result, vbm)
-				if result.GetCardinality() == 0 {
-					continue
-				}
-
-				newResultGroups = append(newResultGroups, resultGroup{
-					fields: append(rg.fields, ResultField{Column: gbf.Column, Value: v.Value}),
-					result: result,
-				})
+			subGroups, err := q.expandGroup(ctx, rg, gbf, idx, warnings)
+			if err != nil {
+				return nil, err
 			}
+
+			newResultGroups = append(newResultGroups, subGroups...)
 		}
 
 		resultGroups = newResultGroups
 	}
 
 	for _, rg := range resultGroups {
-		finalResult = append(finalResult, ResultGroup{
-			Fields: rg.fields,
-			Count:  rg.result.GetCardinality(),
+		g, err := q.newResultGroup(ctx, rg, idx)
+		if err != nil {
+			return nil, err
+		}
+
+		finalResult = append(finalResult, g)
+	}
+
+	return finalResult, nil
+}
+
+// newResultGroup turns a fully expanded group into a grouped result, including the sets
+// of distinct values of the query's CountDistinct columns and the states of its Aggregates.
+func (q *Query) newResultGroup(ctx context.Context, rg resultGroup, idx *Index) (ResultGroup, error) {
+	g := ResultGroup{Fields: rg.fields, Count: rg.count}
+
+	if len(q.CountDistinct) > 0 {
+		distinct, err := idx.distinctSets(ctx, rg.result, q.CountDistinct)
+		if err != nil {
+			return ResultGroup{}, err
+		}
+		g.distinct = distinct
+	}
+
+	if len(q.Aggregates) > 0 {
+		aggregates, err := idx.aggregateStates(ctx, rg.result, q.Aggregates)
+		if err != nil {
+			return ResultGroup{}, err
+		}
+		g.aggregates = aggregates
+	}
+
+	return g, nil
+}
+
+// topGroupsByCount returns the n groups with the highest counts, in no particular order.
+// As the count of a group can only shrink when it is expanded by further columns, groups
+// are expanded depth-first in descending order of their counts, and no group is expanded
+// whose count is already lower than the lowest count of the n best groups found so far.
+func (q *Query) topGroupsByCount(ctx context.Context, root resultGroup, idx *Index, n int, warnings *readWarnings) ([]ResultGroup, error) {
+	top := &groupHeap{compare: q.compareGroups}
+
+	var expand func(level int, rg resultGroup) error
+
+	expand = func(level int, rg resultGroup) error {
+		if level == len(q.groupByFields) {
+			kg := newKeyedGroup(ResultGroup{Fields: rg.fields, Count: rg.count})
+			if top.Len() == n && q.compareGroups(kg, top.groups[0]) >= 0 {
+				return nil
+			}
+
+			// the distinct counts and aggregates don't affect the order, so they are only
+			// determined for groups that make it into the top groups.
+			g, err := q.newResultGroup(ctx, rg, idx)
+			if err != nil {
+				return err
+			}
+
+			kg.group = g
+
+			if top.Len() < n {
+				heap.Push(top, kg)
+			} else {
+				top.groups[0] = kg
+				heap.Fix(top, 0)
+			}
+			return nil
+		}
+
+		subGroups, err := q.expandGroup(ctx, rg, q.groupByFields[level], idx, warnings)
+		if err != nil {
+			return err
+		}
+
+		slices.SortStableFunc(subGroups, func(a, b resultGroup) int {
+			return cmp.Compare(b.count, a.count)
 		})
+
+		for _, sg := range subGroups {
+			if top.Len() == n && sg.count < top.groups[0].group.Count {
+				break
+			}
+
+			if err := expand(level+1, sg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := expand(0, root); err != nil {
+		return nil, err
+	}
+
+	groups := make([]ResultGroup, 0, top.Len())
+	for _, kg := range top.groups {
+		groups = append(groups, kg.group)
 	}
 
-	return finalResult
+	return groups, nil
+}
+
+// expandGroup splits up a group by all values of a column. Only groups with a non-zero
+// count of at least MinCount are returned, ordered by their values. As counts can only
+// shrink when a group is expanded by further columns, groups below MinCount can be
+// dropped before they are expanded any further. If the bitmap of a value can't be read,
+// an error is returned, unless the index uses the PartialResultOnReadError policy, in which
+// case all groups with that value are left out and a warning is recorded instead.
+func (q *Query) expandGroup(ctx context.Context, rg resultGroup, gbf groupBy, idx *Index, warnings *readWarnings) ([]resultGroup, error) {
+	var subGroups []resultGroup
+
+	for _, v := range gbf.Values {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		vbm, err := idx.values.GetCol(v.Idx)
+		if err == nil && vbm == nil {
+			err = errors.New("bitmap not found")
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read bitmap of value %q of column %q: %w", v.Value, gbf.Column, err)
+			if idx.readErrorPolicy != PartialResultOnReadError {
+				return nil, err
+			}
+			warnings.add(v.Idx, err)
+			continue
+		}
+
+		result := roaring.And(rg.result, vbm)
+
+		count := result.GetCardinality()
+		if count == 0 || count < q.MinCount {
+			continue
+		}
+
+		subGroups = append(subGroups, resultGroup{
+			fields: append(slices.Clip(rg.fields), ResultField{Column: gbf.Column, Value: v.Value, Type: gbf.Type}),
+			result: result,
+			count:  count,
+		})
+	}
+
+	return subGroups, nil
+}
+
+// keyedGroup is a grouped result with the sort keys of the values of its fields, which are
+// parsed once so that the group can be compared to other groups repeatedly.
+type keyedGroup struct {
+	group ResultGroup
+	keys  []sortKey
+}
+
+func newKeyedGroup(g ResultGroup) keyedGroup {
+	keys := make([]sortKey, 0, len(g.Fields))
+	for _, f := range g.Fields {
+		keys = append(keys, f.Type.sortKey(f.Value))
+	}
+
+	return keyedGroup{group: g, keys: keys}
+}
+
+// sortGroups sorts grouped results, parsing the values of their fields only once.
+func sortGroups(groups []ResultGroup, compare func(a, b keyedGroup) int) {
+	keyed := make([]keyedGroup, 0, len(groups))
+	for _, g := range groups {
+		keyed = append(keyed, newKeyedGroup(g))
+	}
+
+	slices.SortFunc(keyed, compare)
+
+	for i, kg := range keyed {
+		groups[i] = kg.group
+	}
+}
+
+// compareGroups compares two grouped results according to the query's OrderBy.
+func (q *Query) compareGroups(a, b keyedGroup) int {
+	if q.OrderBy == nil {
+		return compareGroupFields(a, b)
+	}
+
+	var c int
+
+	if q.OrderBy.Column == "" {
+		c = cmp.Compare(a.group.Count, b.group.Count)
+	} else {
+		i := slices.Index(q.GroupBy, q.OrderBy.Column)
+		c = a.group.Fields[i].Type.compare(a.keys[i], b.keys[i])
+	}
+
+	if q.OrderBy.Descending {
+		c = -c
+	}
+
+	if c != 0 {
+		return c
+	}
+
+	return compareGroupFields(a, b)
+}
+
+// compareGroupFields compares two grouped results of the same query by the values of their fields.
+func compareGroupFields(a, b keyedGroup) int {
+	for i, f := range a.group.Fields {
+		if c := f.Type.compare(a.keys[i], b.keys[i]); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// groupHeap is a heap of grouped results where the root is the group that is ordered last.
+type groupHeap struct {
+	groups  []keyedGroup
+	compare func(a, b keyedGroup) int
+}
+
+func (h *groupHeap) Len() int           { return len(h.groups) }
+func (h *groupHeap) Less(i, j int) bool { return h.compare(h.groups[i], h.groups[j]) > 0 }
+func (h *groupHeap) Swap(i, j int)      { h.groups[i], h.groups[j] = h.groups[j], h.groups[i] }
+func (h *groupHeap) Push(x any)         { h.groups = append(h.groups, x.(keyedGroup)) }
+
+func (h *groupHeap) Pop() any {
+	g := h.groups[len(h.groups)-1]
+	h.groups = h.groups[:len(h.groups)-1]
+	return g
+}
+
+// readWarnings collects the errors of values whose bitmaps couldn't be read, reporting
+// each value only once.
+type readWarnings struct {
+	reported map[uint64]bool
+	messages []string
+}
+
+func (w *readWarnings) add(valueIdx uint64, err error) {
+	if w.reported[valueIdx] {
+		return
+	}
+
+	if w.reported == nil {
+		w.reported = make(map[uint64]bool)
+	}
+
+	w.reported[valueIdx] = true
+	w.messages = append(w.messages, err.Error())
 }
 
 type groupBy struct {
 	Column string
+	Type   ColumnType
 	Values []groupByValue
 }
 
@@ -168,32 +640,42 @@ type groupByValue struct {
 	Idx   uint64
 }
 
+// ExprEqual matches all rows where a column has the provided value. For columns with multiple
+// values per row, it matches all rows where the values of the column contain the provided value.
 type ExprEqual struct {
 	Column string
 	Value  string
 }
 
-func (e *ExprEqual) eval(idx *Index) (*roaring.Bitmap, error) {
-	_, ok := idx.schema.Columns[e.Column]
-	if !ok {
-		return nil, fmt.Errorf("column %q not found in schema", e.Column)
+func (e *ExprEqual) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	valueIdx := getValueIndex(e.Column, e.Value)
-
-	cacheKey := e.cacheKey()
+	col, err := idx.valueColumn(e.Column)
+	if err != nil {
+		return nil, err
+	}
 
-	bm, ok := idx.cache.Get(cacheKey)
+	bm, ok := idx.getCached(keys, e)
 	if ok {
 		return bm, nil
 	}
 
-	bm, err := idx.values.GetCol(valueIdx)
-	if err != nil || bm == nil {
-		bm = roaring.New()
+	valueIdx, ok, err := col.valueIndex(e.Column, e.Value)
+	if err != nil {
+		return nil, err
 	}
 
-	idx.cache.Put(cacheKey, bm)
+	bm = roaring.New()
+
+	if ok {
+		if vbm, err := idx.values.GetCol(valueIdx); err == nil && vbm != nil {
+			bm = vbm
+		}
+	}
+
+	idx.putCached(keys, e, bm)
 
 	return bm, nil
 }
@@ -202,30 +684,202 @@ func (e *ExprEqual) String() string {
 	return fmt.Sprintf("(EQUAL %s %q)", e.Column, e.Value)
 }
 
-func (e *ExprEqual) cacheKey() uint64 {
-	return getValueIndex(e.Column, e.Value)
+func (e *ExprEqual) canonical(keys *exprKeys) string {
+	return fmt.Sprintf("(EQUAL %q %q)", e.Column, e.Value)
+}
+
+// ExprIn matches all rows where the value of a column is one of the provided values.
+// It is equivalent to an ExprOr of ExprEqual expressions for all values, but the
+// whole set of values is cached as one entry.
+type ExprIn struct {
+	Column string
+	Values []string
+}
+
+func (e *ExprIn) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	col, err := idx.valueColumn(e.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	bm, ok := idx.getCached(keys, e)
+	if ok {
+		return bm, nil
+	}
+
+	var elems []*roaring.Bitmap
+
+	for _, v := range e.sortedValues() {
+		valueIdx, ok, err := col.valueIndex(e.Column, v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		vbm, err := idx.values.GetCol(valueIdx)
+		if err != nil || vbm == nil {
+			continue
+		}
+
+		elems = append(elems, vbm)
+	}
+
+	bm = roaring.FastOr(elems...)
+
+	idx.putCached(keys, e, bm)
+
+	return bm, nil
+}
+
+// sortedValues returns the sorted and deduplicated list of values.
+func (e *ExprIn) sortedValues() []string {
+	values := slices.Clone(e.Values)
+	sort.Strings(values)
+	return slices.Compact(values)
+}
+
+func (e *ExprIn) String() string {
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "(IN %s", e.Column)
+
+	for _, v := range e.Values {
+		fmt.Fprintf(&buf, " %q", v)
+	}
+
+	buf.WriteString(")")
+
+	return buf.String()
+}
+
+func (e *ExprIn) canonical(keys *exprKeys) string {
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "(IN %q", e.Column)
+
+	for _, v := range e.sortedValues() {
+		fmt.Fprintf(&buf, " %q", v)
+	}
+
+	buf.WriteString(")")
+
+	return buf.String()
+}
+
+// ExprPresent matches all rows that have a value in a column.
+type ExprPresent struct {
+	Column string
+}
+
+func (e *ExprPresent) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	col, err := idx.column(e.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	bm, ok := idx.getCached(keys, e)
+	if ok {
+		return bm, nil
+	}
+
+	bm, err = idx.presence(e.Column, col)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.putCached(keys, e, bm)
+
+	return bm, nil
+}
+
+func (e *ExprPresent) String() string {
+	return fmt.Sprintf("(PRESENT %s)", e.Column)
+}
+
+func (e *ExprPresent) canonical(keys *exprKeys) string {
+	return fmt.Sprintf("(PRESENT %q)", e.Column)
+}
+
+// ExprMissing matches all rows that have no value in a column.
+type ExprMissing struct {
+	Column string
+}
+
+func (e *ExprMissing) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	col, err := idx.column(e.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	bm, ok := idx.getCached(keys, e)
+	if ok {
+		return bm, nil
+	}
+
+	bm, err = idx.presence(e.Column, col)
+	if err != nil {
+		return nil, err
+	}
+
+	bm = roaring.Flip(bm, 0, uint64(idx.nextRowID))
+
+	// deleted rows are not contained in any result, so they must not be
+	// resurrected by flipping their bits.
+	bm.AndNot(idx.deleted)
+
+	idx.putCached(keys, e, bm)
+
+	return bm, nil
+}
+
+func (e *ExprMissing) String() string {
+	return fmt.Sprintf("(MISSING %s)", e.Column)
+}
+
+func (e *ExprMissing) canonical(keys *exprKeys) string {
+	return fmt.Sprintf("(MISSING %q)", e.Column)
 }
 
 type ExprNot struct {
 	Expr Expression
 }
 
-func (e *ExprNot) eval(idx *Index) (*roaring.Bitmap, error) {
-	cacheKey := e.cacheKey()
+func (e *ExprNot) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	bm, ok := idx.cache.Get(cacheKey)
+	bm, ok := idx.getCached(keys, e)
 	if ok {
 		return bm, nil
 	}
 
-	bm, err := e.Expr.eval(idx)
+	bm, err := e.Expr.eval(ctx, idx, keys)
 	if err != nil {
 		return nil, err
 	}
 
 	bm = roaring.Flip(bm, 0, uint64(idx.nextRowID))
 
-	idx.cache.Put(cacheKey, bm)
+	// deleted rows are not contained in any result, so they must not be
+	// resurrected by flipping their bits.
+	bm.AndNot(idx.deleted)
+
+	idx.putCached(keys, e, bm)
 
 	return bm, nil
 }
@@ -234,32 +888,28 @@ func (e *ExprNot) String() string {
 	return fmt.Sprintf("(NOT %s)", e.Expr.String())
 }
 
-const (
-	maskNot = 0x87A9CD14CAEB50EB
-	maskAnd = 0xF9F1F5ADCB67A077
-	maskOr  = 0xBFB85A99B03E78E7
-)
-
-func (e *ExprNot) cacheKey() uint64 {
-	return bits.RotateLeft64(e.Expr.cacheKey(), 1) ^ maskNot
+func (e *ExprNot) canonical(keys *exprKeys) string {
+	return "(NOT " + keys.canonical(e.Expr) + ")"
 }
 
 type ExprAnd struct {
 	Exprs []Expression
 }
 
-func (e *ExprAnd) eval(idx *Index) (*roaring.Bitmap, error) {
-	var elems []*roaring.Bitmap
+func (e *ExprAnd) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	cacheKey := e.cacheKey()
+	var elems []*roaring.Bitmap
 
-	bm, ok := idx.cache.Get(cacheKey)
+	bm, ok := idx.getCached(keys, e)
 	if ok {
 		return bm, nil
 	}
 
 	for _, e := range e.Exprs {
-		elem, err := e.eval(idx)
+		elem, err := e.eval(ctx, idx, keys)
 		if err != nil {
 			return nil, err
 		}
@@ -269,7 +919,7 @@ func (e *ExprAnd) eval(idx *Index) (*roaring.Bitmap, error) {
 
 	bm = roaring.FastAnd(elems...)
 
-	idx.cache.Put(cacheKey, bm)
+	idx.putCached(keys, e, bm)
 
 	return bm, nil
 }
@@ -291,31 +941,28 @@ func (e *ExprAnd) String() string {
 	return buf.String()
 }
 
-func (e *ExprAnd) cacheKey() uint64 {
-	key := uint64(maskAnd)
-	for _, e := range e.Exprs {
-		key = key ^ bits.RotateLeft64(e.cacheKey(), 1)
-	}
-
-	return key
+func (e *ExprAnd) canonical(keys *exprKeys) string {
+	return canonicalList(keys, "AND", e.Exprs)
 }
 
 type ExprOr struct {
 	Exprs []Expression
 }
 
-func (e *ExprOr) eval(idx *Index) (*roaring.Bitmap, error) {
-	var elems []*roaring.Bitmap
+func (e *ExprOr) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	cacheKey := e.cacheKey()
+	var elems []*roaring.Bitmap
 
-	bm, ok := idx.cache.Get(cacheKey)
+	bm, ok := idx.getCached(keys, e)
 	if ok {
 		return bm, nil
 	}
 
 	for _, e := range e.Exprs {
-		elem, err := e.eval(idx)
+		elem, err := e.eval(ctx, idx, keys)
 		if err != nil {
 			return nil, err
 		}
@@ -325,7 +972,7 @@ func (e *ExprOr) eval(idx *Index) (*roaring.Bitmap, error) {
 
 	bm = roaring.FastOr(elems...)
 
-	idx.cache.Put(cacheKey, bm)
+	idx.putCached(keys, e, bm)
 
 	return bm, nil
 }
@@ -347,11 +994,330 @@ func (e *ExprOr) String() string {
 	return buf.String()
 }
 
-func (e *ExprOr) cacheKey() uint64 {
-	key := uint64(maskOr)
-	for _, e := range e.Exprs {
-		key = key ^ bits.RotateLeft64(e.cacheKey(), 1)
+func (e *ExprOr) canonical(keys *exprKeys) string {
+	return canonicalList(keys, "OR", e.Exprs)
+}
+
+// canonicalList returns the canonical encoding of a commutative and idempotent operation
+// like AND or OR. The canonical encodings of the sub-expressions are sorted and deduplicated,
+// and an operation with only a single distinct sub-expression is encoded like that sub-expression.
+func canonicalList(keys *exprKeys, op string, exprs []Expression) string {
+	elems := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		elems = append(elems, keys.canonical(e))
+	}
+
+	sort.Strings(elems)
+	elems = slices.Compact(elems)
+
+	if len(elems) == 1 {
+		return elems[0]
+	}
+
+	return "(" + op + " " + strings.Join(elems, " ") + ")"
+}
+
+// ExprRange matches all rows where the value of a column is numerically within a range.
+// Values of the column that aren't numbers never match. If Lower or Upper is nil,
+// the range is unbounded on that side. For timestamp columns, the range is chronological
+// and resolved to the union of the time buckets of the column that it covers. For numeric
+// columns, the range is resolved using the bit-sliced index of the column.
+type ExprRange struct {
+	Column string
+	Lower  *RangeBound
+	Upper  *RangeBound
+}
+
+// RangeBound is a lower or upper bound of an ExprRange. The value must be a number, or a
+// timestamp for timestamp columns. Bounds are inclusive unless Exclusive is set.
+type RangeBound struct {
+	Value     string
+	Exclusive bool
+}
+
+func (e *ExprRange) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	col, err := idx.column(e.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	bm, ok := idx.getCached(keys, e)
+	if ok {
+		return bm, nil
+	}
+
+	if col.Numeric {
+		bm, err := idx.numericRange(e.Column, col, e.Lower, e.Upper)
+		if err != nil {
+			return nil, err
+		}
+
+		idx.putCached(keys, e, bm)
+
+		return bm, nil
+	}
+
+	if col.Type == ColumnTypeTimestamp {
+		bm, err := idx.timeRange(ctx, e.Column, col, e.Lower, e.Upper)
+		if err != nil {
+			return nil, err
+		}
+
+		idx.putCached(keys, e, bm)
+
+		return bm, nil
+	}
+
+	values, err := idx.numericValues(e.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := 0, len(values)
+
+	if e.Lower != nil {
+		lower, err := e.Lower.parse(e.Column)
+		if err != nil {
+			return nil, err
+		}
+
+		from = sort.Search(len(values), func(i int) bool {
+			if e.Lower.Exclusive {
+				return values[i].num > lower
+			}
+			return values[i].num >= lower
+		})
+	}
+
+	if e.Upper != nil {
+		upper, err := e.Upper.parse(e.Column)
+		if err != nil {
+			return nil, err
+		}
+
+		to = sort.Search(len(values), func(i int) bool {
+			if e.Upper.Exclusive {
+				return values[i].num >= upper
+			}
+			return values[i].num > upper
+		})
 	}
 
-	return key
+	var elems []*roaring.Bitmap
+
+	for i := from; i < to; i++ {
+		vbm, err := idx.values.GetCol(values[i].idx)
+		if err != nil {
+			return nil, err
+		}
+
+		if vbm != nil {
+			elems = append(elems, vbm)
+		}
+	}
+
+	bm = roaring.FastOr(elems...)
+
+	idx.putCached(keys, e, bm)
+
+	return bm, nil
+}
+
+func (b *RangeBound) parse(column string) (float64, error) {
+	f, err := strconv.ParseFloat(b.Value, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid range bound %q for column %q: not a number", b.Value, column)
+	}
+
+	return f, nil
+}
+
+// intBound returns the lowest integer within the bound if it is a lower bound, or the highest
+// integer within the bound if it is an upper bound. ok is false if no 64-bit integer is within
+// the bound.
+func (b *RangeBound) intBound(column string, upper bool) (n int64, ok bool, err error) {
+	n, err = strconv.ParseInt(b.Value, 10, 64)
+	if err != nil {
+		f, err := b.parse(column)
+		if err != nil {
+			return 0, false, err
+		}
+
+		switch {
+		case f >= math.MaxInt64:
+			// float64(math.MaxInt64) is 2^63, so f exceeds all 64-bit integers.
+			return math.MaxInt64, upper, nil
+		case f < math.MinInt64:
+			return math.MinInt64, !upper, nil
+		case f != math.Trunc(f):
+			// a fractional bound is never reached, so whether it is exclusive doesn't matter.
+			if upper {
+				return int64(math.Floor(f)), true, nil
+			}
+			return int64(math.Ceil(f)), true, nil
+		}
+
+		n = int64(f)
+	}
+
+	switch {
+	case !b.Exclusive:
+		return n, true, nil
+	case upper:
+		return n - 1, n > math.MinInt64, nil
+	default:
+		return n + 1, n < math.MaxInt64, nil
+	}
+}
+
+func (e *ExprRange) String() string {
+	return fmt.Sprintf("(RANGE %s %s)", e.Column, e.boundsString())
+}
+
+func (e *ExprRange) canonical(keys *exprKeys) string {
+	return fmt.Sprintf("(RANGE %q %s)", e.Column, e.boundsString())
+}
+
+func (e *ExprRange) boundsString() string {
+	lower, upper := "(-inf", "+inf)"
+
+	if e.Lower != nil {
+		if e.Lower.Exclusive {
+			lower = "(" + strconv.Quote(e.Lower.Value)
+		} else {
+			lower = "[" + strconv.Quote(e.Lower.Value)
+		}
+	}
+
+	if e.Upper != nil {
+		if e.Upper.Exclusive {
+			upper = strconv.Quote(e.Upper.Value) + ")"
+		} else {
+			upper = strconv.Quote(e.Upper.Value) + "]"
+		}
+	}
+
+	return lower + ", " + upper
+}
+
+// MatchType describes how the pattern of an ExprMatch is matched against values.
+type MatchType int
+
+const (
+	// MatchPrefix matches all values that start with the pattern.
+	MatchPrefix MatchType = iota + 1
+
+	// MatchSuffix matches all values that end with the pattern.
+	MatchSuffix
+
+	// MatchRegexp matches all values that match the pattern as regular expression.
+	// The regular expression syntax is the one of the regexp package. The regular
+	// expression is not anchored, i.e. it only needs to match a part of the value.
+	MatchRegexp
+)
+
+func (t MatchType) String() string {
+	switch t {
+	case MatchPrefix:
+		return "PREFIX"
+	case MatchSuffix:
+		return "SUFFIX"
+	case MatchRegexp:
+		return "REGEXP"
+	default:
+		return fmt.Sprintf("MatchType(%d)", int(t))
+	}
+}
+
+// ExprMatch matches all rows where the value of a column matches a pattern. The
+// pattern is matched against all known values of the column, and the expression
+// is then evaluated like an ExprOr of ExprEqual expressions for all matching values.
+// The number of values a pattern may match is limited, see WithMaxMatchValues.
+type ExprMatch struct {
+	Column  string
+	Type    MatchType
+	Pattern string
+}
+
+func (e *ExprMatch) eval(ctx context.Context, idx *Index, keys *exprKeys) (*roaring.Bitmap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	col, err := idx.valueColumn(e.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	bm, ok := idx.getCached(keys, e)
+	if ok {
+		return bm, nil
+	}
+
+	match, err := e.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var valueIdxs []uint64
+
+	for v, valueIdx := range col.Values {
+		if !match(v) {
+			continue
+		}
+
+		if len(valueIdxs) >= idx.maxMatchValues {
+			return nil, fmt.Errorf("pattern %q on column %q matches more than the maximum of %d values", e.Pattern, e.Column, idx.maxMatchValues)
+		}
+
+		valueIdxs = append(valueIdxs, valueIdx)
+	}
+
+	var elems []*roaring.Bitmap
+
+	for _, valueIdx := range valueIdxs {
+		vbm, err := idx.values.GetCol(valueIdx)
+		if err != nil {
+			return nil, err
+		}
+
+		if vbm != nil {
+			elems = append(elems, vbm)
+		}
+	}
+
+	bm = roaring.FastOr(elems...)
+
+	idx.putCached(keys, e, bm)
+
+	return bm, nil
+}
+
+func (e *ExprMatch) matcher() (func(string) bool, error) {
+	switch e.Type {
+	case MatchPrefix:
+		return func(v string) bool { return strings.HasPrefix(v, e.Pattern) }, nil
+	case MatchSuffix:
+		return func(v string) bool { return strings.HasSuffix(v, e.Pattern) }, nil
+	case MatchRegexp:
+		re, err := regexp.Compile(e.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", e.Pattern, err)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, fmt.Errorf("invalid match type %s", e.Type)
+	}
+}
+
+func (e *ExprMatch) String() string {
+	return fmt.Sprintf("(MATCH %s %s %q)", e.Type, e.Column, e.Pattern)
+}
+
+func (e *ExprMatch) canonical(keys *exprKeys) string {
+	return fmt.Sprintf("(MATCH %s %q %q)", e.Type, e.Column, e.Pattern)
 }
@@ -1,12 +1,19 @@
 package updog
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
+	"math"
 	"math/rand"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.etcd.io/bbolt"
 )
@@ -206,6 +213,12 @@ func TestQuery(t *testing.T) {
 		require.NoError(t, err)
 		runTests(t, idx)
 	})
+
+	t.Run("lrucache_verification", func(t *testing.T) {
+		idx, err := OpenIndexFromBoltDatabase(db, WithCache(NewLRUCache(100*1024*1024, WithCacheVerification())))
+		require.NoError(t, err)
+		runTests(t, idx)
+	})
 }
 
 func TestQueryGroupBy(t *testing.T) {
@@ -381,6 +394,957 @@ func TestQueryGroupBy(t *testing.T) {
 	}
 }
 
+func TestQueryOrderByLimit(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	counts := map[string]int{"a": 5, "b": 3, "c": 7, "d": 1, "e": 3}
+
+	for value, n := range counts {
+		for i := 0; i < n; i++ {
+			idxWriter.AddRow(map[string]string{"x": value, "y": fmt.Sprint(i % 2), "z": "1"})
+		}
+	}
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	groupValues := func(groups []ResultGroup) (values []string) {
+		for _, g := range groups {
+			var fields []string
+			for _, f := range g.Fields {
+				fields = append(fields, f.Value)
+			}
+			values = append(values, fmt.Sprintf("%s:%d", strings.Join(fields, ","), g.Count))
+		}
+		return values
+	}
+
+	testData := []struct {
+		name           string
+		query          *Query
+		expectedGroups []string
+	}{
+		{
+			name:           "no order, limit",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, Limit: 2},
+			expectedGroups: []string{"a:5", "b:3"},
+		},
+		{
+			name:           "count descending, limit",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, OrderBy: &OrderBy{Descending: true}, Limit: 3},
+			expectedGroups: []string{"c:7", "a:5", "b:3"},
+		},
+		{
+			name:           "count descending, limit and offset",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, OrderBy: &OrderBy{Descending: true}, Limit: 2, Offset: 2},
+			expectedGroups: []string{"b:3", "e:3"},
+		},
+		{
+			name:           "count descending, nested",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x", "y"}, OrderBy: &OrderBy{Descending: true}, Limit: 3},
+			expectedGroups: []string{"c,0:4", "a,0:3", "c,1:3"},
+		},
+		{
+			name:           "count ascending",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, OrderBy: &OrderBy{}},
+			expectedGroups: []string{"d:1", "b:3", "e:3", "a:5", "c:7"},
+		},
+		{
+			name:           "field descending",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"y", "x"}, OrderBy: &OrderBy{Column: "x", Descending: true}, Limit: 3},
+			expectedGroups: []string{"0,e:2", "1,e:1", "0,d:1"},
+		},
+		{
+			name:           "min count",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, MinCount: 3},
+			expectedGroups: []string{"a:5", "b:3", "c:7", "e:3"},
+		},
+		{
+			name:           "min count, nested",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x", "y"}, MinCount: 3},
+			expectedGroups: []string{"a,0:3", "c,0:4", "c,1:3"},
+		},
+		{
+			name:           "min count, count descending, limit",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x", "y"}, MinCount: 2, OrderBy: &OrderBy{Descending: true}, Limit: 10},
+			expectedGroups: []string{"c,0:4", "a,0:3", "c,1:3", "a,1:2", "b,0:2", "e,0:2"},
+		},
+		{
+			name:           "offset beyond end",
+			query:          &Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, Offset: 10},
+			expectedGroups: nil,
+		},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := idx.Execute(tt.query)
+			require.NoError(t, err)
+			require.Equal(t, uint64(19), result.Count)
+			require.Equal(t, tt.expectedGroups, groupValues(result.Groups))
+		})
+	}
+
+	allGroups, err := idx.Execute(&Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x", "y"}, OrderBy: &OrderBy{Descending: true}})
+	require.NoError(t, err)
+
+	for limit := 1; limit <= len(allGroups.Groups); limit++ {
+		result, err := idx.Execute(&Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x", "y"}, OrderBy: &OrderBy{Descending: true}, Limit: limit, Offset: 1})
+		require.NoError(t, err)
+		require.Equal(t, allGroups.Groups[1:min(limit+1, len(allGroups.Groups))], result.Groups, "limit %d", limit)
+	}
+
+	_, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, OrderBy: &OrderBy{Column: "y"}})
+	require.Error(t, err)
+
+	_, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "z", Value: "1"}, GroupBy: []string{"x"}, Limit: -1})
+	require.Error(t, err)
+}
+
+func TestQueryRange(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	for _, age := range []string{"9", "10", "25", "30", "35", "40", "41", "unknown", "-3.5", "NaN", "nan"} {
+		idxWriter.AddRow(map[string]string{"age": age})
+	}
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db, WithCache(NewLRUCache(1024*1024)))
+	require.NoError(t, err)
+
+	testData := []struct {
+		name           string
+		expr           *ExprRange
+		expectedResult uint64
+	}{
+		{"between 30 and 40", &ExprRange{Column: "age", Lower: &RangeBound{Value: "30"}, Upper: &RangeBound{Value: "40"}}, 3},
+		{"greater than 30", &ExprRange{Column: "age", Lower: &RangeBound{Value: "30", Exclusive: true}}, 3},
+		{"greater or equal 30", &ExprRange{Column: "age", Lower: &RangeBound{Value: "30"}}, 4},
+		{"less than 10", &ExprRange{Column: "age", Upper: &RangeBound{Value: "10", Exclusive: true}}, 2},
+		{"less or equal 10", &ExprRange{Column: "age", Upper: &RangeBound{Value: "10.0"}}, 3},
+		{"unbounded", &ExprRange{Column: "age"}, 8},
+		{"empty range", &ExprRange{Column: "age", Lower: &RangeBound{Value: "11"}, Upper: &RangeBound{Value: "20"}}, 0},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := idx.Execute(&Query{Expr: tt.expr})
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedResult, result.Count)
+		})
+	}
+
+	_, err = idx.Execute(&Query{Expr: &ExprRange{Column: "age", Lower: &RangeBound{Value: "thirty"}}})
+	require.Error(t, err)
+
+	_, err = idx.Execute(&Query{Expr: &ExprRange{Column: "age", Upper: &RangeBound{Value: "NaN"}}})
+	require.Error(t, err)
+
+	_, err = idx.Execute(&Query{Expr: &ExprRange{Column: "height", Lower: &RangeBound{Value: "1"}}})
+	require.Error(t, err)
+
+	t.Run("numeric", func(t *testing.T) {
+		idxWriter := NewIndexWriter("")
+		require.NoError(t, idxWriter.SetNumericColumn("bytes"))
+
+		for _, bytes := range []string{"-9223372036854775808", "-20", "-1", "0", "2", "3", "10", "1024", "9223372036854775807"} {
+			_, err := idxWriter.AddRow(map[string]string{"bytes": bytes})
+			require.NoError(t, err)
+		}
+		idxWriter.AddRow(map[string]string{"other": "x"})
+
+		testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+		require.NoError(t, err)
+
+		db, err := bbolt.Open("", 0644, &bbolt.Options{
+			OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+				return testFile, nil
+			},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+		idx, err := OpenIndexFromBoltDatabase(db)
+		require.NoError(t, err)
+
+		testData := []struct {
+			name           string
+			expr           *ExprRange
+			expectedResult uint64
+		}{
+			{"greater than 2", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "2", Exclusive: true}}, 4},
+			{"greater or equal 2", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "2"}}, 5},
+			{"less than 0", &ExprRange{Column: "bytes", Upper: &RangeBound{Value: "0", Exclusive: true}}, 3},
+			{"less or equal -1", &ExprRange{Column: "bytes", Upper: &RangeBound{Value: "-1"}}, 3},
+			{"between -20 and 3", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "-20"}, Upper: &RangeBound{Value: "3"}}, 5},
+			{"fractional bounds", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "-1.5"}, Upper: &RangeBound{Value: "2.5", Exclusive: true}}, 3},
+			{"exclusive float bound", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "10.0", Exclusive: true}}, 2},
+			{"unbounded", &ExprRange{Column: "bytes"}, 9},
+			{"minimum", &ExprRange{Column: "bytes", Upper: &RangeBound{Value: "-9223372036854775808"}}, 1},
+			{"maximum", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "9223372036854775807"}}, 1},
+			{"above maximum", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "9223372036854775807", Exclusive: true}}, 0},
+			{"below minimum", &ExprRange{Column: "bytes", Upper: &RangeBound{Value: "-1e30"}}, 0},
+			{"infinite bounds", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "-Inf"}, Upper: &RangeBound{Value: "+Inf"}}, 9},
+			{"empty range", &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "4"}, Upper: &RangeBound{Value: "9"}}, 0},
+		}
+
+		for _, tt := range testData {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := idx.Execute(&Query{Expr: tt.expr})
+				require.NoError(t, err)
+				require.Equal(t, tt.expectedResult, result.Count)
+			})
+		}
+
+		_, err = idx.Execute(&Query{Expr: &ExprRange{Column: "bytes", Lower: &RangeBound{Value: "two"}}})
+		require.Error(t, err)
+	})
+}
+
+func TestQueryIn(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	for _, country := range []string{"DE", "AT", "CH", "DE", "FR", "IT"} {
+		idxWriter.AddRow(map[string]string{"country": country})
+	}
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db, WithCache(NewLRUCache(1024*1024)))
+	require.NoError(t, err)
+
+	result, err := idx.Execute(&Query{Expr: &ExprIn{Column: "country", Values: []string{"DE", "AT", "CH", "XX"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), result.Count)
+
+	// same set of values in a different order, served from the cache.
+	result, err = idx.Execute(&Query{Expr: &ExprIn{Column: "country", Values: []string{"CH", "AT", "DE", "XX", "DE"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprIn{Column: "country"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), result.Count)
+
+	_, err = idx.Execute(&Query{Expr: &ExprIn{Column: "city", Values: []string{"Vienna"}}})
+	require.Error(t, err)
+}
+
+func TestQueryMatch(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	for _, url := range []string{"/api/users", "/api/orders.json", "/index.html", "/static/app.json", "/api/users"} {
+		idxWriter.AddRow(map[string]string{"url": url})
+	}
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db, WithMaxMatchValues(2))
+	require.NoError(t, err)
+
+	testData := []struct {
+		name           string
+		expr           *ExprMatch
+		expectedResult uint64
+	}{
+		{"prefix", &ExprMatch{Column: "url", Type: MatchPrefix, Pattern: "/api/"}, 3},
+		{"suffix", &ExprMatch{Column: "url", Type: MatchSuffix, Pattern: ".json"}, 2},
+		{"regexp", &ExprMatch{Column: "url", Type: MatchRegexp, Pattern: `^/[a-z]+/app`}, 1},
+		{"no match", &ExprMatch{Column: "url", Type: MatchPrefix, Pattern: "/admin/"}, 0},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := idx.Execute(&Query{Expr: tt.expr})
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedResult, result.Count)
+		})
+	}
+
+	_, err = idx.Execute(&Query{Expr: &ExprMatch{Column: "url", Type: MatchRegexp, Pattern: "/"}})
+	require.ErrorContains(t, err, "matches more than the maximum of 2 values")
+
+	_, err = idx.Execute(&Query{Expr: &ExprMatch{Column: "url", Type: MatchRegexp, Pattern: "("}})
+	require.Error(t, err)
+
+	_, err = idx.Execute(&Query{Expr: &ExprMatch{Column: "url", Pattern: "/"}})
+	require.Error(t, err)
+}
+
+func TestQueryValueKeyCollision(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	// pretend that the hash of a:x collides with the hash of b:2.
+	idxWriter.schema.Columns["a"] = &column{Values: map[string]uint64{"x": getValueIndex("b", "2")}}
+
+	idxWriter.AddRow(map[string]string{"a": "x"})
+	idxWriter.AddRow(map[string]string{"b": "2"})
+	idxWriter.AddRow(map[string]string{"b": "2"})
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	result, err := idx.Execute(&Query{Expr: &ExprEqual{Column: "a", Value: "x"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprIn{Column: "b", Values: []string{"2", "3"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+}
+
+func TestQueryGroupByReadError(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	idxWriter.AddRow(map[string]string{"a": "1", "b": "2"})
+	idxWriter.AddRow(map[string]string{"a": "1", "b": "3"})
+	idxWriter.AddRow(map[string]string{"a": "1", "b": "3"})
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		return deleteBitmap(tx.Bucket([]byte("data")), getValueIndex("b", "3"))
+	}))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	_, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "a", Value: "1"}, GroupBy: []string{"b"}})
+	require.ErrorContains(t, err, `failed to read bitmap of value "3" of column "b"`)
+
+	idx, err = OpenIndexFromBoltDatabase(db, WithReadErrorPolicy(PartialResultOnReadError))
+	require.NoError(t, err)
+
+	result, err := idx.Execute(&Query{Expr: &ExprEqual{Column: "a", Value: "1"}, GroupBy: []string{"b"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), result.Count)
+	require.Equal(t, []ResultGroup{{Fields: []ResultField{{Column: "b", Value: "2"}}, Count: 1}}, result.Groups)
+	require.Len(t, result.Warnings, 1)
+	require.Contains(t, result.Warnings[0], `failed to read bitmap of value "3" of column "b"`)
+}
+
+func TestQueryMissing(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	idxWriter.AddRow(map[string]string{"a": "1", "b": "2"})
+	idxWriter.AddRow(map[string]string{"a": "1"})
+	idxWriter.AddRow(map[string]string{"a": "2", "b": ""})
+	idxWriter.AddRowMulti(map[string][]string{"a": {"2"}, "b": {}})
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	testData := []struct {
+		Name          string
+		Expr          Expression
+		ExpectedCount uint64
+	}{
+		{"missing", &ExprMissing{Column: "b"}, 2},
+		{"present", &ExprPresent{Column: "b"}, 2},
+		{"missing and equal", &ExprAnd{Exprs: []Expression{&ExprMissing{Column: "b"}, &ExprEqual{Column: "a", Value: "1"}}}, 1},
+		{"not present", &ExprNot{Expr: &ExprPresent{Column: "a"}}, 0},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.Name, func(t *testing.T) {
+			result, err := idx.Execute(&Query{Expr: tt.Expr})
+			require.NoError(t, err)
+			require.Equal(t, tt.ExpectedCount, result.Count)
+		})
+	}
+
+	_, err = idx.Execute(&Query{Expr: &ExprMissing{Column: "c"}})
+	require.Error(t, err)
+
+	require.NoError(t, idx.DeleteRows([]uint32{1}))
+
+	result, err := idx.Execute(&Query{Expr: &ExprMissing{Column: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+
+	require.NoError(t, idx.Compact())
+	require.NoError(t, idx.Verify())
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+}
+
+func TestQueryCountDistinct(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	for i := 0; i < 1000; i++ {
+		idxWriter.AddRow(map[string]string{"country": []string{"AT", "DE"}[i%2], "user": strconv.Itoa(i % 300)})
+	}
+	idxWriter.AddRowMulti(map[string][]string{"country": {"CH"}, "user": {"1", "2"}})
+	idxWriter.AddRow(map[string]string{"country": "CH"})
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	result, err := idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, GroupBy: []string{"country"}, CountDistinct: []string{"user"}})
+	require.NoError(t, err)
+	require.Equal(t, &Result{
+		Count:    1002,
+		Distinct: []DistinctCount{{Column: "user", Count: 300}},
+		Groups: []ResultGroup{
+			{Fields: []ResultField{{Column: "country", Value: "AT"}}, Count: 500, Distinct: []DistinctCount{{Column: "user", Count: 150}}},
+			{Fields: []ResultField{{Column: "country", Value: "CH"}}, Count: 2, Distinct: []DistinctCount{{Column: "user", Count: 2}}},
+			{Fields: []ResultField{{Column: "country", Value: "DE"}}, Count: 500, Distinct: []DistinctCount{{Column: "user", Count: 150}}},
+		},
+	}, result)
+
+	result, err = idx.Execute(&Query{
+		Expr:          &ExprPresent{Column: "country"},
+		GroupBy:       []string{"country"},
+		CountDistinct: []string{"user"},
+		OrderBy:       &OrderBy{Descending: true},
+		Limit:         1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ResultGroup{
+		{Fields: []ResultField{{Column: "country", Value: "AT"}}, Count: 500, Distinct: []DistinctCount{{Column: "user", Count: 150}}},
+	}, result.Groups)
+
+	idx, err = OpenIndexFromBoltDatabase(db, WithMaxExactDistinctValues(100))
+	require.NoError(t, err)
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, GroupBy: []string{"country"}, CountDistinct: []string{"user", "country"}})
+	require.NoError(t, err)
+	require.True(t, result.Distinct[0].Approximate)
+	require.InDelta(t, 300, result.Distinct[0].Count, 10)
+	require.Equal(t, DistinctCount{Column: "country", Count: 3}, result.Distinct[1])
+	require.Len(t, result.Groups, 3)
+	require.InDelta(t, 150, result.Groups[0].Distinct[0].Count, 5)
+	require.Equal(t, uint64(2), result.Groups[1].Distinct[0].Count)
+
+	_, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, CountDistinct: []string{"doesnt_exist"}})
+	require.Error(t, err)
+
+	// only the most recently used mapping of row IDs to values fits into the limit.
+	idx, err = OpenIndexFromBoltDatabase(db, WithMaxExactDistinctValues(1), WithMaxRowValuesSize(8*1024))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, CountDistinct: []string{"user", "country"}})
+		require.NoError(t, err)
+		require.InDelta(t, 300, result.Distinct[0].Count, 10)
+		require.Equal(t, DistinctCount{Column: "country", Count: 3, Approximate: true}, result.Distinct[1])
+		require.Equal(t, []string{"country"}, idx.rowValuesOrder)
+		require.LessOrEqual(t, idx.rowValuesSize, uint64(8*1024))
+	}
+
+	idx, err = OpenIndexFromBoltDatabase(db, WithMaxExactDistinctValues(1), WithMaxRowValuesSize(0))
+	require.NoError(t, err)
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, CountDistinct: []string{"user"}})
+	require.NoError(t, err)
+	require.InDelta(t, 300, result.Distinct[0].Count, 10)
+	require.Empty(t, idx.rowValuesCols)
+}
+
+func TestQueryAggregates(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	require.NoError(t, idxWriter.SetNumericColumn("bytes"))
+	require.NoError(t, idxWriter.SetNumericColumn("big"))
+
+	rows := []map[string]string{
+		{"country": "AT", "bytes": "10"},
+		{"country": "AT", "bytes": "20", "big": strconv.FormatInt(math.MaxInt64, 10)},
+		{"country": "AT", "bytes": "-5", "big": strconv.FormatInt(math.MaxInt64, 10)},
+		{"country": "CH", "bytes": strconv.FormatInt(math.MinInt64, 10)},
+		{"country": "CH", "bytes": "7"},
+		{"country": "DE"},
+	}
+
+	for i, row := range rows {
+		rowID, err := idxWriter.AddRow(row)
+		require.NoError(t, err)
+		require.Equal(t, uint32(i), rowID)
+
+		_, err = idxWriter.AddRow(map[string]string{"country": "AT", "bytes": "1.5"})
+		require.Error(t, err)
+	}
+
+	_, err := idxWriter.AddRowMulti(map[string][]string{"bytes": {"1", "2"}})
+	require.Error(t, err)
+
+	require.Error(t, idxWriter.SetNumericColumn("country"))
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	require.NoError(t, idx.Verify())
+
+	aggregates := []Aggregate{
+		{Func: AggregateSum, Column: "bytes"},
+		{Func: AggregateAvg, Column: "bytes"},
+		{Func: AggregateMin, Column: "bytes"},
+		{Func: AggregateMax, Column: "bytes"},
+	}
+
+	values := func(sum int64, avg float64, min, max int64) []AggregateValue {
+		return []AggregateValue{
+			{Func: AggregateSum, Column: "bytes", Valid: true, Int: sum},
+			{Func: AggregateAvg, Column: "bytes", Valid: true, Float: avg},
+			{Func: AggregateMin, Column: "bytes", Valid: true, Int: min},
+			{Func: AggregateMax, Column: "bytes", Valid: true, Int: max},
+		}
+	}
+
+	result, err := idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, GroupBy: []string{"country"}, Aggregates: aggregates})
+	require.NoError(t, err)
+	require.Equal(t, &Result{
+		Count:      6,
+		Aggregates: values(math.MinInt64+32, (math.MinInt64+32)/5.0, math.MinInt64, 20),
+		Groups: []ResultGroup{
+			{Fields: []ResultField{{Column: "country", Value: "AT"}}, Count: 3, Aggregates: values(25, 25/3.0, -5, 20)},
+			{Fields: []ResultField{{Column: "country", Value: "CH"}}, Count: 2, Aggregates: values(math.MinInt64+7, (math.MinInt64+7)/2.0, math.MinInt64, 7)},
+			{Fields: []ResultField{{Column: "country", Value: "DE"}}, Count: 1, Aggregates: []AggregateValue{
+				{Func: AggregateSum, Column: "bytes"},
+				{Func: AggregateAvg, Column: "bytes"},
+				{Func: AggregateMin, Column: "bytes"},
+				{Func: AggregateMax, Column: "bytes"},
+			}},
+		},
+	}, result)
+
+	result, err = idx.Execute(&Query{Expr: &ExprMissing{Column: "big"}, Aggregates: []Aggregate{{Func: AggregateMax, Column: "big"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), result.Count)
+	require.Equal(t, []AggregateValue{{Func: AggregateMax, Column: "big"}}, result.Aggregates)
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "big"}, Aggregates: []Aggregate{{Func: AggregateMax, Column: "big"}}})
+	require.NoError(t, err)
+	require.Equal(t, []AggregateValue{{Func: AggregateMax, Column: "big", Valid: true, Int: math.MaxInt64}}, result.Aggregates)
+
+	_, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "big"}, Aggregates: []Aggregate{{Func: AggregateSum, Column: "big"}}})
+	require.ErrorContains(t, err, "overflows")
+
+	_, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, Aggregates: []Aggregate{{Func: AggregateSum, Column: "country"}}})
+	require.ErrorContains(t, err, "not numeric")
+
+	_, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "bytes", Value: "10"}})
+	require.ErrorContains(t, err, "numeric")
+
+	_, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "country"}, GroupBy: []string{"bytes"}})
+	require.ErrorContains(t, err, "numeric")
+}
+
+func TestQueryColumnTypes(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	require.NoError(t, idxWriter.SetColumnType("n", ColumnTypeInteger))
+	require.NoError(t, idxWriter.SetColumnType("f", ColumnTypeFloat))
+	require.NoError(t, idxWriter.SetColumnType("b", ColumnTypeBoolean))
+	require.NoError(t, idxWriter.SetColumnType("ts", ColumnTypeTimestamp))
+	require.NoError(t, idxWriter.SetColumnType("n", ColumnTypeInteger))
+	require.Error(t, idxWriter.SetColumnType("n", ColumnTypeFloat))
+	require.Error(t, idxWriter.SetColumnType("x", ColumnType(42)))
+
+	rows := []map[string]string{
+		{"n": "10", "f": "1.50", "b": "true", "ts": "2024-01-02T10:00:00+01:00", "s": "10"},
+		{"n": "9", "f": "1e3", "b": "F", "ts": "2024-01-02 09:00:00", "s": "9"},
+		{"n": "-3", "f": "-0.5", "b": "1", "ts": "2023-12-31", "s": "-3"},
+		{"n": "007", "s": "007"},
+	}
+
+	for _, row := range rows {
+		_, err := idxWriter.AddRow(row)
+		require.NoError(t, err)
+	}
+
+	_, err := idxWriter.AddRow(map[string]string{"n": "ten"})
+	require.ErrorContains(t, err, `invalid value "ten" of integer column "n"`)
+
+	_, err = idxWriter.AddRowMulti(map[string][]string{"ts": {"2024-01-01", "yesterday"}})
+	require.Error(t, err)
+
+	require.Error(t, idxWriter.SetColumnType("s", ColumnTypeInteger))
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	require.NoError(t, idx.Verify())
+
+	result, err := idx.Execute(&Query{Expr: &ExprEqual{Column: "n", Value: "07"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprIn{Column: "f", Values: []string{"1.5", "1000"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "ts", Value: "2024-01-02T09:00:00Z"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+
+	_, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "n", Value: "ten"}})
+	require.Error(t, err)
+
+	groupValues := func(column string) []any {
+		result, err := idx.Execute(&Query{Expr: &ExprPresent{Column: column}, GroupBy: []string{column}})
+		require.NoError(t, err)
+
+		var values []any
+		for _, g := range result.Groups {
+			require.Len(t, g.Fields, 1)
+			values = append(values, g.Fields[0].TypedValue())
+		}
+		return values
+	}
+
+	require.Equal(t, []any{int64(-3), int64(7), int64(9), int64(10)}, groupValues("n"))
+	require.Equal(t, []any{-0.5, 1.5, 1000.0}, groupValues("f"))
+	require.Equal(t, []any{false, true}, groupValues("b"))
+	require.Equal(t, []any{
+		time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+	}, groupValues("ts"))
+	require.Equal(t, []any{"-3", "007", "10", "9"}, groupValues("s"))
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "n"}, GroupBy: []string{"n"}})
+	require.NoError(t, err)
+	require.Equal(t, ResultField{Column: "n", Value: "-3", Type: ColumnTypeInteger}, result.Groups[0].Fields[0])
+
+	schema := idx.GetSchema()
+	for _, col := range schema.Columns {
+		if col.Name == "n" {
+			require.Equal(t, ColumnTypeInteger, col.Type)
+			require.Equal(t, []string{"-3", "7", "9", "10"}, []string{col.Values[0].Value, col.Values[1].Value, col.Values[2].Value, col.Values[3].Value})
+		}
+	}
+}
+
+func TestParseColumnType(t *testing.T) {
+	for _, typ := range []ColumnType{ColumnTypeString, ColumnTypeInteger, ColumnTypeFloat, ColumnTypeBoolean, ColumnTypeTimestamp} {
+		parsed, err := ParseColumnType(strings.ToUpper(typ.String()))
+		require.NoError(t, err)
+		require.Equal(t, typ, parsed)
+	}
+
+	_, err := ParseColumnType("decimal")
+	require.Error(t, err)
+}
+
+func TestQueryContextCancelled(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	idxWriter.AddRow(map[string]string{"a": "1", "b": "2"})
+	idxWriter.AddRow(map[string]string{"a": "2", "b": "3"})
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := idx.ExecuteContext(ctx, &Query{
+		Expr: &ExprOr{
+			Exprs: []Expression{
+				&ExprEqual{Column: "a", Value: "1"},
+				&ExprEqual{Column: "a", Value: "2"},
+			},
+		},
+		GroupBy: []string{"b"},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, result)
+}
+
+func TestQueryConcurrent(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	for i := 0; i < 1000; i++ {
+		idxWriter.AddRow(map[string]string{
+			"a": fmt.Sprint(i % 10),
+			"b": fmt.Sprint(i % 7),
+			"c": fmt.Sprint(i % 3),
+		})
+	}
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	// a small cache makes sure that evictions happen concurrently with lookups.
+	idx, err := OpenIndexFromBoltDatabase(db, WithCache(NewLRUCache(64*1024, WithCacheShards(4))))
+	require.NoError(t, err)
+
+	newQuery := func(i int) *Query {
+		return &Query{
+			Expr: &ExprAnd{
+				Exprs: []Expression{
+					&ExprEqual{Column: "a", Value: fmt.Sprint(i % 10)},
+					&ExprNot{
+						Expr: &ExprOr{
+							Exprs: []Expression{
+								&ExprEqual{Column: "b", Value: fmt.Sprint(i % 7)},
+								&ExprEqual{Column: "b", Value: fmt.Sprint((i + 1) % 7)},
+							},
+						},
+					},
+				},
+			},
+			GroupBy: []string{"c"},
+		}
+	}
+
+	expectedResults := make([]*Result, 70)
+	for i := range expectedResults {
+		result, err := idx.Execute(newQuery(i))
+		require.NoError(t, err)
+		expectedResults[i] = result
+	}
+
+	var wg sync.WaitGroup
+
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				j := (g*31 + i) % len(expectedResults)
+				result, err := idx.Execute(newQuery(j))
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.Equal(t, expectedResults[j], result)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestExpressionCacheKeys(t *testing.T) {
+	a := &ExprEqual{Column: "a", Value: "1"}
+	b := &ExprEqual{Column: "b", Value: "2"}
+	c := &ExprEqual{Column: "c", Value: "3"}
+
+	sameKeys := []struct {
+		name string
+		x, y Expression
+	}{
+		{"and reordered", &ExprAnd{Exprs: []Expression{a, b}}, &ExprAnd{Exprs: []Expression{b, a}}},
+		{"or reordered", &ExprOr{Exprs: []Expression{a, b, c}}, &ExprOr{Exprs: []Expression{c, a, b}}},
+		{"and deduplicated", &ExprAnd{Exprs: []Expression{a, a, b}}, &ExprAnd{Exprs: []Expression{a, b}}},
+		{"single element", &ExprOr{Exprs: []Expression{a, a}}, a},
+	}
+
+	for _, tt := range sameKeys {
+		t.Run(tt.name, func(t *testing.T) {
+			xKey, xCanonical := newExprKeys("").key(tt.x)
+			yKey, yCanonical := newExprKeys("").key(tt.y)
+			require.Equal(t, xCanonical, yCanonical)
+			require.Equal(t, xKey, yKey)
+		})
+	}
+
+	differentKeys := []struct {
+		name string
+		x, y Expression
+	}{
+		{"and with duplicate vs single", &ExprAnd{Exprs: []Expression{a, a, b}}, b},
+		{"and vs or", &ExprAnd{Exprs: []Expression{a, b}}, &ExprOr{Exprs: []Expression{a, b}}},
+		{"not vs expr", &ExprNot{Expr: a}, a},
+		{"double not", &ExprNot{Expr: &ExprNot{Expr: a}}, a},
+		{"column value split", &ExprEqual{Column: "a b", Value: "c"}, &ExprEqual{Column: "a", Value: "b c"}},
+		{"missing vs present", &ExprMissing{Column: "a"}, &ExprPresent{Column: "a"}},
+		{"nested vs flat", &ExprAnd{Exprs: []Expression{a, &ExprOr{Exprs: []Expression{b, c}}}}, &ExprOr{Exprs: []Expression{&ExprAnd{Exprs: []Expression{a, b}}, c}}},
+	}
+
+	for _, tt := range differentKeys {
+		t.Run(tt.name, func(t *testing.T) {
+			xKey, xCanonical := newExprKeys("").key(tt.x)
+			yKey, yCanonical := newExprKeys("").key(tt.y)
+			require.NotEqual(t, xCanonical, yCanonical)
+			require.NotEqual(t, xKey, yKey)
+		})
+	}
+}
+
+func TestSharedCache(t *testing.T) {
+	cache := NewLRUCache(100 * 1024 * 1024)
+
+	openIndex := func(rows []map[string]string) *Index {
+		idxWriter := NewIndexWriter("")
+
+		for _, row := range rows {
+			idxWriter.AddRow(row)
+		}
+
+		testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+		require.NoError(t, err)
+
+		db, err := bbolt.Open("", 0644, &bbolt.Options{
+			OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+				return testFile, nil
+			},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+		idx, err := OpenIndexFromBoltDatabase(db, WithCache(cache))
+		require.NoError(t, err)
+
+		return idx
+	}
+
+	idx1 := openIndex([]map[string]string{{"a": "1"}, {"a": "2"}})
+	idx2 := openIndex([]map[string]string{{"a": "1"}, {"a": "1"}, {"a": "1"}})
+
+	q := func() *Query {
+		return &Query{Expr: &ExprNot{Expr: &ExprEqual{Column: "a", Value: "2"}}}
+	}
+
+	for i := 0; i < 2; i++ {
+		result1, err := idx1.Execute(q())
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), result1.Count)
+
+		result2, err := idx2.Execute(q())
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), result2.Count)
+	}
+}
+
 const x = 7324239828
 
 func BenchmarkQuery(b *testing.B) {
@@ -0,0 +1,643 @@
+package updog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/akrennmair/updog/internal/openfile"
+	"go.etcd.io/bbolt"
+)
+
+// SegmentedIndex is an index that consists of a set of immutable segments. Each segment is
+// an index file as created by the IndexWriter, and contains a contiguous range of rows. New
+// rows are added as new segments, so that adding data never requires rewriting existing
+// segments. Small segments can be combined into bigger ones using Merge or RunMerger.
+// You have to create objects using the OpenSegmentedIndex constructor function.
+type SegmentedIndex struct {
+	mtx      sync.RWMutex
+	addMtx   sync.Mutex
+	mergeMtx sync.Mutex
+
+	dir  string
+	opts []IndexOption
+
+	segments  []*segment
+	nextRowID uint32
+}
+
+type segment struct {
+	firstRowID uint32
+	filename   string
+	idx        *Index
+}
+
+const (
+	segmentFilePrefix = "segment-"
+	segmentFileSuffix = ".updog"
+	segmentTempSuffix = ".tmp"
+)
+
+// segmentFilename returns the file name of the segment that contains the rows from
+// firstRowID up to, but not including, endRowID.
+func segmentFilename(firstRowID, endRowID uint32) string {
+	return fmt.Sprintf("%s%010d-%010d%s", segmentFilePrefix, firstRowID, endRowID, segmentFileSuffix)
+}
+
+func parseSegmentFilename(name string) (firstRowID, endRowID uint32, ok bool) {
+	if !strings.HasPrefix(name, segmentFilePrefix) || !strings.HasSuffix(name, segmentFileSuffix) {
+		return 0, 0, false
+	}
+
+	rowRange := strings.TrimSuffix(strings.TrimPrefix(name, segmentFilePrefix), segmentFileSuffix)
+
+	if _, err := fmt.Sscanf(rowRange, "%d-%d", &firstRowID, &endRowID); err != nil || endRowID <= firstRowID {
+		return 0, 0, false
+	}
+
+	return firstRowID, endRowID, true
+}
+
+// OpenSegmentedIndex opens the segmented index stored in the provided directory. If the
+// directory doesn't exist yet, it is created. The index options are applied to every segment.
+func OpenSegmentedIndex(dir string, opts ...IndexOption) (*SegmentedIndex, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index directory: %w", err)
+	}
+
+	type segmentFile struct {
+		name                 string
+		firstRowID, endRowID uint32
+	}
+
+	var files []segmentFile
+
+	for _, entry := range entries {
+		// temporary files are left over from segments that were never completely written.
+		if strings.HasPrefix(entry.Name(), segmentFilePrefix) && strings.HasSuffix(entry.Name(), segmentTempSuffix) {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, fmt.Errorf("failed to remove incomplete segment: %w", err)
+			}
+			continue
+		}
+
+		firstRowID, endRowID, ok := parseSegmentFilename(entry.Name())
+		if !ok {
+			continue
+		}
+
+		files = append(files, segmentFile{name: entry.Name(), firstRowID: firstRowID, endRowID: endRowID})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].firstRowID != files[j].firstRowID {
+			return files[i].firstRowID < files[j].firstRowID
+		}
+		return files[i].endRowID > files[j].endRowID
+	})
+
+	si := &SegmentedIndex{
+		dir:  dir,
+		opts: opts,
+	}
+
+	for _, f := range files {
+		filename := filepath.Join(dir, f.name)
+
+		// segments whose rows are already covered by a bigger segment are left over
+		// from a merge that was interrupted before the merged segments were removed.
+		if f.endRowID <= si.nextRowID {
+			if err := os.Remove(filename); err != nil {
+				si.Close()
+				return nil, fmt.Errorf("failed to remove merged segment: %w", err)
+			}
+			continue
+		}
+
+		if f.firstRowID != si.nextRowID {
+			si.Close()
+			return nil, fmt.Errorf("segment %s doesn't start at row %d", f.name, si.nextRowID)
+		}
+
+		seg, err := si.openSegment(f.firstRowID, filename)
+		if err != nil {
+			si.Close()
+			return nil, err
+		}
+
+		if seg.endRowID() != f.endRowID {
+			seg.idx.Close()
+			si.Close()
+			return nil, fmt.Errorf("segment %s contains %d rows", f.name, seg.idx.nextRowID)
+		}
+
+		si.segments = append(si.segments, seg)
+		si.nextRowID = f.endRowID
+	}
+
+	return si, nil
+}
+
+func (si *SegmentedIndex) openSegment(firstRowID uint32, filename string) (*segment, error) {
+	idx, err := OpenIndex(filename, si.opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment %s: %w", filepath.Base(filename), err)
+	}
+
+	idx.allowMissingColumns = true
+
+	return &segment{
+		firstRowID: firstRowID,
+		filename:   filename,
+		idx:        idx,
+	}, nil
+}
+
+func (seg *segment) endRowID() uint32 {
+	return seg.firstRowID + seg.idx.nextRowID
+}
+
+// Close closes all segments of the index. It waits for segments that are currently being
+// added or merged.
+func (si *SegmentedIndex) Close() error {
+	si.addMtx.Lock()
+	defer si.addMtx.Unlock()
+
+	si.mergeMtx.Lock()
+	defer si.mergeMtx.Unlock()
+
+	si.mtx.Lock()
+	defer si.mtx.Unlock()
+
+	var errs []error
+
+	for _, seg := range si.segments {
+		errs = append(errs, seg.idx.Close())
+	}
+
+	si.segments = nil
+
+	return errors.Join(errs...)
+}
+
+// AddSegment writes the rows that were added to the IndexWriter as a new segment and
+// adds it to the index. The rows are assigned the row IDs following the existing rows,
+// in the order of their row IDs in the IndexWriter. The file name of the IndexWriter is
+// ignored. Columns that already exist in other segments must have the same type and be
+// numeric in the same way.
+func (si *SegmentedIndex) AddSegment(w *IndexWriter) error {
+	si.addMtx.Lock()
+	defer si.addMtx.Unlock()
+
+	w.mtx.RLock()
+	numRows := w.nextRowID
+	w.mtx.RUnlock()
+
+	if numRows == 0 {
+		return nil
+	}
+
+	if numRows > math.MaxUint32-si.nextRowID {
+		return errors.New("too many rows")
+	}
+
+	if err := si.checkColumnTypes(w); err != nil {
+		return err
+	}
+
+	seg, err := si.writeSegment(si.nextRowID, w, nil)
+	if err != nil {
+		return err
+	}
+
+	si.mtx.Lock()
+	defer si.mtx.Unlock()
+
+	si.segments = append(si.segments, seg)
+	si.nextRowID = seg.endRowID()
+
+	return nil
+}
+
+// checkColumnTypes returns an error if a column of the IndexWriter has a different type than
+// the same column in one of the segments. Segments with different types for the same column
+// couldn't be merged, and their values couldn't be grouped together.
+func (si *SegmentedIndex) checkColumnTypes(w *IndexWriter) error {
+	si.mtx.RLock()
+	segments := si.segments
+	si.mtx.RUnlock()
+
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	for _, seg := range segments {
+		seg.idx.mtx.RLock()
+		err := checkSchemaTypes(w.schema, seg.idx.schema)
+		seg.idx.mtx.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSchemaTypes returns an error if a column in both schemas has a different type, or is
+// numeric in only one of them.
+func checkSchemaTypes(sch, other *schema) error {
+	for colName, col := range sch.Columns {
+		otherCol, ok := other.Columns[colName]
+		if !ok {
+			continue
+		}
+
+		if col.Type != otherCol.Type {
+			return fmt.Errorf("column %q has type %s, but type %s in an existing segment", colName, col.Type, otherCol.Type)
+		}
+
+		if col.Numeric != otherCol.Numeric {
+			return fmt.Errorf("column %q is numeric in only one of the new and an existing segment", colName)
+		}
+	}
+
+	return nil
+}
+
+// writeSegment writes the index data of the IndexWriter and the deleted rows, if any,
+// to a new segment file starting at firstRowID, and opens it. The segment file only
+// gets its final file name once it has been written completely.
+func (si *SegmentedIndex) writeSegment(firstRowID uint32, w *IndexWriter, deleted *roaring.Bitmap) (*segment, error) {
+	filename := filepath.Join(si.dir, segmentFilename(firstRowID, firstRowID+w.nextRowID))
+	tempFilename := filename + segmentTempSuffix
+
+	db, err := bbolt.Open(tempFilename, 0644, &bbolt.Options{OpenFile: openfile.OpenFile(openfile.Options{FailIfFileExists: true})})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+
+	err = w.WriteToBoltDatabase(db)
+	if err == nil && deleted != nil && !deleted.IsEmpty() {
+		err = db.Update(func(tx *bbolt.Tx) error {
+			buf, err := deleted.ToBytes()
+			if err != nil {
+				return err
+			}
+			return tx.Bucket([]byte("data")).Put(keyDeletedRows, buf)
+		})
+	}
+
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tempFilename, filename)
+	}
+
+	if err != nil {
+		os.Remove(tempFilename)
+		return nil, fmt.Errorf("failed to write segment: %w", err)
+	}
+
+	return si.openSegment(firstRowID, filename)
+}
+
+// Merge combines runs of adjacent segments into single segments, as long as the combined
+// segments contain no more than maxRows rows. Deleted rows are removed from the index data
+// of the merged segments. Queries can be executed while segments are being merged.
+func (si *SegmentedIndex) Merge(ctx context.Context, maxRows uint32) error {
+	si.mergeMtx.Lock()
+	defer si.mergeMtx.Unlock()
+
+	si.mtx.RLock()
+	segments := slices.Clone(si.segments)
+	si.mtx.RUnlock()
+
+	for _, run := range mergeRuns(segments, maxRows) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		merged, err := si.mergeSegments(run)
+		if err != nil {
+			return err
+		}
+
+		si.mtx.Lock()
+		i := slices.Index(si.segments, run[0])
+		si.segments = slices.Replace(si.segments, i, i+len(run), merged)
+		si.mtx.Unlock()
+
+		for _, seg := range run {
+			if err := seg.idx.Close(); err != nil {
+				return err
+			}
+
+			if err := os.Remove(seg.filename); err != nil {
+				return fmt.Errorf("failed to remove merged segment: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunMerger calls Merge in the provided interval until the context is cancelled, and is
+// meant to be run in its own goroutine. It returns nil when the context is cancelled, or
+// the first error returned by Merge.
+func (si *SegmentedIndex) RunMerger(ctx context.Context, interval time.Duration, maxRows uint32) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := si.Merge(ctx, maxRows); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// mergeRuns returns all runs of at least two adjacent segments that contain no more than
+// maxRows rows in total.
+func mergeRuns(segments []*segment, maxRows uint32) (runs [][]*segment) {
+	var (
+		run     []*segment
+		runRows uint64
+	)
+
+	for _, seg := range segments {
+		rows := uint64(seg.idx.nextRowID)
+
+		if runRows+rows > uint64(maxRows) {
+			if len(run) > 1 {
+				runs = append(runs, run)
+			}
+			run, runRows = nil, 0
+		}
+
+		run = append(run, seg)
+		runRows += rows
+	}
+
+	if len(run) > 1 {
+		runs = append(runs, run)
+	}
+
+	return runs
+}
+
+func (si *SegmentedIndex) mergeSegments(run []*segment) (*segment, error) {
+	firstRowID := run[0].firstRowID
+
+	w := NewIndexWriter("")
+	deleted := roaring.New()
+
+	for _, seg := range run {
+		offset := seg.firstRowID - firstRowID
+
+		if err := seg.mergeInto(w, deleted, offset); err != nil {
+			return nil, err
+		}
+
+		w.nextRowID += seg.idx.nextRowID
+	}
+
+	return si.writeSegment(firstRowID, w, deleted)
+}
+
+// mergeInto adds the index data of the segment to the IndexWriter, with all row IDs moved
+// by offset. Deleted rows are left out of the index data, and only added to deleted.
+func (seg *segment) mergeInto(w *IndexWriter, deleted *roaring.Bitmap, offset uint32) error {
+	seg.idx.mtx.RLock()
+	defer seg.idx.mtx.RUnlock()
+
+	for colName, col := range seg.idx.schema.Columns {
+		if col.Numeric {
+			if err := seg.mergeNumericInto(w, colName, col, offset); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := w.schema.setType(colName, col.Type); err != nil {
+			return err
+		}
+
+		for v, valueIdx := range col.Values {
+			bm, err := seg.idx.values.GetCol(valueIdx)
+			if err != nil {
+				return fmt.Errorf("failed to read value %q of column %q: %w", v, colName, err)
+			}
+
+			if bm == nil {
+				continue
+			}
+
+			bm = roaring.AndNot(bm, seg.idx.deleted)
+			if bm.IsEmpty() {
+				continue
+			}
+
+			bm = roaring.AddOffset(bm, offset)
+
+			w.getValueBitmap(w.schema.add(colName, v)).Or(bm)
+			w.getValueBitmap(w.schema.addPresence(colName)).Or(bm)
+		}
+	}
+
+	deleted.Or(roaring.AddOffset(seg.idx.deleted, offset))
+
+	return nil
+}
+
+// mergeNumericInto adds the bitmaps of the bits and the presence bitmap of a numeric column
+// of the segment to the IndexWriter, with all row IDs moved by offset and deleted rows removed.
+func (seg *segment) mergeNumericInto(w *IndexWriter, colName string, col *column, offset uint32) error {
+	if err := w.schema.setNumeric(colName); err != nil {
+		return err
+	}
+
+	presence, err := seg.idx.presence(colName, col)
+	if err != nil {
+		return err
+	}
+
+	presence = roaring.AndNot(presence, seg.idx.deleted)
+	if presence.IsEmpty() {
+		return nil
+	}
+
+	w.getValueBitmap(w.schema.addPresence(colName)).Or(roaring.AddOffset(presence, offset))
+
+	for i, bitIdx := range col.Bits {
+		if bitIdx == 0 {
+			continue
+		}
+
+		bm, err := seg.idx.values.GetCol(bitIdx)
+		if err != nil {
+			return fmt.Errorf("failed to read bit %d of column %q: %w", i, colName, err)
+		}
+
+		if bm == nil {
+			continue
+		}
+
+		bm = roaring.AndNot(bm, seg.idx.deleted)
+		if bm.IsEmpty() {
+			continue
+		}
+
+		w.getValueBitmap(w.schema.addBit(colName, i)).Or(roaring.AddOffset(bm, offset))
+	}
+
+	return nil
+}
+
+// Execute runs the provided query on all segments of the index and returns the combined
+// query result.
+func (si *SegmentedIndex) Execute(q *Query) (*Result, error) {
+	return si.ExecuteContext(context.Background(), q)
+}
+
+// exprColumns returns the names of all columns that are used in the expression.
+func exprColumns(e Expression) []string {
+	switch e := e.(type) {
+	case *ExprEqual:
+		return []string{e.Column}
+	case *ExprIn:
+		return []string{e.Column}
+	case *ExprPresent:
+		return []string{e.Column}
+	case *ExprMissing:
+		return []string{e.Column}
+	case *ExprRange:
+		return []string{e.Column}
+	case *ExprMatch:
+		return []string{e.Column}
+	case *ExprNot:
+		return exprColumns(e.Expr)
+	case *ExprAnd:
+		var columns []string
+		for _, ee := range e.Exprs {
+			columns = append(columns, exprColumns(ee)...)
+		}
+		return columns
+	case *ExprOr:
+		var columns []string
+		for _, ee := range e.Exprs {
+			columns = append(columns, exprColumns(ee)...)
+		}
+		return columns
+	default:
+		return nil
+	}
+}
+
+// ExecuteContext runs the provided query on all segments of the index and returns the
+// combined query result. The counts of groups with the same values are summed up before
+// MinCount, OrderBy, Limit and Offset are applied. Distinct counts are determined from
+// the distinct values of all segments, so values that occur in multiple segments are
+// only counted once, and aggregates are computed over the values of all segments.
+func (si *SegmentedIndex) ExecuteContext(ctx context.Context, q *Query) (*Result, error) {
+	if err := q.checkOrder(); err != nil {
+		return nil, err
+	}
+
+	si.mtx.RLock()
+	defer si.mtx.RUnlock()
+
+	columns := slices.Concat(exprColumns(q.Expr), q.GroupBy, q.CountDistinct)
+	for _, agg := range q.Aggregates {
+		columns = append(columns, agg.Column)
+	}
+
+	for _, colName := range columns {
+		if !slices.ContainsFunc(si.segments, func(seg *segment) bool {
+			_, ok := seg.idx.schema.Columns[colName]
+			return ok
+		}) {
+			return nil, fmt.Errorf("column %q not found", colName)
+		}
+	}
+
+	var (
+		result     = &Result{}
+		groupIndex = map[string]int{}
+	)
+
+	for range q.CountDistinct {
+		result.distinct = append(result.distinct, &distinctSet{values: map[string]struct{}{}})
+	}
+
+	for range q.Aggregates {
+		result.aggregates = append(result.aggregates, newAggregateState())
+	}
+
+	for _, seg := range si.segments {
+		segResult, err := seg.idx.executeContext(ctx, &Query{Expr: q.Expr, GroupBy: q.GroupBy, CountDistinct: q.CountDistinct, Aggregates: q.Aggregates})
+		if err != nil {
+			return nil, err
+		}
+
+		result.Count += segResult.Count
+		result.Warnings = append(result.Warnings, segResult.Warnings...)
+		mergeDistinctSets(result.distinct, segResult.distinct)
+		mergeAggregateStates(result.aggregates, segResult.aggregates)
+
+		for _, g := range segResult.Groups {
+			var key strings.Builder
+			for _, f := range g.Fields {
+				fmt.Fprintf(&key, "%d:%s", len(f.Value), f.Value)
+			}
+
+			if i, ok := groupIndex[key.String()]; ok {
+				result.Groups[i].Count += g.Count
+				mergeDistinctSets(result.Groups[i].distinct, g.distinct)
+				mergeAggregateStates(result.Groups[i].aggregates, g.aggregates)
+				continue
+			}
+
+			groupIndex[key.String()] = len(result.Groups)
+			result.Groups = append(result.Groups, g)
+		}
+	}
+
+	result.Groups = slices.DeleteFunc(result.Groups, func(g ResultGroup) bool {
+		return g.Count < q.MinCount
+	})
+
+	// groups that are ordered according to the query's OrderBy are sorted by orderGroups.
+	if q.OrderBy == nil {
+		sortGroups(result.Groups, compareGroupFields)
+	}
+
+	result.Groups = q.orderGroups(result.Groups)
+
+	if err := result.finish(q); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,239 @@
+package updog_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akrennmair/updog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentedIndex(t *testing.T) {
+	dir := t.TempDir()
+
+	si, err := updog.OpenSegmentedIndex(dir)
+	require.NoError(t, err)
+
+	segmentRows := [][]map[string]string{
+		{
+			{"a": "1", "b": "x", "n": "5"},
+			{"a": "2", "b": "x", "n": "-3"},
+		},
+		{
+			{"a": "1", "b": "y"},
+		},
+		{
+			{"a": "1", "b": "x", "c": "only_here", "n": "10"},
+			{"a": "3", "b": "z", "c": "only_here"},
+		},
+	}
+
+	for i, rows := range segmentRows {
+		w := updog.NewIndexWriter("")
+		if i != 1 {
+			require.NoError(t, w.SetNumericColumn("n"))
+		}
+		for _, row := range rows {
+			_, err := w.AddRow(row)
+			require.NoError(t, err)
+		}
+		require.NoError(t, si.AddSegment(w))
+	}
+
+	require.NoError(t, si.AddSegment(updog.NewIndexWriter("")))
+
+	expectedResults := map[*updog.Query]*updog.Result{
+		{Expr: &updog.ExprEqual{Column: "a", Value: "1"}, GroupBy: []string{"b"}}: {
+			Count: 3,
+			Groups: []updog.ResultGroup{
+				{Fields: []updog.ResultField{{Column: "b", Value: "x"}}, Count: 2},
+				{Fields: []updog.ResultField{{Column: "b", Value: "y"}}, Count: 1},
+			},
+		},
+		{Expr: &updog.ExprNot{Expr: &updog.ExprEqual{Column: "c", Value: "only_here"}}, GroupBy: []string{"a"}, MinCount: 2}: {
+			Count: 3,
+			Groups: []updog.ResultGroup{
+				{Fields: []updog.ResultField{{Column: "a", Value: "1"}}, Count: 2},
+			},
+		},
+		{Expr: &updog.ExprIn{Column: "a", Values: []string{"1", "2", "3"}}, GroupBy: []string{"b"}, OrderBy: &updog.OrderBy{Descending: true}, Limit: 1}: {
+			Count: 5,
+			Groups: []updog.ResultGroup{
+				{Fields: []updog.ResultField{{Column: "b", Value: "x"}}, Count: 3},
+			},
+		},
+		{Expr: &updog.ExprIn{Column: "a", Values: []string{"1", "2", "3"}}, GroupBy: []string{"b"}, CountDistinct: []string{"a"}}: {
+			Count:    5,
+			Distinct: []updog.DistinctCount{{Column: "a", Count: 3}},
+			Groups: []updog.ResultGroup{
+				{Fields: []updog.ResultField{{Column: "b", Value: "x"}}, Count: 3, Distinct: []updog.DistinctCount{{Column: "a", Count: 2}}},
+				{Fields: []updog.ResultField{{Column: "b", Value: "y"}}, Count: 1, Distinct: []updog.DistinctCount{{Column: "a", Count: 1}}},
+				{Fields: []updog.ResultField{{Column: "b", Value: "z"}}, Count: 1, Distinct: []updog.DistinctCount{{Column: "a", Count: 1}}},
+			},
+		},
+		{Expr: &updog.ExprIn{Column: "a", Values: []string{"1", "2", "3"}}, GroupBy: []string{"b"}, Aggregates: []updog.Aggregate{{Func: updog.AggregateSum, Column: "n"}, {Func: updog.AggregateAvg, Column: "n"}, {Func: updog.AggregateMin, Column: "n"}}}: {
+			Count: 5,
+			Aggregates: []updog.AggregateValue{
+				{Func: updog.AggregateSum, Column: "n", Valid: true, Int: 12},
+				{Func: updog.AggregateAvg, Column: "n", Valid: true, Float: 4},
+				{Func: updog.AggregateMin, Column: "n", Valid: true, Int: -3},
+			},
+			Groups: []updog.ResultGroup{
+				{Fields: []updog.ResultField{{Column: "b", Value: "x"}}, Count: 3, Aggregates: []updog.AggregateValue{
+					{Func: updog.AggregateSum, Column: "n", Valid: true, Int: 12},
+					{Func: updog.AggregateAvg, Column: "n", Valid: true, Float: 4},
+					{Func: updog.AggregateMin, Column: "n", Valid: true, Int: -3},
+				}},
+				{Fields: []updog.ResultField{{Column: "b", Value: "y"}}, Count: 1, Aggregates: []updog.AggregateValue{
+					{Func: updog.AggregateSum, Column: "n"},
+					{Func: updog.AggregateAvg, Column: "n"},
+					{Func: updog.AggregateMin, Column: "n"},
+				}},
+				{Fields: []updog.ResultField{{Column: "b", Value: "z"}}, Count: 1, Aggregates: []updog.AggregateValue{
+					{Func: updog.AggregateSum, Column: "n"},
+					{Func: updog.AggregateAvg, Column: "n"},
+					{Func: updog.AggregateMin, Column: "n"},
+				}},
+			},
+		},
+	}
+
+	checkResults := func() {
+		for q, expected := range expectedResults {
+			result, err := si.Execute(q)
+			require.NoError(t, err)
+			require.Equal(t, expected, result, "query %s", q.Expr)
+		}
+
+		_, err := si.Execute(&updog.Query{Expr: &updog.ExprEqual{Column: "a", Value: "1"}, GroupBy: []string{"doesnt_exist"}})
+		require.Error(t, err)
+
+		for _, expr := range []updog.Expression{
+			&updog.ExprEqual{Column: "doesnt_exist", Value: "1"},
+			&updog.ExprNot{Expr: &updog.ExprPresent{Column: "doesnt_exist"}},
+			&updog.ExprOr{Exprs: []updog.Expression{&updog.ExprEqual{Column: "a", Value: "1"}, &updog.ExprMissing{Column: "doesnt_exist"}}},
+		} {
+			_, err = si.Execute(&updog.Query{Expr: expr})
+			require.EqualError(t, err, `column "doesnt_exist" not found`, "query %s", expr)
+		}
+	}
+
+	checkResults()
+
+	require.NoError(t, si.Merge(context.Background(), 3))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	checkResults()
+
+	require.NoError(t, si.Close())
+
+	si, err = updog.OpenSegmentedIndex(dir)
+	require.NoError(t, err)
+
+	checkResults()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mergerDone := make(chan error)
+
+	go func() {
+		mergerDone <- si.RunMerger(ctx, time.Millisecond, 100)
+	}()
+
+	require.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-mergerDone)
+
+	checkResults()
+
+	require.NoError(t, si.Close())
+}
+
+func TestSegmentedIndexInterruptedMerge(t *testing.T) {
+	dir := t.TempDir()
+
+	si, err := updog.OpenSegmentedIndex(dir)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		w := updog.NewIndexWriter("")
+		_, err := w.AddRow(map[string]string{"a": fmt.Sprint(i)})
+		require.NoError(t, err)
+		require.NoError(t, si.AddSegment(w))
+	}
+
+	require.NoError(t, si.Close())
+
+	// simulate a merge of the first two segments that was interrupted before the
+	// merged segments were removed.
+	w := updog.NewIndexWriter(filepath.Join(dir, "segment-0000000000-0000000002.updog"))
+	_, err = w.AddRow(map[string]string{"a": "0"})
+	require.NoError(t, err)
+	_, err = w.AddRow(map[string]string{"a": "1"})
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "segment-0000000003-0000000004.updog.tmp"), []byte("incomplete"), 0644))
+
+	si, err = updog.OpenSegmentedIndex(dir)
+	require.NoError(t, err)
+	defer si.Close()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	result, err := si.Execute(&updog.Query{Expr: &updog.ExprNot{Expr: &updog.ExprEqual{Column: "a", Value: "1"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+}
+
+func TestSegmentedIndexColumnTypeMismatch(t *testing.T) {
+	dir := t.TempDir()
+
+	si, err := updog.OpenSegmentedIndex(dir)
+	require.NoError(t, err)
+	defer si.Close()
+
+	w := updog.NewIndexWriter("")
+	require.NoError(t, w.SetColumnType("n", updog.ColumnTypeInteger))
+	require.NoError(t, w.SetNumericColumn("m"))
+	_, err = w.AddRow(map[string]string{"n": "07", "m": "1"})
+	require.NoError(t, err)
+	require.NoError(t, si.AddSegment(w))
+
+	for _, typeColumns := range []func(w *updog.IndexWriter) error{
+		func(w *updog.IndexWriter) error { return nil },
+		func(w *updog.IndexWriter) error { return w.SetColumnType("n", updog.ColumnTypeFloat) },
+		func(w *updog.IndexWriter) error { return w.SetColumnType("m", updog.ColumnTypeInteger) },
+	} {
+		w := updog.NewIndexWriter("")
+		require.NoError(t, typeColumns(w))
+		_, err := w.AddRow(map[string]string{"n": "7", "m": "1"})
+		require.NoError(t, err)
+		require.Error(t, si.AddSegment(w))
+	}
+
+	w = updog.NewIndexWriter("")
+	require.NoError(t, w.SetColumnType("n", updog.ColumnTypeInteger))
+	require.NoError(t, w.SetNumericColumn("m"))
+	_, err = w.AddRow(map[string]string{"n": "7", "m": "2"})
+	require.NoError(t, err)
+	require.NoError(t, si.AddSegment(w))
+
+	require.NoError(t, si.Merge(context.Background(), 10))
+
+	result, err := si.Execute(&updog.Query{Expr: &updog.ExprPresent{Column: "m"}, GroupBy: []string{"n"}})
+	require.NoError(t, err)
+	require.Equal(t, []updog.ResultGroup{{Fields: []updog.ResultField{{Column: "n", Value: "7", Type: updog.ColumnTypeInteger}}, Count: 2}}, result.Groups)
+}
@@ -0,0 +1,423 @@
+package updog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RoaringBitmap/roaring"
+	"go.etcd.io/bbolt"
+)
+
+// Granularity is the size of the time buckets that the values of timestamp columns are
+// truncated to. For each timestamp column, the writers store a time bucket column per
+// granularity, which contains the truncated values of the column, so that rows can be
+// grouped by e.g. the day of their timestamp, and time ranges can be resolved to the
+// union of a few time buckets instead of all values in the range.
+type Granularity int
+
+const (
+	// GranularityMinute truncates timestamps to the start of their minute.
+	GranularityMinute Granularity = iota + 1
+
+	// GranularityHour truncates timestamps to the start of their hour.
+	GranularityHour
+
+	// GranularityDay truncates timestamps to the start of their day in UTC.
+	GranularityDay
+
+	// GranularityMonth truncates timestamps to the start of their month in UTC.
+	GranularityMonth
+)
+
+// granularities contains all granularities from the coarsest to the finest.
+var granularities = []Granularity{GranularityMonth, GranularityDay, GranularityHour, GranularityMinute}
+
+func (g Granularity) String() string {
+	switch g {
+	case GranularityMinute:
+		return "minute"
+	case GranularityHour:
+		return "hour"
+	case GranularityDay:
+		return "day"
+	case GranularityMonth:
+		return "month"
+	default:
+		return fmt.Sprintf("Granularity(%d)", int(g))
+	}
+}
+
+// ParseGranularity returns the granularity with the provided name, as returned by Granularity.String.
+func ParseGranularity(name string) (Granularity, error) {
+	for _, g := range granularities {
+		if strings.EqualFold(name, g.String()) {
+			return g, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown granularity %q", name)
+}
+
+// truncate returns the start of the time bucket that contains t.
+func (g Granularity) truncate(t time.Time) time.Time {
+	switch g {
+	case GranularityMinute:
+		return t.Truncate(time.Minute)
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	case GranularityDay:
+		return t.Truncate(24 * time.Hour)
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// next returns the start of the time bucket that follows the time bucket starting at t.
+func (g Granularity) next(t time.Time) time.Time {
+	switch g {
+	case GranularityMinute:
+		return t.Add(time.Minute)
+	case GranularityHour:
+		return t.Add(time.Hour)
+	case GranularityDay:
+		return t.Add(24 * time.Hour)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// ceil returns the start of the first time bucket that starts at or after t.
+func (g Granularity) ceil(t time.Time) time.Time {
+	start := g.truncate(t)
+	if start.Equal(t) {
+		return start
+	}
+
+	return g.next(start)
+}
+
+// DateTrunc returns the name of the time bucket column of a timestamp column with the provided
+// granularity, e.g. date_trunc('day', ts). Time bucket columns are virtual columns that can be
+// used in expressions and to group by like any other column, but rows can't have values in them.
+func DateTrunc(g Granularity, column string) string {
+	return fmt.Sprintf("date_trunc('%s', %s)", g, column)
+}
+
+var dateTruncRegexp = regexp.MustCompile(`^date_trunc\('([a-z]+)', (.+)\)$`)
+
+// parseDateTrunc returns the granularity and the timestamp column of a time bucket column name.
+func parseDateTrunc(name string) (Granularity, string, bool) {
+	m := dateTruncRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return 0, "", false
+	}
+
+	g, err := ParseGranularity(m[1])
+	if err != nil {
+		return 0, "", false
+	}
+
+	return g, m[2], true
+}
+
+// timeBucket is a value of a time bucket column.
+type timeBucket struct {
+	column string
+	value  string
+}
+
+// timeBuckets returns the values of the time bucket columns that a row with the canonical
+// value v in column k has. It returns nil if k isn't a timestamp column.
+func (sch *schema) timeBuckets(k, v string) []timeBucket {
+	col, ok := sch.Columns[k]
+	if !ok || col.Type != ColumnTypeTimestamp {
+		return nil
+	}
+
+	if _, _, ok := parseDateTrunc(k); ok {
+		return nil
+	}
+
+	t, err := parseTimestamp(v)
+	if err != nil {
+		return nil
+	}
+
+	buckets := make([]timeBucket, 0, len(granularities))
+
+	for _, g := range granularities {
+		buckets = append(buckets, timeBucket{column: DateTrunc(g, k), value: g.truncate(t).Format(time.RFC3339Nano)})
+	}
+
+	return buckets
+}
+
+// addTimeBuckets adds the time bucket columns of a timestamp column to the schema if they
+// don't exist yet.
+func (sch *schema) addTimeBuckets(k string) {
+	if col, ok := sch.Columns[k]; !ok || col.Type != ColumnTypeTimestamp {
+		return
+	}
+
+	if _, _, ok := parseDateTrunc(k); ok {
+		return
+	}
+
+	for _, g := range granularities {
+		name := DateTrunc(g, k)
+		if _, ok := sch.Columns[name]; !ok {
+			sch.Columns[name] = &column{
+				Values: make(map[string]uint64),
+				Type:   ColumnTypeTimestamp,
+			}
+		}
+	}
+}
+
+// addMissingTimeBuckets adds the time bucket columns of all timestamp columns that don't have
+// them yet, including the values of the existing rows, and returns the names of these timestamp
+// columns.
+func (sch *schema) addMissingTimeBuckets() []string {
+	var names []string
+
+	for _, name := range sch.columnNames() {
+		col := sch.Columns[name]
+
+		if col.Type != ColumnTypeTimestamp {
+			continue
+		}
+
+		if _, _, ok := parseDateTrunc(name); ok {
+			continue
+		}
+
+		if _, ok := sch.Columns[DateTrunc(GranularityMinute, name)]; ok {
+			continue
+		}
+
+		sch.addTimeBuckets(name)
+
+		for v := range col.Values {
+			for _, b := range sch.timeBuckets(name, v) {
+				sch.add(b.column, b.value)
+				sch.addPresence(b.column)
+			}
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// rebuildTimeBucketBitmaps writes the bitmaps of the time bucket columns of the provided
+// timestamp columns as union of the stored bitmaps of all values in each time bucket. It is
+// used when appending to index files that were written before time buckets were introduced,
+// and must be called after all bitmaps of values and presence bitmaps were written.
+func rebuildTimeBucketBitmaps(bucket *bbolt.Bucket, sch *schema, columns []string) error {
+	for _, colName := range columns {
+		col := sch.Columns[colName]
+
+		bitmaps := map[uint64]*roaring.Bitmap{}
+
+		for v, valueIdx := range col.Values {
+			data := bucket.Get(valueKey(keyPrefixValue, valueIdx))
+			if data == nil {
+				continue
+			}
+
+			bm := roaring.New()
+			if err := bm.UnmarshalBinary(data); err != nil {
+				return fmt.Errorf("failed to decode bitmap of value %q of column %q: %w", v, colName, err)
+			}
+
+			for _, b := range sch.timeBuckets(colName, v) {
+				key := sch.Columns[b.column].Values[b.value]
+				if bitmaps[key] == nil {
+					bitmaps[key] = roaring.New()
+				}
+				bitmaps[key].Or(bm)
+			}
+		}
+
+		if data := bucket.Get(valueKey(keyPrefixValue, col.Presence)); data != nil {
+			presence := roaring.New()
+			if err := presence.UnmarshalBinary(data); err != nil {
+				return fmt.Errorf("failed to decode presence bitmap of column %q: %w", colName, err)
+			}
+
+			for _, g := range granularities {
+				bitmaps[sch.Columns[DateTrunc(g, colName)].Presence] = presence
+			}
+		}
+
+		for key, bm := range bitmaps {
+			bm, err := mergeStoredBitmap(bucket, key, bm)
+			if err != nil {
+				return err
+			}
+
+			bm.RunOptimize()
+
+			if err := putBitmap(bucket, key, bm); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// timeValue is a value of a timestamp column and its value index key.
+type timeValue struct {
+	t   time.Time
+	idx uint64
+}
+
+// timeValues returns all values of a timestamp column in chronological order. The result is
+// computed on first use and then kept for later use.
+func (idx *Index) timeValues(colName string, col *column) []timeValue {
+	idx.numericMtx.Lock()
+	defer idx.numericMtx.Unlock()
+
+	if values, ok := idx.timeCols[colName]; ok {
+		return values
+	}
+
+	values := make([]timeValue, 0, len(col.Values))
+
+	for v, valueIdx := range col.Values {
+		t, err := parseTimestamp(v)
+		if err != nil {
+			continue
+		}
+
+		values = append(values, timeValue{t: t, idx: valueIdx})
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].t.Before(values[j].t) })
+
+	if idx.timeCols == nil {
+		idx.timeCols = make(map[string][]timeValue)
+	}
+
+	idx.timeCols[colName] = values
+
+	return values
+}
+
+// timeRange returns all rows whose value of a timestamp column is within the range.
+func (idx *Index) timeRange(ctx context.Context, colName string, col *column, lower, upper *RangeBound) (*roaring.Bitmap, error) {
+	values := idx.timeValues(colName, col)
+	if len(values) == 0 {
+		return roaring.New(), nil
+	}
+
+	// the range is turned into the half-open interval [from, to).
+	from, to := values[0].t, values[len(values)-1].t.Add(time.Nanosecond)
+
+	if lower != nil {
+		t, err := lower.parseTime(colName)
+		if err != nil {
+			return nil, err
+		}
+
+		if lower.Exclusive {
+			t = t.Add(time.Nanosecond)
+		}
+
+		if t.After(from) {
+			from = t
+		}
+	}
+
+	if upper != nil {
+		t, err := upper.parseTime(colName)
+		if err != nil {
+			return nil, err
+		}
+
+		if !upper.Exclusive {
+			t = t.Add(time.Nanosecond)
+		}
+
+		if t.Before(to) {
+			to = t
+		}
+	}
+
+	if !from.Before(to) {
+		return roaring.New(), nil
+	}
+
+	var elems []*roaring.Bitmap
+
+	for _, valueIdx := range idx.timeRangeKeys(colName, values, from, to, 0) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		vbm, err := idx.values.GetCol(valueIdx)
+		if err != nil {
+			return nil, err
+		}
+
+		if vbm != nil {
+			elems = append(elems, vbm)
+		}
+	}
+
+	return roaring.FastOr(elems...), nil
+}
+
+// timeRangeKeys returns the keys of the bitmaps whose union contains all rows whose value of
+// a timestamp column is within [from, to). The interval is covered by the time buckets of the
+// coarsest granularity that fit into it, starting at the granularity with the provided level,
+// and the remaining parts at its edges are covered by finer granularities. Parts smaller than
+// a minute are covered by the values themselves.
+func (idx *Index) timeRangeKeys(colName string, values []timeValue, from, to time.Time, level int) []uint64 {
+	for ; level < len(granularities); level++ {
+		g := granularities[level]
+
+		bucketCol, ok := idx.schema.Columns[DateTrunc(g, colName)]
+		if !ok {
+			continue
+		}
+
+		start, end := g.ceil(from), g.truncate(to)
+		if !start.Before(end) {
+			continue
+		}
+
+		keys := idx.timeRangeKeys(colName, values, from, start, level+1)
+
+		for t := start; t.Before(end); t = g.next(t) {
+			if valueIdx, ok := bucketCol.Values[t.Format(time.RFC3339Nano)]; ok {
+				keys = append(keys, valueIdx)
+			}
+		}
+
+		return append(keys, idx.timeRangeKeys(colName, values, end, to, level+1)...)
+	}
+
+	var keys []uint64
+
+	for i := sort.Search(len(values), func(i int) bool { return !values[i].t.Before(from) }); i < len(values) && values[i].t.Before(to); i++ {
+		keys = append(keys, values[i].idx)
+	}
+
+	return keys
+}
+
+func (b *RangeBound) parseTime(column string) (time.Time, error) {
+	t, err := parseTimestamp(b.Value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid range bound %q for column %q: not a timestamp", b.Value, column)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,188 @@
+package updog
+
+import (
+	"io/fs"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestGranularity(t *testing.T) {
+	ts := time.Date(2024, 2, 29, 13, 45, 30, 500, time.UTC)
+
+	testData := []struct {
+		g        Granularity
+		truncate time.Time
+		next     time.Time
+	}{
+		{GranularityMinute, time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC), time.Date(2024, 2, 29, 13, 46, 0, 0, time.UTC)},
+		{GranularityHour, time.Date(2024, 2, 29, 13, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 14, 0, 0, 0, time.UTC)},
+		{GranularityDay, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{GranularityMonth, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.g.String(), func(t *testing.T) {
+			require.Equal(t, tt.truncate, tt.g.truncate(ts))
+			require.Equal(t, tt.next, tt.g.next(tt.truncate))
+			require.Equal(t, tt.next, tt.g.ceil(ts))
+			require.Equal(t, tt.truncate, tt.g.ceil(tt.truncate))
+
+			g, err := ParseGranularity(tt.g.String())
+			require.NoError(t, err)
+			require.Equal(t, tt.g, g)
+
+			g, column, ok := parseDateTrunc(DateTrunc(tt.g, "event time"))
+			require.True(t, ok)
+			require.Equal(t, tt.g, g)
+			require.Equal(t, "event time", column)
+		})
+	}
+
+	_, err := ParseGranularity("week")
+	require.Error(t, err)
+
+	_, _, ok := parseDateTrunc("date_trunc('week', ts)")
+	require.False(t, ok)
+}
+
+func TestQueryTimeRange(t *testing.T) {
+	idxWriter := NewIndexWriter("")
+
+	require.NoError(t, idxWriter.SetColumnType("ts", ColumnTypeTimestamp))
+
+	rng := rand.New(rand.NewSource(42))
+
+	start := time.Date(2023, 11, 28, 0, 0, 0, 0, time.UTC)
+
+	var timestamps []time.Time
+
+	for range 2000 {
+		ts := start.Add(time.Duration(rng.Int63n(int64(100 * 24 * time.Hour))))
+		if rng.Intn(4) == 0 {
+			ts = ts.Truncate(time.Hour)
+		}
+
+		_, err := idxWriter.AddRow(map[string]string{"ts": ts.Format(time.RFC3339Nano)})
+		require.NoError(t, err)
+
+		timestamps = append(timestamps, ts)
+	}
+
+	_, err := idxWriter.AddRow(map[string]string{"other": "x"})
+	require.NoError(t, err)
+
+	_, err = idxWriter.AddRow(map[string]string{DateTrunc(GranularityDay, "ts"): "2024-01-01"})
+	require.Error(t, err)
+
+	require.Error(t, idxWriter.SetColumnType(DateTrunc(GranularityDay, "ts"), ColumnTypeString))
+
+	testFile, err := os.CreateTemp(os.TempDir(), "updog_test_")
+	require.NoError(t, err)
+
+	db, err := bbolt.Open("", 0644, &bbolt.Options{
+		OpenFile: func(string, int, fs.FileMode) (*os.File, error) {
+			return testFile, nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, idxWriter.WriteToBoltDatabase(db))
+
+	idx, err := OpenIndexFromBoltDatabase(db, WithCache(NewLRUCache(1024*1024)))
+	require.NoError(t, err)
+
+	require.NoError(t, idx.Verify())
+
+	count := func(from, to time.Time) uint64 {
+		var n uint64
+		for _, ts := range timestamps {
+			if !ts.Before(from) && !ts.After(to) {
+				n++
+			}
+		}
+		return n
+	}
+
+	bound := func(ts time.Time) *RangeBound {
+		return &RangeBound{Value: ts.Format(time.RFC3339Nano)}
+	}
+
+	for range 200 {
+		from := start.Add(time.Duration(rng.Int63n(int64(110 * 24 * time.Hour)))).Add(-5 * 24 * time.Hour)
+		to := from.Add(time.Duration(rng.Int63n(int64(60 * 24 * time.Hour))))
+
+		switch rng.Intn(3) {
+		case 0:
+			from = from.Truncate(24 * time.Hour)
+		case 1:
+			to = to.Truncate(time.Minute)
+		}
+
+		result, err := idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Lower: bound(from), Upper: bound(to)}})
+		require.NoError(t, err)
+		require.Equal(t, count(from, to), result.Count, "%s BETWEEN %s AND %s", "ts", from, to)
+	}
+
+	ts := timestamps[0]
+
+	result, err := idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Lower: &RangeBound{Value: ts.Format(time.RFC3339Nano), Exclusive: true}, Upper: bound(ts)}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Upper: bound(ts)}})
+	require.NoError(t, err)
+	require.Equal(t, count(start, ts), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprRange{Column: "ts"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(timestamps)), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Lower: &RangeBound{Value: "2024-01-01"}, Upper: &RangeBound{Value: "2024-02-01", Exclusive: true}}})
+	require.NoError(t, err)
+	require.Equal(t, count(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.UTC)), result.Count)
+
+	// whole months are covered by one time bucket each, and the remaining days, hours and
+	// minutes by at most one time bucket per day, hour and minute.
+	col := idx.schema.Columns["ts"]
+	keys := idx.timeRangeKeys("ts", idx.timeValues("ts", col), time.Date(2023, 12, 31, 22, 59, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), 0)
+	require.LessOrEqual(t, len(keys), 4)
+
+	_, err = idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Lower: &RangeBound{Value: "yesterday"}}})
+	require.ErrorContains(t, err, "not a timestamp")
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "ts"}, GroupBy: []string{DateTrunc(GranularityMonth, "ts")}})
+	require.NoError(t, err)
+
+	var (
+		months []any
+		total  uint64
+	)
+	for _, g := range result.Groups {
+		months = append(months, g.Fields[0].TypedValue())
+		total += g.Count
+	}
+
+	require.Equal(t, []any{
+		time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	}, months)
+	require.Equal(t, uint64(len(timestamps)), total)
+
+	day := DateTrunc(GranularityDay, "ts")
+
+	result, err = idx.Execute(&Query{Expr: &ExprEqual{Column: day, Value: "2024-01-15"}})
+	require.NoError(t, err)
+	require.Equal(t, count(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 23, 59, 59, 999999999, time.UTC)), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprMissing{Column: day}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+}
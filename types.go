@@ -1,9 +1,30 @@
 package updog
 
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"strconv"
+
+	updogv1 "github.com/akrennmair/updog/proto/updog/v1"
+	"github.com/cespare/xxhash/v2"
+	"google.golang.org/protobuf/proto"
+)
+
 type schema struct {
 	Columns map[string]*column
+
+	// keys contains the value index keys of all values in the schema. It is used
+	// to detect hash collisions, and built on first use.
+	keys map[uint64]struct{}
 }
 
+// add adds a value of a column to the schema, and returns its value index key. The key is
+// usually the hash of the column name and value. If that hash is already used by a different
+// column name and value, a different unused key is assigned instead. As the keys of all values
+// are persisted with the schema, indexes always need to look up keys in the schema instead of
+// hashing column names and values.
 func (sch *schema) add(k, v string) uint64 {
 	col, ok := sch.Columns[k]
 	if !ok {
@@ -15,13 +36,313 @@ func (sch *schema) add(k, v string) uint64 {
 
 	val, ok := col.Values[v]
 	if !ok {
-		val = getValueIndex(k, v)
+		val = sch.newKey(getValueIndex(k, v))
 		col.Values[v] = val
 	}
 
 	return val
 }
 
+// addPresence adds a column to the schema if it doesn't exist yet, and returns the key of its
+// presence bitmap, which contains all rows that have at least one value in the column. Keys of
+// presence bitmaps are assigned like value index keys, but start from the hash of the column name.
+func (sch *schema) addPresence(k string) uint64 {
+	col, ok := sch.Columns[k]
+	if !ok {
+		col = &column{
+			Values: make(map[string]uint64),
+		}
+		sch.Columns[k] = col
+	}
+
+	if col.Presence == 0 {
+		col.Presence = sch.newKey(xxhash.Sum64String(k))
+	}
+
+	return col.Presence
+}
+
+// setType declares the type of a column, adding it to the schema if it doesn't exist yet.
+// As values are stored in the canonical form of their type, the type of a column that already
+// has values can't be changed. Declaring a timestamp column also adds its time bucket columns.
+func (sch *schema) setType(k string, typ ColumnType) error {
+	if typ < ColumnTypeString || typ > ColumnTypeTimestamp {
+		return fmt.Errorf("invalid type %d of column %q", int(typ), k)
+	}
+
+	if _, _, ok := parseDateTrunc(k); ok && typ != ColumnTypeTimestamp {
+		return fmt.Errorf("time bucket column %q can only have type %s", k, ColumnTypeTimestamp)
+	}
+
+	col, ok := sch.Columns[k]
+	if !ok {
+		col = &column{
+			Values: make(map[string]uint64),
+		}
+		sch.Columns[k] = col
+	}
+
+	if col.Type == typ {
+		sch.addTimeBuckets(k)
+		return nil
+	}
+
+	if col.Numeric {
+		return fmt.Errorf("numeric column %q can only have type %s", k, ColumnTypeInteger)
+	}
+
+	if col.Type != ColumnTypeString {
+		return fmt.Errorf("column %q already has type %s", k, col.Type)
+	}
+
+	if len(col.Values) > 0 {
+		return fmt.Errorf("column %q already contains values, so its type can't be changed to %s", k, typ)
+	}
+
+	col.Type = typ
+
+	sch.addTimeBuckets(k)
+
+	return nil
+}
+
+// setNumeric marks a column as numeric, adding it to the schema if it doesn't exist yet.
+// Numeric columns are integer columns that are stored as bit-sliced index instead of one
+// bitmap per value, so a column that already has values can't be turned into a numeric column.
+func (sch *schema) setNumeric(k string) error {
+	if err := sch.setType(k, ColumnTypeInteger); err != nil {
+		return err
+	}
+
+	col := sch.Columns[k]
+
+	if col.Numeric {
+		return nil
+	}
+
+	if len(col.Values) > 0 {
+		return fmt.Errorf("column %q already contains non-numeric values", k)
+	}
+
+	col.Numeric = true
+	col.Bits = make([]uint64, numericBits)
+
+	return nil
+}
+
+// canonicalValue validates a value of a column according to the column's type, and returns
+// the canonical form of the value. Values of columns that aren't in the schema yet are strings.
+func (sch *schema) canonicalValue(k, v string) (string, error) {
+	col, ok := sch.Columns[k]
+	if !ok || col.Type == ColumnTypeString {
+		return v, nil
+	}
+
+	canonical, err := col.Type.canonical(v)
+	if err != nil {
+		return "", fmt.Errorf("invalid value %q of %s column %q: %w", v, col.Type, k, err)
+	}
+
+	return canonical, nil
+}
+
+// addBit returns the key of the bitmap of bit i of a numeric column, and assigns one if
+// the column doesn't have one yet. Keys of bits start from the hash of the column name and
+// the bit number.
+func (sch *schema) addBit(k string, i int) uint64 {
+	col := sch.Columns[k]
+
+	if col.Bits[i] == 0 {
+		col.Bits[i] = sch.newKey(xxhash.Sum64(append([]byte(k), 1, byte(i))))
+	}
+
+	return col.Bits[i]
+}
+
+// numericValue parses the value of a column if the column is numeric, and returns it
+// encoded as it is stored in the bit-sliced index.
+func (sch *schema) numericValue(k, v string) (encoded uint64, numeric bool, err error) {
+	col, ok := sch.Columns[k]
+	if !ok || !col.Numeric {
+		return 0, false, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid value %q of numeric column %q: %w", v, k, err)
+	}
+
+	return encodeNumeric(n), true, nil
+}
+
+// numericBits is the number of bits of the values of numeric columns.
+const numericBits = 64
+
+// encodeNumeric encodes a value of a numeric column. The sign bit is flipped, so that the
+// order of the encoded values as unsigned integers matches the order of the values.
+func encodeNumeric(n int64) uint64 {
+	return uint64(n) ^ 1<<63
+}
+
+func decodeNumeric(encoded uint64) int64 {
+	return int64(encoded ^ 1<<63)
+}
+
+// addMissingPresence assigns keys of presence bitmaps to all columns that don't have one yet,
+// and returns the names of these columns.
+func (sch *schema) addMissingPresence() []string {
+	var names []string
+
+	for _, name := range sch.columnNames() {
+		if sch.Columns[name].Presence == 0 {
+			sch.addPresence(name)
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// newKey returns the first unused key starting from the provided hash, and marks it as used.
+// The key 0 is never used, as it marks columns without a presence bitmap.
+func (sch *schema) newKey(hash uint64) uint64 {
+	if sch.keys == nil {
+		sch.buildKeys()
+	}
+
+	key := hash
+	for _, collision := sch.keys[key]; collision || key == 0; _, collision = sch.keys[key] {
+		key++
+	}
+
+	sch.keys[key] = struct{}{}
+
+	return key
+}
+
+func (sch *schema) buildKeys() {
+	sch.keys = make(map[uint64]struct{})
+
+	for _, col := range sch.Columns {
+		for _, val := range col.Values {
+			sch.keys[val] = struct{}{}
+		}
+		if col.Presence != 0 {
+			sch.keys[col.Presence] = struct{}{}
+		}
+		for _, key := range col.Bits {
+			if key != 0 {
+				sch.keys[key] = struct{}{}
+			}
+		}
+	}
+}
+
+func (sch *schema) columnNames() []string {
+	names := make([]string, 0, len(sch.Columns))
+
+	for name := range sch.Columns {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// marshal encodes the schema as protobuf Schema message, with columns and values sorted by name.
+func (sch *schema) marshal() ([]byte, error) {
+	pbs := &updogv1.Schema{}
+
+	for _, name := range sch.columnNames() {
+		col := sch.Columns[name]
+
+		pbc := &updogv1.Schema_Column{
+			Name:        name,
+			Values:      make([]*updogv1.Schema_Value, 0, len(col.Values)),
+			PresenceKey: col.Presence,
+			Numeric:     col.Numeric,
+			BitKeys:     col.Bits,
+			Type:        updogv1.ColumnType(col.Type),
+		}
+
+		for v, key := range col.Values {
+			pbc.Values = append(pbc.Values, &updogv1.Schema_Value{Value: v, Key: key})
+		}
+
+		sort.Slice(pbc.Values, func(i, j int) bool { return pbc.Values[i].Value < pbc.Values[j].Value })
+
+		pbs.Columns = append(pbs.Columns, pbc)
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(pbs)
+}
+
+// unmarshalSchema decodes a schema. Index files of format versions before 3 contain
+// a gob-encoded schema, later versions a protobuf Schema message.
+func unmarshalSchema(data []byte, formatVersion int) (*schema, error) {
+	sch := &schema{
+		Columns: make(map[string]*column),
+	}
+
+	if formatVersion < formatVersionProtobufSchema {
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(sch); err != nil {
+			return nil, err
+		}
+		return sch, nil
+	}
+
+	var pbs updogv1.Schema
+
+	if err := proto.Unmarshal(data, &pbs); err != nil {
+		return nil, err
+	}
+
+	for _, pbc := range pbs.Columns {
+		if _, ok := sch.Columns[pbc.Name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", pbc.Name)
+		}
+
+		col := &column{
+			Values:   make(map[string]uint64, len(pbc.Values)),
+			Presence: pbc.PresenceKey,
+			Numeric:  pbc.Numeric,
+			Bits:     pbc.BitKeys,
+			Type:     ColumnType(pbc.Type),
+		}
+
+		if col.Type < ColumnTypeString || col.Type > ColumnTypeTimestamp {
+			return nil, fmt.Errorf("column %q has unknown type %d", pbc.Name, int(col.Type))
+		}
+
+		if col.Numeric && len(col.Bits) != numericBits {
+			return nil, fmt.Errorf("numeric column %q has %d bits instead of %d", pbc.Name, len(col.Bits), numericBits)
+		}
+
+		for _, pbv := range pbc.Values {
+			col.Values[pbv.Value] = pbv.Key
+		}
+
+		sch.Columns[pbc.Name] = col
+	}
+
+	return sch, nil
+}
+
 type column struct {
 	Values map[string]uint64
+
+	// Presence is the key of the presence bitmap of the column, or 0 if the index
+	// was written before presence bitmaps were introduced.
+	Presence uint64
+
+	// Numeric is set if the column is stored as bit-sliced index. Bits contains the keys
+	// of the bitmaps of each bit of the encoded values, or 0 for bits that no row has set.
+	// The presence bitmap contains all rows that have a value in a numeric column.
+	Numeric bool
+	Bits    []uint64
+
+	// Type is the type of the column's values. All values are stored in the canonical form
+	// of the type.
+	Type ColumnType
 }
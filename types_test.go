@@ -0,0 +1,82 @@
+package updog
+
+import (
+	"slices"
+	"testing"
+
+	updogv1 "github.com/akrennmair/updog/proto/updog/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSchemaAddCollision(t *testing.T) {
+	sch := &schema{
+		Columns: map[string]*column{
+			// pretend that a:x is a value whose hash collides with the hash of b:2.
+			"a": {Values: map[string]uint64{"x": getValueIndex("b", "2")}},
+		},
+	}
+
+	key := sch.add("b", "1")
+	require.Equal(t, getValueIndex("b", "1"), key)
+
+	key = sch.add("b", "2")
+	require.NotEqual(t, getValueIndex("b", "2"), key)
+	require.Equal(t, key, sch.add("b", "2"))
+	require.Equal(t, getValueIndex("b", "2"), sch.Columns["a"].Values["x"])
+}
+
+func TestSchemaMarshal(t *testing.T) {
+	bits := make([]uint64, numericBits)
+	bits[0], bits[63] = 6, 7
+
+	sch := &schema{
+		Columns: map[string]*column{
+			"b": {Values: map[string]uint64{"2": 2, "1": 1}},
+			"a": {Values: map[string]uint64{"x": 3}, Presence: 4},
+			"c": {Values: map[string]uint64{}, Presence: 5, Numeric: true, Bits: bits, Type: ColumnTypeInteger},
+			"d": {Values: map[string]uint64{"2024-01-02T00:00:00Z": 8}, Type: ColumnTypeTimestamp},
+		},
+	}
+
+	data, err := sch.marshal()
+	require.NoError(t, err)
+
+	var pbs updogv1.Schema
+	require.NoError(t, proto.Unmarshal(data, &pbs))
+	require.Len(t, pbs.Columns, 4)
+	require.Equal(t, "a", pbs.Columns[0].Name)
+	require.Equal(t, "b", pbs.Columns[1].Name)
+	require.Equal(t, "1", pbs.Columns[1].Values[0].Value)
+	require.Equal(t, uint64(1), pbs.Columns[1].Values[0].Key)
+	require.Equal(t, uint64(4), pbs.Columns[0].PresenceKey)
+	require.True(t, pbs.Columns[2].Numeric)
+	require.Len(t, pbs.Columns[2].BitKeys, numericBits)
+	require.Equal(t, updogv1.ColumnType_COLUMN_TYPE_TIMESTAMP, pbs.Columns[3].Type)
+
+	decoded, err := unmarshalSchema(data, formatVersionCurrent)
+	require.NoError(t, err)
+	require.Equal(t, sch.Columns, decoded.Columns)
+}
+
+func TestSortByType(t *testing.T) {
+	testData := []struct {
+		typ      ColumnType
+		values   []string
+		expected []string
+	}{
+		{ColumnTypeString, []string{"b", "10", "a", "9"}, []string{"10", "9", "a", "b"}},
+		{ColumnTypeInteger, []string{"10", "-3", "x", "9"}, []string{"x", "-3", "9", "10"}},
+		{ColumnTypeFloat, []string{"1.5", "1e-3", "-2"}, []string{"-2", "1e-3", "1.5"}},
+		{ColumnTypeBoolean, []string{"true", "false"}, []string{"false", "true"}},
+		{ColumnTypeTimestamp, []string{"2024-02-01", "2024-01-01T12:00:00Z", "b", "a"}, []string{"a", "b", "2024-01-01T12:00:00Z", "2024-02-01"}},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.typ.String(), func(t *testing.T) {
+			values := slices.Clone(tt.values)
+			sortByType(tt.typ, values, func(v string) string { return v })
+			require.Equal(t, tt.expected, values)
+		})
+	}
+}
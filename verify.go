@@ -0,0 +1,118 @@
+package updog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/cespare/xxhash/v2"
+	"go.etcd.io/bbolt"
+)
+
+// Verify checks the integrity of the index data. It checks that every value, every bit of
+// numeric columns and the presence of every column in the schema have a bitmap that can be
+// decoded, that no bitmap contains row IDs beyond the number of rows, that the checksums of
+// all bitmaps match, and that no bitmaps or checksums are stored that don't belong to any
+// value or column in the schema. If any problems are found, the returned error joins the
+// errors describing each problem.
+func (idx *Index) Verify() error {
+	idx.mtx.RLock()
+	defer idx.mtx.RUnlock()
+
+	var problems []error
+
+	// index files written before checksums or presence bitmaps were introduced don't contain any.
+	checksums := idx.metadata.FormatVersion >= formatVersionChecksums
+	presence := idx.metadata.FormatVersion >= formatVersionPresence
+
+	err := idx.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("data"))
+
+		knownValues := map[uint64]bool{}
+
+		checkBitmap := func(valueIdx uint64, desc string) {
+			knownValues[valueIdx] = true
+
+			data := bucket.Get(valueKey(keyPrefixValue, valueIdx))
+			if data == nil {
+				problems = append(problems, fmt.Errorf("%s has no bitmap", desc))
+				return
+			}
+
+			if checksums {
+				checksum := bucket.Get(valueKey(keyPrefixChecksum, valueIdx))
+				switch {
+				case checksum == nil:
+					problems = append(problems, fmt.Errorf("bitmap of %s has no checksum", desc))
+				case len(checksum) != 8 || binary.BigEndian.Uint64(checksum) != xxhash.Sum64(data):
+					problems = append(problems, fmt.Errorf("bitmap of %s doesn't match its checksum", desc))
+				}
+			}
+
+			bm := roaring.New()
+			if err := bm.UnmarshalBinary(data); err != nil {
+				problems = append(problems, fmt.Errorf("bitmap of %s can't be decoded: %w", desc, err))
+				return
+			}
+
+			if !bm.IsEmpty() && bm.Maximum() >= idx.nextRowID {
+				problems = append(problems, fmt.Errorf("bitmap of %s contains row ID %d, but the index only has %d rows", desc, bm.Maximum(), idx.nextRowID))
+			}
+		}
+
+		for _, colName := range idx.schema.columnNames() {
+			col := idx.schema.Columns[colName]
+
+			values := make([]string, 0, len(col.Values))
+			for v := range col.Values {
+				values = append(values, v)
+			}
+			sort.Strings(values)
+
+			for _, v := range values {
+				checkBitmap(col.Values[v], fmt.Sprintf("value %q of column %q", v, colName))
+			}
+
+			for i, bitIdx := range col.Bits {
+				if bitIdx != 0 {
+					checkBitmap(bitIdx, fmt.Sprintf("bit %d of column %q", i, colName))
+				}
+			}
+
+			if col.Presence != 0 {
+				checkBitmap(col.Presence, fmt.Sprintf("presence of column %q", colName))
+			} else if presence {
+				problems = append(problems, fmt.Errorf("column %q has no presence bitmap", colName))
+			}
+		}
+
+		for _, prefix := range [][]byte{keyPrefixValue, keyPrefixChecksum} {
+			c := bucket.Cursor()
+
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				if len(k) != len(prefix)+8 {
+					problems = append(problems, fmt.Errorf("invalid key %q", k))
+					continue
+				}
+
+				if valueIdx := binary.BigEndian.Uint64(k[len(prefix):]); !knownValues[valueIdx] {
+					problems = append(problems, fmt.Errorf("orphaned key %c%016x doesn't belong to any value", prefix[0], valueIdx))
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !idx.deleted.IsEmpty() && idx.deleted.Maximum() >= idx.nextRowID {
+		problems = append(problems, fmt.Errorf("deleted rows contain row ID %d, but the index only has %d rows", idx.deleted.Maximum(), idx.nextRowID))
+	}
+
+	return errors.Join(problems...)
+}
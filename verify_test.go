@@ -0,0 +1,269 @@
+package updog
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func TestVerify(t *testing.T) {
+	testData := []struct {
+		name        string
+		corrupt     func(bucket *bbolt.Bucket) error
+		expectedErr string
+	}{
+		{
+			name:    "ok",
+			corrupt: func(bucket *bbolt.Bucket) error { return nil },
+		},
+		{
+			name: "checksum mismatch",
+			corrupt: func(bucket *bbolt.Bucket) error {
+				key := valueKey(keyPrefixValue, getValueIndex("a", "1"))
+				data := append([]byte{}, bucket.Get(key)...)
+				data[len(data)-1] ^= 0xFF
+				return bucket.Put(key, data)
+			},
+			expectedErr: `bitmap of value "1" of column "a" doesn't match its checksum`,
+		},
+		{
+			name: "truncated bitmap",
+			corrupt: func(bucket *bbolt.Bucket) error {
+				key := valueKey(keyPrefixValue, getValueIndex("a", "1"))
+				return bucket.Put(key, bucket.Get(key)[:3])
+			},
+			expectedErr: `bitmap of value "1" of column "a" can't be decoded`,
+		},
+		{
+			name: "missing bitmap",
+			corrupt: func(bucket *bbolt.Bucket) error {
+				return deleteBitmap(bucket, getValueIndex("b", "x"))
+			},
+			expectedErr: `value "x" of column "b" has no bitmap`,
+		},
+		{
+			name: "row ID out of range",
+			corrupt: func(bucket *bbolt.Bucket) error {
+				return putBitmap(bucket, getValueIndex("b", "x"), roaring.BitmapOf(0, 2))
+			},
+			expectedErr: `bitmap of value "x" of column "b" contains row ID 2, but the index only has 2 rows`,
+		},
+		{
+			name: "missing presence bitmap",
+			corrupt: func(bucket *bbolt.Bucket) error {
+				return deleteBitmap(bucket, xxhash.Sum64String("b"))
+			},
+			expectedErr: `presence of column "b" has no bitmap`,
+		},
+		{
+			name: "orphaned bitmap",
+			corrupt: func(bucket *bbolt.Bucket) error {
+				return putBitmap(bucket, 42, roaring.BitmapOf(0))
+			},
+			expectedErr: "orphaned key V000000000000002a doesn't belong to any value",
+		},
+	}
+
+	for _, tt := range testData {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "index.updog")
+
+			w := NewIndexWriter(filename)
+			w.AddRow(map[string]string{"a": "1", "b": "x"})
+			w.AddRow(map[string]string{"a": "2"})
+			require.NoError(t, w.Flush())
+
+			db, err := bbolt.Open(filename, 0644, nil)
+			require.NoError(t, err)
+
+			require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+				return tt.corrupt(tx.Bucket([]byte("data")))
+			}))
+
+			idx, err := OpenIndexFromBoltDatabase(db)
+			require.NoError(t, err)
+			defer idx.Close()
+
+			err = idx.Verify()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestAppendAddsMissingChecksums(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := NewIndexWriter(filename)
+	w.AddRow(map[string]string{"a": "1"})
+	w.AddRow(map[string]string{"a": "2"})
+	require.NoError(t, w.Flush())
+
+	db, err := bbolt.Open(filename, 0644, nil)
+	require.NoError(t, err)
+
+	// turn the index file into one that was written before checksums were introduced.
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("data"))
+		for _, valueIdx := range w.schema.Columns["a"].Values {
+			if err := bucket.Delete(valueKey(keyPrefixChecksum, valueIdx)); err != nil {
+				return err
+			}
+		}
+		return bucket.Put(keyMetadata, []byte(`{"format_version":3,"rows":2,"columns":["a"]}`))
+	}))
+	require.NoError(t, db.Close())
+
+	w, err = OpenIndexWriterForAppend(filename)
+	require.NoError(t, err)
+	w.AddRow(map[string]string{"a": "1"})
+	require.NoError(t, w.Flush())
+
+	idx, err := OpenIndex(filename)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	require.Equal(t, formatVersionCurrent, idx.GetMetadata().FormatVersion)
+	require.NoError(t, idx.Verify())
+}
+
+func TestAppendRebuildsPresence(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := NewIndexWriter(filename)
+	w.AddRow(map[string]string{"a": "1", "b": "x"})
+	w.AddRow(map[string]string{"a": "2"})
+	require.NoError(t, w.Flush())
+
+	db, err := bbolt.Open(filename, 0644, nil)
+	require.NoError(t, err)
+
+	// turn the index file into one that was written before presence bitmaps were introduced.
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("data"))
+		for _, col := range w.schema.Columns {
+			if err := deleteBitmap(bucket, col.Presence); err != nil {
+				return err
+			}
+			col.Presence = 0
+		}
+		schemaBuf, err := w.schema.marshal()
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(keySchema, schemaBuf); err != nil {
+			return err
+		}
+		return bucket.Put(keyMetadata, []byte(`{"format_version":4,"rows":2,"columns":["a","b"]}`))
+	}))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+	require.NoError(t, idx.Verify())
+
+	result, err := idx.Execute(&Query{Expr: &ExprMissing{Column: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+	require.NoError(t, db.Close())
+
+	w, err = OpenIndexWriterForAppend(filename)
+	require.NoError(t, err)
+	w.AddRow(map[string]string{"a": "3", "b": "y"})
+	w.AddRow(map[string]string{"a": "4"})
+	require.NoError(t, w.Flush())
+
+	idx, err = OpenIndex(filename)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	require.Equal(t, formatVersionCurrent, idx.GetMetadata().FormatVersion)
+	require.NoError(t, idx.Verify())
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+}
+
+func TestAppendRebuildsTimeBuckets(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := NewIndexWriter(filename)
+	require.NoError(t, w.SetColumnType("ts", ColumnTypeTimestamp))
+	w.AddRow(map[string]string{"ts": "2024-01-01T10:00:00Z"})
+	w.AddRow(map[string]string{"ts": "2024-01-02T11:30:00Z"})
+	w.AddRow(map[string]string{"a": "1"})
+	require.NoError(t, w.Flush())
+
+	db, err := bbolt.Open(filename, 0644, nil)
+	require.NoError(t, err)
+
+	// turn the index file into one that was written before time buckets were introduced.
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte("data"))
+		for _, g := range granularities {
+			name := DateTrunc(g, "ts")
+			col := w.schema.Columns[name]
+			for _, valueIdx := range col.Values {
+				if err := deleteBitmap(bucket, valueIdx); err != nil {
+					return err
+				}
+			}
+			if err := deleteBitmap(bucket, col.Presence); err != nil {
+				return err
+			}
+			delete(w.schema.Columns, name)
+		}
+		schemaBuf, err := w.schema.marshal()
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(keySchema, schemaBuf); err != nil {
+			return err
+		}
+		return bucket.Put(keyMetadata, []byte(`{"format_version":7,"rows":3,"columns":["a","ts"]}`))
+	}))
+
+	idx, err := OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+	require.NoError(t, idx.Verify())
+
+	result, err := idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Lower: &RangeBound{Value: "2024-01-01"}, Upper: &RangeBound{Value: "2024-01-03"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+	require.NoError(t, db.Close())
+
+	w, err = OpenIndexWriterForAppend(filename)
+	require.NoError(t, err)
+	_, err = w.AddRow(map[string]string{"ts": "2024-01-02T12:00:00Z"})
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	idx, err = OpenIndex(filename)
+	require.NoError(t, err)
+	defer idx.Close()
+
+	require.Equal(t, formatVersionCurrent, idx.GetMetadata().FormatVersion)
+	require.NoError(t, idx.Verify())
+
+	result, err = idx.Execute(&Query{Expr: &ExprRange{Column: "ts", Lower: &RangeBound{Value: "2024-01-01"}, Upper: &RangeBound{Value: "2024-01-03"}}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), result.Count)
+
+	result, err = idx.Execute(&Query{Expr: &ExprPresent{Column: "ts"}, GroupBy: []string{DateTrunc(GranularityDay, "ts")}})
+	require.NoError(t, err)
+	require.Equal(t, []ResultGroup{
+		{Fields: []ResultField{{Column: DateTrunc(GranularityDay, "ts"), Value: "2024-01-01T00:00:00Z", Type: ColumnTypeTimestamp}}, Count: 1},
+		{Fields: []ResultField{{Column: DateTrunc(GranularityDay, "ts"), Value: "2024-01-02T00:00:00Z", Type: ColumnTypeTimestamp}}, Count: 2},
+	}, result.Groups)
+
+	result, err = idx.Execute(&Query{Expr: &ExprMissing{Column: DateTrunc(GranularityHour, "ts")}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+}
@@ -2,10 +2,13 @@ package updog
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/binary"
-	"encoding/gob"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/akrennmair/updog/internal/openfile"
@@ -22,8 +25,22 @@ type IndexWriter struct {
 
 	values    map[uint64]*roaring.Bitmap
 	nextRowID uint32
+	createdAt time.Time
 
-	filename string
+	filename  string
+	appending bool
+
+	// addChecksums is set when appending to an index file that was written before
+	// checksums of bitmaps were introduced.
+	addChecksums bool
+
+	// rebuildPresence contains the columns of an index file that was written before
+	// presence bitmaps were introduced.
+	rebuildPresence []string
+
+	// rebuildTimeBuckets contains the timestamp columns of an index file that was written
+	// before time buckets were introduced.
+	rebuildTimeBuckets []string
 }
 
 // NewIndexWriter creates a new IndexWriter object. IndexWriter is used to add row data and to write
@@ -38,28 +55,255 @@ func NewIndexWriter(filename string) *IndexWriter {
 	}
 }
 
+// OpenIndexWriterForAppend opens an existing index file to add further rows to it. Row IDs
+// continue after the existing rows, and Flush merges the new rows into the existing index data.
+func OpenIndexWriterForAppend(filename string) (*IndexWriter, error) {
+	db, err := bbolt.Open(filename, 0644, &bbolt.Options{ReadOnly: true, OpenFile: openfile.OpenFile(openfile.Options{FailIfFileDoesntExist: true})})
+	if err != nil {
+		return nil, err
+	}
+
+	defer db.Close()
+
+	var state *indexState
+
+	if err := db.View(func(tx *bbolt.Tx) (err error) {
+		state, err = readIndexState(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return &IndexWriter{
+		schema:             state.schema,
+		values:             make(map[uint64]*roaring.Bitmap),
+		nextRowID:          state.nextRowID,
+		createdAt:          state.metadata.CreatedAt,
+		filename:           filename,
+		appending:          true,
+		addChecksums:       state.metadata.FormatVersion < formatVersionChecksums,
+		rebuildPresence:    state.schema.addMissingPresence(),
+		rebuildTimeBuckets: state.schema.addMissingTimeBuckets(),
+	}, nil
+}
+
+// SetColumnType declares the type of a column. The values of typed columns are validated when
+// rows are added, and stored in the canonical form of the type. The type of a column needs to
+// be declared before any rows with values in it are added. Columns whose type isn't declared
+// are string columns. The values of timestamp columns are also stored in the column's time
+// bucket columns, see DateTrunc.
+func (idx *IndexWriter) SetColumnType(name string, typ ColumnType) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	return idx.schema.setType(name, typ)
+}
+
+// SetNumericColumn declares a column as numeric. Numeric columns are integer columns that
+// are stored as bit-sliced index, i.e. as one bitmap per bit of the values, which allows
+// queries to compute aggregates like SUM and AVG of the column and to match ranges of values.
+// Numeric columns can't be used in expressions that match single values or to group by. A column needs to be declared as numeric
+// before any rows with values in it are added.
+func (idx *IndexWriter) SetNumericColumn(name string) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	return idx.schema.setNumeric(name)
+}
+
 // AddRow adds a row of data and returns its row ID. The row data must be provided as map,
 // where the keys contain the column names, and the values the corresponding column values.
+// Columns that aren't contained in the map are missing in the row. If a value is not valid for
+// the type of its column, an error is returned and no row is added.
 func (idx *IndexWriter) AddRow(values map[string]string) (uint32, error) {
 	idx.mtx.Lock()
 	defer idx.mtx.Unlock()
 
+	canonical, numeric, err := checkRow(idx.schema, values)
+	if err != nil {
+		return 0, err
+	}
+
 	rowID := idx.nextRowID
 	defer func() {
 		idx.nextRowID++
 	}()
 
 	for k, v := range values {
+		if encoded, ok := numeric[k]; ok {
+			idx.addNumeric(k, encoded, rowID)
+			continue
+		}
+
+		if c, ok := canonical[k]; ok {
+			v = c
+		}
+
 		valueIdx := idx.schema.add(k, v)
 
 		bm := idx.getValueBitmap(valueIdx)
 
 		bm.Add(rowID)
+
+		idx.getValueBitmap(idx.schema.addPresence(k)).Add(rowID)
+
+		idx.addTimeBuckets(k, v, rowID)
 	}
 
 	return rowID, nil
 }
 
+// AddRowMulti adds a row of data where columns can have multiple values, and returns its row ID.
+// The row is added to the bitmap of each of the values, so an equality expression on such a column
+// matches all rows that contain the value, and grouping by the column counts the row in the group of
+// each of its values. Columns with an empty list of values are treated as if they were missing.
+// Numeric columns can't have more than one value.
+func (idx *IndexWriter) AddRowMulti(values map[string][]string) (uint32, error) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	canonical, numeric, err := checkRowMulti(idx.schema, values)
+	if err != nil {
+		return 0, err
+	}
+
+	rowID := idx.nextRowID
+	defer func() {
+		idx.nextRowID++
+	}()
+
+	for k, vs := range values {
+		if encoded, ok := numeric[k]; ok {
+			idx.addNumeric(k, encoded, rowID)
+			continue
+		}
+
+		if c, ok := canonical[k]; ok {
+			vs = c
+		}
+
+		for _, v := range vs {
+			valueIdx := idx.schema.add(k, v)
+
+			bm := idx.getValueBitmap(valueIdx)
+
+			bm.Add(rowID)
+
+			idx.addTimeBuckets(k, v, rowID)
+		}
+
+		if len(vs) > 0 {
+			idx.getValueBitmap(idx.schema.addPresence(k)).Add(rowID)
+		}
+	}
+
+	return rowID, nil
+}
+
+// checkRow validates the values of a row according to the types of their columns. It returns
+// the canonical forms of the values of typed columns that differ from the provided values, and
+// the values of numeric columns encoded as they are stored in the bit-sliced index.
+func checkRow(sch *schema, values map[string]string) (canonical map[string]string, numeric map[string]uint64, err error) {
+	for k, v := range values {
+		if _, _, ok := parseDateTrunc(k); ok {
+			return nil, nil, fmt.Errorf("rows can't have values in time bucket column %q", k)
+		}
+
+		encoded, ok, err := sch.numericValue(k, v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			if numeric == nil {
+				numeric = make(map[string]uint64)
+			}
+			numeric[k] = encoded
+			continue
+		}
+
+		c, err := sch.canonicalValue(k, v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if c != v {
+			if canonical == nil {
+				canonical = make(map[string]string)
+			}
+			canonical[k] = c
+		}
+	}
+
+	return canonical, numeric, nil
+}
+
+// checkRowMulti validates the values of a row with multiple values per column like checkRow.
+func checkRowMulti(sch *schema, values map[string][]string) (canonical map[string][]string, numeric map[string]uint64, err error) {
+	for k, vs := range values {
+		if _, _, ok := parseDateTrunc(k); ok {
+			return nil, nil, fmt.Errorf("rows can't have values in time bucket column %q", k)
+		}
+
+		if col, ok := sch.Columns[k]; ok && col.Numeric && len(vs) > 0 {
+			if len(vs) > 1 {
+				return nil, nil, fmt.Errorf("numeric column %q can't have multiple values", k)
+			}
+
+			encoded, _, err := sch.numericValue(k, vs[0])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if numeric == nil {
+				numeric = make(map[string]uint64)
+			}
+			numeric[k] = encoded
+			continue
+		}
+
+		for i, v := range vs {
+			c, err := sch.canonicalValue(k, v)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if c != v {
+				if canonical[k] == nil {
+					if canonical == nil {
+						canonical = make(map[string][]string)
+					}
+					canonical[k] = slices.Clone(vs)
+				}
+				canonical[k][i] = c
+			}
+		}
+	}
+
+	return canonical, numeric, nil
+}
+
+// addNumeric adds a row to the bitmaps of all bits that are set in the encoded value of a
+// numeric column, and to the column's presence bitmap.
+func (idx *IndexWriter) addNumeric(k string, encoded uint64, rowID uint32) {
+	for i := range numericBits {
+		if encoded&(1<<i) != 0 {
+			idx.getValueBitmap(idx.schema.addBit(k, i)).Add(rowID)
+		}
+	}
+
+	idx.getValueBitmap(idx.schema.addPresence(k)).Add(rowID)
+}
+
+// addTimeBuckets adds a row to the time bucket columns of a timestamp column according to the
+// canonical value v of the column.
+func (idx *IndexWriter) addTimeBuckets(k, v string, rowID uint32) {
+	for _, b := range idx.schema.timeBuckets(k, v) {
+		idx.getValueBitmap(idx.schema.add(b.column, b.value)).Add(rowID)
+		idx.getValueBitmap(idx.schema.addPresence(b.column)).Add(rowID)
+	}
+}
+
 func getValueIndex(k, v string) uint64 {
 	return xxhash.Sum64(append(append([]byte(k), 0), []byte(v)...))
 }
@@ -89,14 +333,192 @@ func (idx *IndexWriter) optimize() {
 }
 
 var (
-	keySchema      = []byte{'S'}
-	keyNextRowID   = []byte{'I'}
-	keyPrefixValue = []byte{'V'}
+	keySchema         = []byte{'S'}
+	keyNextRowID      = []byte{'I'}
+	keyIndexID        = []byte{'F'}
+	keyMetadata       = []byte{'M'}
+	keyDeletedRows    = []byte{'D'}
+	keyPrefixValue    = []byte{'V'}
+	keyPrefixChecksum = []byte{'C'}
 )
 
-// WriteToFile writes the index data to the provided file.
+// indexState contains the data of an existing index that is kept in memory.
+type indexState struct {
+	schema    *schema
+	nextRowID uint32
+	metadata  *Metadata
+}
+
+// readIndexState reads the metadata, the schema and the next row ID from the data bucket of
+// an existing index. Index files of older format versions are migrated while they are read.
+func readIndexState(tx *bbolt.Tx) (*indexState, error) {
+	bucket := tx.Bucket([]byte("data"))
+	if bucket == nil {
+		return nil, errors.New("no index data found")
+	}
+
+	md, err := readMetadata(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	sch, err := unmarshalSchema(bucket.Get(keySchema), md.FormatVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode schema: %w", err)
+	}
+
+	rowsItem := bucket.Get(keyNextRowID)
+	if len(rowsItem) != 4 {
+		return nil, fmt.Errorf("invalid next row ID of length %d", len(rowsItem))
+	}
+
+	nextRowID := binary.BigEndian.Uint32(rowsItem)
+
+	if md.FormatVersion == formatVersionLegacy {
+		md.Rows = nextRowID
+		md.Columns = sch.columnNames()
+	}
+
+	return &indexState{
+		schema:    sch,
+		nextRowID: nextRowID,
+		metadata:  md,
+	}, nil
+}
+
+// valueKey returns the key under which data of a value is stored, i.e. the prefix followed
+// by the value index key as 64-bit big-endian integer.
+func valueKey(prefix []byte, valueIdx uint64) []byte {
+	key := make([]byte, len(prefix)+8)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], valueIdx)
+	return key
+}
+
+// putBitmap writes the bitmap of a value and its checksum to the data bucket.
+func putBitmap(bucket *bbolt.Bucket, valueIdx uint64, bm *roaring.Bitmap) error {
+	valueBuf, err := bm.ToBytes()
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.Put(valueKey(keyPrefixValue, valueIdx), valueBuf); err != nil {
+		return err
+	}
+
+	var checksumBuf [8]byte
+
+	binary.BigEndian.PutUint64(checksumBuf[:], xxhash.Sum64(valueBuf))
+
+	return bucket.Put(valueKey(keyPrefixChecksum, valueIdx), checksumBuf[:])
+}
+
+// addMissingChecksums writes the checksums of all bitmaps in the data bucket that don't have one.
+func addMissingChecksums(bucket *bbolt.Bucket) error {
+	var missing []uint64
+
+	c := bucket.Cursor()
+
+	for k, _ := c.Seek(keyPrefixValue); k != nil && bytes.HasPrefix(k, keyPrefixValue); k, _ = c.Next() {
+		valueIdx := binary.BigEndian.Uint64(k[len(keyPrefixValue):])
+		if bucket.Get(valueKey(keyPrefixChecksum, valueIdx)) == nil {
+			missing = append(missing, valueIdx)
+		}
+	}
+
+	for _, valueIdx := range missing {
+		var checksumBuf [8]byte
+
+		binary.BigEndian.PutUint64(checksumBuf[:], xxhash.Sum64(bucket.Get(valueKey(keyPrefixValue, valueIdx))))
+
+		if err := bucket.Put(valueKey(keyPrefixChecksum, valueIdx), checksumBuf[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteBitmap removes the bitmap of a value and its checksum from the data bucket.
+func deleteBitmap(bucket *bbolt.Bucket, valueIdx uint64) error {
+	if err := bucket.Delete(valueKey(keyPrefixValue, valueIdx)); err != nil {
+		return err
+	}
+
+	return bucket.Delete(valueKey(keyPrefixChecksum, valueIdx))
+}
+
+// mergeStoredBitmap returns the union of bm and the bitmap of the value stored in the bucket.
+// If no bitmap of the value is stored, bm is returned as is.
+func mergeStoredBitmap(bucket *bbolt.Bucket, valueIdx uint64, bm *roaring.Bitmap) (*roaring.Bitmap, error) {
+	data := bucket.Get(valueKey(keyPrefixValue, valueIdx))
+	if data == nil {
+		return bm, nil
+	}
+
+	stored := roaring.New()
+	if err := stored.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("failed to decode stored bitmap: %w", err)
+	}
+
+	stored.Or(bm)
+	stored.RunOptimize()
+
+	return stored, nil
+}
+
+// rebuildPresenceBitmaps writes the presence bitmaps of the provided columns as union of the
+// stored bitmaps of all their values. It is used when appending to index files that were
+// written before presence bitmaps were introduced, and must be called after all bitmaps of
+// values were written.
+func rebuildPresenceBitmaps(bucket *bbolt.Bucket, sch *schema, columns []string) error {
+	for _, colName := range columns {
+		col := sch.Columns[colName]
+
+		presence := roaring.New()
+
+		for v, valueIdx := range col.Values {
+			data := bucket.Get(valueKey(keyPrefixValue, valueIdx))
+			if data == nil {
+				continue
+			}
+
+			bm := roaring.New()
+			if err := bm.UnmarshalBinary(data); err != nil {
+				return fmt.Errorf("failed to decode bitmap of value %q of column %q: %w", v, colName, err)
+			}
+
+			presence.Or(bm)
+		}
+
+		presence.RunOptimize()
+
+		if err := putBitmap(bucket, col.Presence, presence); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newIndexID returns a new random index ID. The index ID is stored in the data bucket
+// and identifies the index, e.g. to scope cache keys when a cache is shared between indexes.
+func newIndexID() ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate index ID: %w", err)
+	}
+	return id, nil
+}
+
+// Flush writes the index data to the file the IndexWriter was created for. If the IndexWriter
+// was opened using OpenIndexWriterForAppend, the data is merged into the existing file,
+// otherwise the file must not exist yet.
 func (idx *IndexWriter) Flush() error {
-	db, err := bbolt.Open(idx.filename, 0644, &bbolt.Options{OpenFile: openfile.OpenFile(openfile.Options{FailIfFileExists: true})})
+	db, err := bbolt.Open(idx.filename, 0644, &bbolt.Options{OpenFile: openfile.OpenFile(openfile.Options{
+		FailIfFileExists:      !idx.appending,
+		FailIfFileDoesntExist: idx.appending,
+	})})
 	if err != nil {
 		return err
 	}
@@ -106,21 +528,29 @@ func (idx *IndexWriter) Flush() error {
 	return idx.WriteToBoltDatabase(db)
 }
 
-// WriteToBoltDatabase writes the index data directly to a badger database.
+// testHookWriteBitmap is called before each bitmap is written by WriteToBoltDatabase, so that
+// tests can make writing fail at a specific point.
+var testHookWriteBitmap func() error
+
+// WriteToBoltDatabase writes the index data directly to a badger database. When appending
+// to an existing index, all data is written in a single transaction, so that the existing
+// index is left unchanged if writing fails. Otherwise, the data is committed in batches.
 func (idx *IndexWriter) WriteToBoltDatabase(db *bbolt.DB) error {
 	tx, err := db.Begin(true)
 	if err != nil {
 		return fmt.Errorf("failed to start new transaction: %w", err)
 	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
 	idx.mtx.Lock()
 	defer idx.mtx.Unlock()
 
 	idx.optimize()
 
-	var buf bytes.Buffer
-
-	if err := gob.NewEncoder(&buf).Encode(idx.schema); err != nil {
+	schemaBuf, err := idx.schema.marshal()
+	if err != nil {
 		return err
 	}
 
@@ -129,7 +559,11 @@ func (idx *IndexWriter) WriteToBoltDatabase(db *bbolt.DB) error {
 		return err
 	}
 
-	if err := bucket.Put(keySchema, buf.Bytes()); err != nil {
+	if err := bucket.Put(keySchema, schemaBuf); err != nil {
+		return err
+	}
+
+	if err := writeMetadata(bucket, newMetadata(idx.schema, idx.nextRowID, idx.createdAt)); err != nil {
 		return err
 	}
 
@@ -141,25 +575,38 @@ func (idx *IndexWriter) WriteToBoltDatabase(db *bbolt.DB) error {
 		return err
 	}
 
+	indexID, err := newIndexID()
+	if err != nil {
+		return err
+	}
+
+	if err := bucket.Put(keyIndexID, indexID); err != nil {
+		return err
+	}
+
 	i := 0
 
 	for k, v := range idx.values {
-		var keyBuf [8]byte
-
-		binary.BigEndian.PutUint64(keyBuf[:], k)
+		if idx.appending {
+			v, err = mergeStoredBitmap(bucket, k, v)
+			if err != nil {
+				return err
+			}
+		}
 
-		valueBuf, err := v.ToBytes()
-		if err != nil {
-			return err
+		if testHookWriteBitmap != nil {
+			if err := testHookWriteBitmap(); err != nil {
+				return err
+			}
 		}
 
-		if err := bucket.Put(append(keyPrefixValue, keyBuf[:]...), valueBuf); err != nil {
+		if err := putBitmap(bucket, k, v); err != nil {
 			return err
 		}
 
 		i++
 
-		if i%1000 == 0 {
+		if !idx.appending && i%1000 == 0 {
 			if err := tx.Commit(); err != nil {
 				return fmt.Errorf("failed to commit transaction: %w", err)
 			}
@@ -173,6 +620,20 @@ func (idx *IndexWriter) WriteToBoltDatabase(db *bbolt.DB) error {
 		}
 	}
 
+	if err := rebuildPresenceBitmaps(bucket, idx.schema, idx.rebuildPresence); err != nil {
+		return err
+	}
+
+	if err := rebuildTimeBucketBitmaps(bucket, idx.schema, idx.rebuildTimeBuckets); err != nil {
+		return err
+	}
+
+	if idx.addChecksums {
+		if err := addMissingChecksums(bucket); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
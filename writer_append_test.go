@@ -0,0 +1,79 @@
+package updog
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexWriterAppendFailure(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := NewIndexWriter(filename)
+
+	for i := 0; i < 1500; i++ {
+		_, err := w.AddRow(map[string]string{"a": strconv.Itoa(i), "b": "old"})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Flush())
+
+	appendRows := func() *IndexWriter {
+		w, err := OpenIndexWriterForAppend(filename)
+		require.NoError(t, err)
+
+		for i := 0; i < 1500; i++ {
+			_, err := w.AddRow(map[string]string{"a": strconv.Itoa(i + 1000), "b": "new"})
+			require.NoError(t, err)
+		}
+
+		return w
+	}
+
+	// writing fails after more bitmaps were written than are committed in one batch
+	// when writing a new index.
+	written := 0
+	testHookWriteBitmap = func() error {
+		written++
+		if written > 1200 {
+			return errors.New("write failed")
+		}
+		return nil
+	}
+	defer func() {
+		testHookWriteBitmap = nil
+	}()
+
+	require.Error(t, appendRows().Flush())
+
+	testHookWriteBitmap = nil
+
+	checkIndex := func(rows, newRows, rowsWithA1000 uint64) {
+		idx, err := OpenIndex(filename)
+		require.NoError(t, err)
+		defer idx.Close()
+
+		require.NoError(t, idx.Verify())
+
+		result, err := idx.Execute(&Query{Expr: &ExprPresent{Column: "a"}})
+		require.NoError(t, err)
+		require.Equal(t, rows, result.Count)
+
+		result, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "b", Value: "new"}})
+		require.NoError(t, err)
+		require.Equal(t, newRows, result.Count)
+
+		result, err = idx.Execute(&Query{Expr: &ExprEqual{Column: "a", Value: "1000"}})
+		require.NoError(t, err)
+		require.Equal(t, rowsWithA1000, result.Count)
+	}
+
+	checkIndex(1500, 0, 1)
+
+	require.NoError(t, appendRows().Flush())
+
+	checkIndex(3000, 1500, 2)
+}
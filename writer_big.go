@@ -1,25 +1,57 @@
 package updog
 
 import (
-	"bytes"
 	"encoding/binary"
-	"encoding/gob"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/RoaringBitmap/roaring"
 	"go.etcd.io/bbolt"
 )
 
 func NewBigIndexWriter(db *bbolt.DB, tempDB *bbolt.DB) (*BigIndexWriter, error) {
-	idx := &BigIndexWriter{
+	return newBigIndexWriter(&BigIndexWriter{
 		schema: &schema{
 			Columns: make(map[string]*column),
 		},
 		db:     db,
 		tempDB: tempDB,
+	})
+}
+
+// OpenBigIndexWriterForAppend creates a BigIndexWriter that adds further rows to the index
+// that already exists in db. Row IDs continue after the existing rows, and Flush merges the
+// new rows into the existing index data.
+func OpenBigIndexWriterForAppend(db *bbolt.DB, tempDB *bbolt.DB) (*BigIndexWriter, error) {
+	idx := &BigIndexWriter{
+		db:        db,
+		tempDB:    tempDB,
+		appending: true,
 	}
 
+	if err := db.View(func(tx *bbolt.Tx) error {
+		state, err := readIndexState(tx)
+		if err != nil {
+			return err
+		}
+
+		idx.schema = state.schema
+		idx.nextRowID = state.nextRowID
+		idx.createdAt = state.metadata.CreatedAt
+		idx.addChecksums = state.metadata.FormatVersion < formatVersionChecksums
+		idx.rebuildPresence = state.schema.addMissingPresence()
+		idx.rebuildTimeBuckets = state.schema.addMissingTimeBuckets()
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return newBigIndexWriter(idx)
+}
+
+func newBigIndexWriter(idx *BigIndexWriter) (*BigIndexWriter, error) {
 	if err := idx.tempDB.Update(func(tx *bbolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte("temp"))
 		return err
@@ -46,45 +78,194 @@ type BigIndexWriter struct {
 	tempTx *bbolt.Tx
 
 	nextRowID uint32
+	createdAt time.Time
+	appending bool
+
+	// addChecksums is set when appending to an index file that was written before
+	// checksums of bitmaps were introduced.
+	addChecksums bool
+
+	// rebuildPresence contains the columns of an index file that was written before
+	// presence bitmaps were introduced.
+	rebuildPresence []string
+
+	// rebuildTimeBuckets contains the timestamp columns of an index file that was written
+	// before time buckets were introduced.
+	rebuildTimeBuckets []string
+}
+
+// SetColumnType declares the type of a column. See IndexWriter.SetColumnType for details on
+// column types.
+func (idx *BigIndexWriter) SetColumnType(name string, typ ColumnType) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	return idx.schema.setType(name, typ)
+}
+
+// SetNumericColumn declares a column as numeric. See IndexWriter.SetNumericColumn for
+// details on numeric columns.
+func (idx *BigIndexWriter) SetNumericColumn(name string) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	return idx.schema.setNumeric(name)
 }
 
 func (idx *BigIndexWriter) AddRow(values map[string]string) (uint32, error) {
 	idx.mtx.Lock()
 	defer idx.mtx.Unlock()
 
+	canonical, numeric, err := checkRow(idx.schema, values)
+	if err != nil {
+		return 0, err
+	}
+
 	rowID := idx.nextRowID
 	defer func() {
 		idx.nextRowID++
 	}()
 
 	for k, v := range values {
-		valueIdx := idx.schema.add(k, v)
+		if encoded, ok := numeric[k]; ok {
+			if err := idx.putNumeric(k, encoded, rowID); err != nil {
+				return 0, err
+			}
+			continue
+		}
 
-		var key [12]byte
+		if c, ok := canonical[k]; ok {
+			v = c
+		}
 
-		binary.BigEndian.PutUint64(key[:8], valueIdx)
-		binary.BigEndian.PutUint32(key[8:], rowID)
+		if err := idx.putTempKey(idx.schema.add(k, v), rowID); err != nil {
+			return 0, err
+		}
 
-		bucket := idx.tempTx.Bucket([]byte("temp"))
+		if err := idx.putTempKey(idx.schema.addPresence(k), rowID); err != nil {
+			return 0, err
+		}
 
-		if err := bucket.Put(key[:], []byte{}); err != nil {
+		if err := idx.putTimeBuckets(k, v, rowID); err != nil {
 			return 0, err
 		}
 	}
 
+	if err := idx.commitPeriodically(rowID); err != nil {
+		return 0, err
+	}
+
+	return rowID, nil
+}
+
+// AddRowMulti adds a row of data where columns can have multiple values, and returns its row ID.
+// See IndexWriter.AddRowMulti for the semantics of multi-valued columns.
+func (idx *BigIndexWriter) AddRowMulti(values map[string][]string) (uint32, error) {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+
+	canonical, numeric, err := checkRowMulti(idx.schema, values)
+	if err != nil {
+		return 0, err
+	}
+
+	rowID := idx.nextRowID
+	defer func() {
+		idx.nextRowID++
+	}()
+
+	for k, vs := range values {
+		if encoded, ok := numeric[k]; ok {
+			if err := idx.putNumeric(k, encoded, rowID); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if c, ok := canonical[k]; ok {
+			vs = c
+		}
+
+		for _, v := range vs {
+			if err := idx.putTempKey(idx.schema.add(k, v), rowID); err != nil {
+				return 0, err
+			}
+
+			if err := idx.putTimeBuckets(k, v, rowID); err != nil {
+				return 0, err
+			}
+		}
+
+		if len(vs) > 0 {
+			if err := idx.putTempKey(idx.schema.addPresence(k), rowID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := idx.commitPeriodically(rowID); err != nil {
+		return 0, err
+	}
+
+	return rowID, nil
+}
+
+// putTempKey records in the temp bucket that the bitmap with the provided key contains the row.
+func (idx *BigIndexWriter) putTempKey(valueIdx uint64, rowID uint32) error {
+	var key [12]byte
+
+	binary.BigEndian.PutUint64(key[:8], valueIdx)
+	binary.BigEndian.PutUint32(key[8:], rowID)
+
+	bucket := idx.tempTx.Bucket([]byte("temp"))
+
+	return bucket.Put(key[:], []byte{})
+}
+
+// putNumeric records the row in the bitmaps of all bits that are set in the encoded value of
+// a numeric column, and in the column's presence bitmap.
+func (idx *BigIndexWriter) putNumeric(k string, encoded uint64, rowID uint32) error {
+	for i := range numericBits {
+		if encoded&(1<<i) != 0 {
+			if err := idx.putTempKey(idx.schema.addBit(k, i), rowID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return idx.putTempKey(idx.schema.addPresence(k), rowID)
+}
+
+// putTimeBuckets records the row in the time bucket columns of a timestamp column according
+// to the canonical value v of the column.
+func (idx *BigIndexWriter) putTimeBuckets(k, v string, rowID uint32) error {
+	for _, b := range idx.schema.timeBuckets(k, v) {
+		if err := idx.putTempKey(idx.schema.add(b.column, b.value), rowID); err != nil {
+			return err
+		}
+
+		if err := idx.putTempKey(idx.schema.addPresence(b.column), rowID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (idx *BigIndexWriter) commitPeriodically(rowID uint32) error {
 	if rowID > 0 && rowID%1000 == 0 {
 		err := idx.tempTx.Commit()
 		if err != nil {
-			return 0, fmt.Errorf("failed to commit: %w", err)
+			return fmt.Errorf("failed to commit: %w", err)
 		}
 
 		idx.tempTx, err = idx.tempDB.Begin(true)
 		if err != nil {
-			return 0, fmt.Errorf("failed to start new transaction: %w", err)
+			return fmt.Errorf("failed to start new transaction: %w", err)
 		}
 	}
 
-	return rowID, nil
+	return nil
 }
 
 func (idx *BigIndexWriter) Flush() error {
@@ -115,6 +296,20 @@ func (idx *BigIndexWriter) Flush() error {
 		return err
 	}
 
+	writeBitmap := func(valueIdx uint64, bm *roaring.Bitmap) error {
+		if idx.appending {
+			var err error
+			bm, err = mergeStoredBitmap(dataBucket, valueIdx, bm)
+			if err != nil {
+				return err
+			}
+		}
+
+		bm.RunOptimize()
+
+		return putBitmap(dataBucket, valueIdx, bm)
+	}
+
 	var (
 		currentValueIdx uint64
 		bm              *roaring.Bitmap
@@ -136,17 +331,7 @@ func (idx *BigIndexWriter) Flush() error {
 			// bm == nil indicates that this is for the first valueIdx, so we don't need to do
 			// a full rotate yet.
 			if bm != nil {
-				var keyBuf [8]byte
-
-				binary.BigEndian.PutUint64(keyBuf[:], currentValueIdx)
-
-				bm.RunOptimize()
-				valueBuf, err := bm.ToBytes()
-				if err != nil {
-					return err
-				}
-
-				if err := dataBucket.Put(append(keyPrefixValue, keyBuf[:]...), valueBuf); err != nil {
+				if err := writeBitmap(currentValueIdx, bm); err != nil {
 					return err
 				}
 			}
@@ -160,19 +345,17 @@ func (idx *BigIndexWriter) Flush() error {
 
 	// write last bitmap to data bucket:
 	if bm != nil {
-		var keyBuf [8]byte
-
-		binary.BigEndian.PutUint64(keyBuf[:], currentValueIdx)
-
-		bm.RunOptimize()
-		valueBuf, err := bm.ToBytes()
-		if err != nil {
+		if err := writeBitmap(currentValueIdx, bm); err != nil {
 			return err
 		}
+	}
 
-		if err := dataBucket.Put(append(keyPrefixValue, keyBuf[:]...), valueBuf); err != nil {
-			return err
-		}
+	if err := rebuildPresenceBitmaps(dataBucket, idx.schema, idx.rebuildPresence); err != nil {
+		return err
+	}
+
+	if err := rebuildTimeBucketBitmaps(dataBucket, idx.schema, idx.rebuildTimeBuckets); err != nil {
+		return err
 	}
 
 	// write nextRowID to data bucket:
@@ -184,14 +367,34 @@ func (idx *BigIndexWriter) Flush() error {
 		return err
 	}
 
+	// write index ID to data bucket:
+	indexID, err := newIndexID()
+	if err != nil {
+		return err
+	}
+
+	if err := dataBucket.Put(keyIndexID, indexID); err != nil {
+		return err
+	}
+
 	// write schema to data bucket:
-	var buf bytes.Buffer
+	schemaBuf, err := idx.schema.marshal()
+	if err != nil {
+		return err
+	}
 
-	if err := gob.NewEncoder(&buf).Encode(idx.schema); err != nil {
+	if err := dataBucket.Put(keySchema, schemaBuf); err != nil {
 		return err
 	}
 
-	if err := dataBucket.Put(keySchema, buf.Bytes()); err != nil {
+	if idx.addChecksums {
+		if err := addMissingChecksums(dataBucket); err != nil {
+			return err
+		}
+	}
+
+	// write metadata to data bucket:
+	if err := writeMetadata(dataBucket, newMetadata(idx.schema, idx.nextRowID, idx.createdAt)); err != nil {
 		return err
 	}
 
@@ -92,3 +92,186 @@ func TestBigWriter(t *testing.T) {
 	}, result2)
 
 }
+
+func TestBigWriterAppend(t *testing.T) {
+	f, err := os.CreateTemp("", "updog_test_*")
+	require.NoError(t, err)
+	tf, err := os.CreateTemp("", "updog_tmp_*")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer os.Remove(tf.Name())
+
+	db, err := bbolt.Open(f.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	tempDB, err := bbolt.Open(tf.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	idx, err := updog.NewBigIndexWriter(db, tempDB)
+	require.NoError(t, err)
+
+	require.NoError(t, idx.SetNumericColumn("n"))
+
+	_, err = idx.AddRow(map[string]string{"a": "1", "b": "2", "n": "3"})
+	require.NoError(t, err)
+
+	require.NoError(t, idx.Flush())
+
+	tf2, err := os.CreateTemp("", "updog_tmp_*")
+	require.NoError(t, err)
+	defer os.Remove(tf2.Name())
+
+	tempDB2, err := bbolt.Open(tf2.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	idx, err = updog.OpenBigIndexWriterForAppend(db, tempDB2)
+	require.NoError(t, err)
+
+	_, err = idx.AddRow(map[string]string{"a": "1", "b": "3", "n": "x"})
+	require.Error(t, err)
+
+	rowID, err := idx.AddRow(map[string]string{"a": "1", "b": "3", "n": "-4"})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), rowID)
+
+	require.NoError(t, idx.Flush())
+
+	newIdx, err := updog.OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	result, err := newIdx.Execute(&updog.Query{
+		Expr: &updog.ExprEqual{
+			Column: "a",
+			Value:  "1",
+		},
+		GroupBy: []string{"b"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &updog.Result{
+		Count: 2,
+		Groups: []updog.ResultGroup{
+			{
+				Fields: []updog.ResultField{
+					{
+						Column: "b",
+						Value:  "2",
+					},
+				},
+				Count: 1,
+			},
+			{
+				Fields: []updog.ResultField{
+					{
+						Column: "b",
+						Value:  "3",
+					},
+				},
+				Count: 1,
+			},
+		},
+	}, result)
+
+	result, err = newIdx.Execute(&updog.Query{
+		Expr:       &updog.ExprPresent{Column: "n"},
+		Aggregates: []updog.Aggregate{{Func: updog.AggregateSum, Column: "n"}, {Func: updog.AggregateMax, Column: "n"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []updog.AggregateValue{
+		{Func: updog.AggregateSum, Column: "n", Valid: true, Int: -1},
+		{Func: updog.AggregateMax, Column: "n", Valid: true, Int: 3},
+	}, result.Aggregates)
+	require.NoError(t, newIdx.Verify())
+}
+
+func TestBigWriterAddRowMulti(t *testing.T) {
+	f, err := os.CreateTemp("", "updog_test_*")
+	require.NoError(t, err)
+	tf, err := os.CreateTemp("", "updog_tmp_*")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer os.Remove(tf.Name())
+
+	db, err := bbolt.Open(f.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	tempDB, err := bbolt.Open(tf.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	idx, err := updog.NewBigIndexWriter(db, tempDB)
+	require.NoError(t, err)
+
+	_, err = idx.AddRowMulti(map[string][]string{"a": {"1"}, "tags": {"x", "y"}})
+	require.NoError(t, err)
+
+	_, err = idx.AddRow(map[string]string{"a": "1", "tags": "y"})
+	require.NoError(t, err)
+
+	require.NoError(t, idx.Flush())
+
+	newIdx, err := updog.OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	result, err := newIdx.Execute(&updog.Query{
+		Expr:    &updog.ExprEqual{Column: "a", Value: "1"},
+		GroupBy: []string{"tags"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &updog.Result{
+		Count: 2,
+		Groups: []updog.ResultGroup{
+			{Fields: []updog.ResultField{{Column: "tags", Value: "x"}}, Count: 1},
+			{Fields: []updog.ResultField{{Column: "tags", Value: "y"}}, Count: 2},
+		},
+	}, result)
+}
+
+func TestBigWriterTimeBuckets(t *testing.T) {
+	f, err := os.CreateTemp("", "updog_test_*")
+	require.NoError(t, err)
+	tf, err := os.CreateTemp("", "updog_tmp_*")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer os.Remove(tf.Name())
+
+	db, err := bbolt.Open(f.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	tempDB, err := bbolt.Open(tf.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	idx, err := updog.NewBigIndexWriter(db, tempDB)
+	require.NoError(t, err)
+
+	require.NoError(t, idx.SetColumnType("ts", updog.ColumnTypeTimestamp))
+
+	_, err = idx.AddRow(map[string]string{"ts": "2024-01-31 23:59:59"})
+	require.NoError(t, err)
+
+	_, err = idx.AddRowMulti(map[string][]string{"ts": {"2024-02-01", "2024-02-01T00:00:30Z"}})
+	require.NoError(t, err)
+
+	_, err = idx.AddRow(map[string]string{"ts": "2024-03-01T00:00:00Z"})
+	require.NoError(t, err)
+
+	require.NoError(t, idx.Flush())
+
+	newIdx, err := updog.OpenIndexFromBoltDatabase(db)
+	require.NoError(t, err)
+
+	require.NoError(t, newIdx.Verify())
+
+	month := updog.DateTrunc(updog.GranularityMonth, "ts")
+
+	result, err := newIdx.Execute(&updog.Query{
+		Expr:    &updog.ExprRange{Column: "ts", Lower: &updog.RangeBound{Value: "2024-01-31T12:00:00Z"}, Upper: &updog.RangeBound{Value: "2024-03-01", Exclusive: true}},
+		GroupBy: []string{month},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &updog.Result{
+		Count: 2,
+		Groups: []updog.ResultGroup{
+			{Fields: []updog.ResultField{{Column: month, Value: "2024-01-01T00:00:00Z", Type: updog.ColumnTypeTimestamp}}, Count: 1},
+			{Fields: []updog.ResultField{{Column: month, Value: "2024-02-01T00:00:00Z", Type: updog.ColumnTypeTimestamp}}, Count: 1},
+		},
+	}, result)
+}
@@ -0,0 +1,109 @@
+package updog_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/akrennmair/updog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexWriterAppend(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := updog.NewIndexWriter(filename)
+
+	rowID, err := w.AddRow(map[string]string{"a": "1", "b": "x"})
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), rowID)
+
+	_, err = w.AddRow(map[string]string{"a": "2", "b": "x"})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Flush())
+
+	_, err = updog.OpenIndexWriterForAppend(filepath.Join(t.TempDir(), "doesnt_exist.updog"))
+	require.Error(t, err)
+
+	w, err = updog.OpenIndexWriterForAppend(filename)
+	require.NoError(t, err)
+
+	rowID, err = w.AddRow(map[string]string{"a": "1", "b": "y"})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), rowID)
+
+	_, err = w.AddRow(map[string]string{"a": "3", "c": "z"})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Flush())
+
+	idx, err := updog.OpenIndex(filename)
+	require.NoError(t, err)
+
+	result, err := idx.Execute(&updog.Query{
+		Expr:    &updog.ExprEqual{Column: "a", Value: "1"},
+		GroupBy: []string{"b"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &updog.Result{
+		Count: 2,
+		Groups: []updog.ResultGroup{
+			{Fields: []updog.ResultField{{Column: "b", Value: "x"}}, Count: 1},
+			{Fields: []updog.ResultField{{Column: "b", Value: "y"}}, Count: 1},
+		},
+	}, result)
+
+	result, err = idx.Execute(&updog.Query{
+		Expr: &updog.ExprNot{Expr: &updog.ExprEqual{Column: "c", Value: "z"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), result.Count)
+}
+
+func TestIndexWriterAddRowMulti(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "index.updog")
+
+	w := updog.NewIndexWriter(filename)
+
+	_, err := w.AddRowMulti(map[string][]string{"a": {"1"}, "tags": {"x", "y"}})
+	require.NoError(t, err)
+
+	_, err = w.AddRowMulti(map[string][]string{"a": {"1"}, "tags": {"y"}})
+	require.NoError(t, err)
+
+	_, err = w.AddRowMulti(map[string][]string{"a": {"2"}, "tags": {}})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Flush())
+
+	idx, err := updog.OpenIndex(filename)
+	require.NoError(t, err)
+
+	result, err := idx.Execute(&updog.Query{
+		Expr:    &updog.ExprEqual{Column: "a", Value: "1"},
+		GroupBy: []string{"tags"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &updog.Result{
+		Count: 2,
+		Groups: []updog.ResultGroup{
+			{Fields: []updog.ResultField{{Column: "tags", Value: "x"}}, Count: 1},
+			{Fields: []updog.ResultField{{Column: "tags", Value: "y"}}, Count: 2},
+		},
+	}, result)
+
+	result, err = idx.Execute(&updog.Query{
+		Expr: &updog.ExprAnd{Exprs: []updog.Expression{
+			&updog.ExprEqual{Column: "tags", Value: "x"},
+			&updog.ExprEqual{Column: "tags", Value: "y"},
+		}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), result.Count)
+
+	result, err = idx.Execute(&updog.Query{
+		Expr: &updog.ExprNot{Expr: &updog.ExprEqual{Column: "tags", Value: "x"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), result.Count)
+}